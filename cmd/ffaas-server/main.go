@@ -2,62 +2,161 @@ package main
 
 import (
 	"context"
-	"database/sql"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
 	"time"
 
-	"github.com/go-chi/chi/v5"
 	// comentar esta si no vas a usar godotenv
 	// "github.com/joho/godotenv"
 
 	"github.com/redis/go-redis/v9"
 
+	"github.com/Franconl/ffaas/internal/breaker"
+	"github.com/Franconl/ffaas/internal/httpapi"
+	"github.com/Franconl/ffaas/internal/outbox"
 	"github.com/Franconl/ffaas/internal/repo"
 	"github.com/Franconl/ffaas/internal/repo/cached"
+	"github.com/Franconl/ffaas/internal/repo/file"
+	"github.com/Franconl/ffaas/internal/repo/instrumented"
 	"github.com/Franconl/ffaas/internal/repo/memory"
 	"github.com/Franconl/ffaas/internal/repo/postgres"
-
-	_ "github.com/jackc/pgx/v5/stdlib" // driver para sql.Open("pgx", ...)
+	"github.com/Franconl/ffaas/internal/repo/resilient"
+	"github.com/Franconl/ffaas/internal/repo/sqlite"
+	"github.com/Franconl/ffaas/internal/stream"
+	"github.com/Franconl/ffaas/internal/tracing"
+	"github.com/Franconl/ffaas/internal/usage"
 )
 
 func main() {
 	// _ = godotenv.Load() // opcional si usás .env
 
+	// ffaas-server migrate [up|down [n]|status]
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		runMigrate(os.Args[2:])
+		return
+	}
+
+	autoMigrate := getEnv("DB_AUTO_MIGRATE", "true") == "true"
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// 🔹 Tracing (OpenTelemetry): exporter según OTEL_TRACES_EXPORTER (otlp | stdout | none)
+	shutdownTracing, err := tracing.Setup(ctx, "ffaas")
+	if err != nil {
+		log.Fatal("❌ Error configurando tracing:", err)
+	}
+
 	useMemory := os.Getenv("USE_MEMORY") == "true"
+	flagsDir := os.Getenv("FLAGS_DIR")
+	sqlitePath := os.Getenv("SQLITE_PATH")
 
 	var store repo.Flags
+	var usageStore repo.Usage
+	// lista versionada para /sdk/flags (solo con Postgres + Redis)
+	var snapshots repo.Snapshotter
+	// cambios de flags para /sdk/stream (solo con el change feed de Postgres)
+	var hub *stream.Hub
+	// repo en memoria con persistencia en disco (USE_MEMORY + MEMORY_DATA_DIR)
+	var persisted *memory.Repo
+
+	if flagsDir != "" {
+		// 🔹 GitOps: flags de solo lectura desde un directorio de YAML/JSON
+		fileRepo, err := file.New(flagsDir)
+		if err != nil {
+			log.Fatal("❌ Error cargando flags desde FLAGS_DIR:", err)
+		}
+		reloadEvery, err := time.ParseDuration(getEnv("FLAGS_RELOAD_INTERVAL", "5s"))
+		if err != nil {
+			log.Fatal("❌ FLAGS_RELOAD_INTERVAL inválido:", err)
+		}
+		go fileRepo.Watch(ctx, reloadEvery)
+
+		store = instrumented.New(fileRepo, "file")
+		// el uso de flags no va a los archivos: queda en memoria
+		usageStore = memory.New()
+		log.Println("⚡ Usando flags de solo lectura desde", flagsDir)
+	} else if sqlitePath != "" {
+		// 🔹 SQLite: persistencia en un archivo, sin servicios externos
+		db, err := sqlite.Open(sqlitePath)
+		if err != nil {
+			log.Fatal("❌ Error abriendo SQLite:", err)
+		}
+		defer db.Close()
+
+		if autoMigrate {
+			applied, err := sqlite.Migrate(ctx, db)
+			if err != nil {
+				log.Fatal("❌ Error migrando SQLite:", err)
+			}
+			logApplied(applied)
+		}
 
-	if useMemory {
+		sqliteRepo := sqlite.New(db)
+		store = instrumented.New(sqliteRepo, "sqlite")
+		usageStore = sqliteRepo
+		log.Println("⚡ Usando SQLite en", sqlitePath)
+	} else if useMemory {
 		// 🔹 Repositorio en memoria (ideal para dev rápido)
-		store = memory.New()
+		memRepo := memory.New()
+		// con MEMORY_DATA_DIR sobrevive reinicios: snapshot periódico + WAL
+		if dataDir := os.Getenv("MEMORY_DATA_DIR"); dataDir != "" {
+			memRepo, err = memory.Open(dataDir)
+			if err != nil {
+				log.Fatal("❌ Error restaurando el repositorio en memoria:", err)
+			}
+			persisted = memRepo
+			log.Println("💾 Persistiendo flags en", dataDir)
+		}
+		store = instrumented.New(memRepo, "memory")
+		usageStore = memRepo
 		log.Println("⚡ Usando repositorio en memoria")
 	} else {
-		// 🔹 Config DB
-		pgUser := getEnv("DB_USER", "app")
-		pgPass := getEnv("DB_PASS", "app")
-		pgHost := getEnv("DB_HOST", "localhost")
-		pgPort := getEnv("DB_PORT", "5432")
-		pgName := getEnv("DB_NAME", "appdb")
-
-		dsn := fmt.Sprintf("postgres://%s:%s@%s:%s/%s",
-			pgUser, pgPass, pgHost, pgPort, pgName,
-		)
-
 		// Conexión a Postgres
-		db, err := sql.Open("pgx", dsn)
+		db, err := openPostgres()
 		if err != nil {
 			log.Fatal("❌ Error conectando a Postgres:", err)
 		}
 		defer db.Close()
 
-		if err := db.Ping(); err != nil {
-			log.Fatal("❌ Error al hacer ping a Postgres:", err)
+		// 🔹 Schema: migraciones embebidas (con advisory lock entre réplicas)
+		if autoMigrate {
+			applied, err := postgres.Migrate(ctx, db)
+			if err != nil {
+				log.Fatal("❌ Error migrando Postgres:", err)
+			}
+			logApplied(applied)
 		}
-		pgRepo := postgres.New(db)
+
+		pg := postgres.New(db)
+		usageStore = pg
+
+		// 🔹 Outbox: eventos de cambio en la misma transacción + relay al sink (OUTBOX_SINK)
+		if kind := os.Getenv("OUTBOX_SINK"); kind != "" {
+			sink, closeSink, err := newOutboxSink(kind)
+			if err != nil {
+				log.Fatal("❌ Error configurando OUTBOX_SINK:", err)
+			}
+			defer closeSink()
+			relayEvery, err := time.ParseDuration(getEnv("OUTBOX_INTERVAL", "1s"))
+			if err != nil {
+				log.Fatal("❌ OUTBOX_INTERVAL inválido:", err)
+			}
+			relay := postgres.NewRelay(db, sink)
+			if relay.Retention, err = time.ParseDuration(getEnv("OUTBOX_RETENTION", "24h")); err != nil {
+				log.Fatal("❌ OUTBOX_RETENTION inválido:", err)
+			}
+			pg.EnableOutbox()
+			go relay.Run(ctx, relayEvery)
+			log.Println("📤 Publicando eventos de cambio en", kind)
+		}
+		pgRepo := instrumented.New(pg, "postgres")
 
 		// 🔹 Redis (opcional)
 		redisAddr := getEnv("REDIS_ADDR", "localhost:6379")
@@ -70,45 +169,189 @@ func main() {
 			log.Fatal("❌ Error conectando a Redis:", err)
 		}
 
+		// 🔹 Resiliencia: circuit breakers sobre Postgres y Redis + última copia buena
+		// de las flags para los SDKs (RESILIENCE=false lo apaga)
+		resilience := getEnv("RESILIENCE", "true") == "true"
+		breakerFailures, err := strconv.Atoi(getEnv("BREAKER_FAILURES", "5"))
+		if err != nil {
+			log.Fatal("❌ BREAKER_FAILURES inválido:", err)
+		}
+		breakerCooldown, err := time.ParseDuration(getEnv("BREAKER_COOLDOWN", "10s"))
+		if err != nil {
+			log.Fatal("❌ BREAKER_COOLDOWN inválido:", err)
+		}
+
+		var base cached.BaseRepo = pgRepo
+		if resilience {
+			base = resilient.Guard(pgRepo, breaker.New("postgres", breakerFailures, breakerCooldown, resilient.IsFailure))
+		}
+
 		// Repo cacheado (Postgres + Redis)
-		store = cached.New(pgRepo, rdb, 60*time.Second)
+		cachedRepo := cached.New(base, rdb, 60*time.Second)
+		if resilience {
+			cachedRepo.UseBreaker(breaker.New("redis", breakerFailures, breakerCooldown, nil))
+		}
+		// 🔹 LRU en proceso delante de Redis (LOCAL_CACHE_SIZE=0 lo apaga)
+		localSize, err := strconv.Atoi(getEnv("LOCAL_CACHE_SIZE", "10000"))
+		if err != nil {
+			log.Fatal("❌ LOCAL_CACHE_SIZE inválido:", err)
+		}
+		localTTL, err := time.ParseDuration(getEnv("LOCAL_CACHE_TTL", "5s"))
+		if err != nil {
+			log.Fatal("❌ LOCAL_CACHE_TTL inválido:", err)
+		}
+		cachedRepo.EnableLocal(localSize, localTTL)
+		// "no existe" cacheado en Redis (CACHE_NEGATIVE_TTL=0 lo apaga)
+		negativeTTL, err := time.ParseDuration(getEnv("CACHE_NEGATIVE_TTL", cached.DefaultNegativeTTL.String()))
+		if err != nil {
+			log.Fatal("❌ CACHE_NEGATIVE_TTL inválido:", err)
+		}
+		cachedRepo.SetNegativeTTL(negativeTTL)
+		// invalidaciones entre réplicas por pub/sub (con resync al reconectar)
+		go cachedRepo.RunInvalidations(ctx)
+
+		// 🔹 Change feed (LISTEN/NOTIFY): escrituras que no pasan por esta app
+		// (psql, otros servicios) también invalidan las caches y llegan a los SDKs
+		if getEnv("PG_CHANGE_FEED", "true") == "true" {
+			hub = stream.NewHub()
+			feed := postgres.NewListener(db)
+			feed.OnChange(func(c postgres.FlagChange) {
+				cachedRepo.Invalidate(ctx, c.ChangeID(), cached.Invalidation{IDs: []string{c.ID}, Keys: c.Keys()})
+				hub.Publish(stream.Event{Op: c.Op, ID: c.ID, Key: c.Key, OldKey: c.OldKey})
+			})
+			go feed.Run(ctx)
+		}
+		store = instrumented.New(cachedRepo, "cached")
+		snapshots = cachedRepo
+
+		if resilience {
+			refreshEvery, err := time.ParseDuration(getEnv("STALE_REFRESH_INTERVAL", "30s"))
+			if err != nil {
+				log.Fatal("❌ STALE_REFRESH_INTERVAL inválido:", err)
+			}
+			lastGood := resilient.New(store, cachedRepo)
+			go lastGood.Run(ctx, refreshEvery)
+			store = lastGood
+			snapshots = lastGood
+		}
 		log.Println("⚡ Usando Postgres + Redis")
 	}
 
-	// --- HTTP Router ---
-	r := chi.NewRouter()
-
-	r.Get("/health", func(w http.ResponseWriter, r *http.Request) {
-		w.Write([]byte("ok"))
-	})
+	// 🔹 Uso de flags: se agrega en memoria y se baja al store cada USAGE_FLUSH_INTERVAL
+	flushEvery, err := time.ParseDuration(getEnv("USAGE_FLUSH_INTERVAL", "30s"))
+	if err != nil {
+		log.Fatal("❌ USAGE_FLUSH_INTERVAL inválido:", err)
+	}
+	tracker := usage.NewTracker(usageStore)
+	// se frena recién después de cerrar el server, para no perder evaluaciones en vuelo
+	usageCtx, stopUsage := context.WithCancel(context.Background())
+	usageDone := make(chan struct{})
+	go func() {
+		tracker.Run(usageCtx, flushEvery)
+		close(usageDone)
+	}()
 
-	r.Get("/flags", func(w http.ResponseWriter, r *http.Request) {
-		flags, err := store.List()
+	// 🔹 Snapshots del repo en memoria: después del último flush de uso, así entra en el snapshot final
+	persistCtx, stopPersist := context.WithCancel(context.Background())
+	persistDone := make(chan struct{})
+	if persisted != nil {
+		snapshotEvery, err := time.ParseDuration(getEnv("MEMORY_SNAPSHOT_INTERVAL", "5m"))
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
+			log.Fatal("❌ MEMORY_SNAPSHOT_INTERVAL inválido:", err)
 		}
-		writeJSON(w, flags)
+		go func() {
+			persisted.RunSnapshots(persistCtx, snapshotEvery)
+			close(persistDone)
+		}()
+	} else {
+		close(persistDone)
+	}
+
+	// 🔹 Flags archivadas: se pueden purgar recién después de ARCHIVE_RETENTION
+	retention, err := time.ParseDuration(getEnv("ARCHIVE_RETENTION", "720h"))
+	if err != nil {
+		log.Fatal("❌ ARCHIVE_RETENTION inválido:", err)
+	}
+
+	// --- HTTP Router ---
+	// incluye /healthz, /metrics, la API admin (/flags) y la API SDK (/sdk)
+	r := httpapi.NewRouter(store, httpapi.Options{
+		Usage:            tracker,
+		ArchiveRetention: retention,
+		Snapshots:        snapshots,
+		Stream:           hub,
 	})
 
 	// --- Server ---
 	addr := ":" + getEnv("APP_PORT", "8080")
-	log.Println("🚀 API escuchando en", addr)
-	log.Fatal(http.ListenAndServe(addr, r))
+	srv := &http.Server{Addr: addr, Handler: r}
+	if hub != nil {
+		// los streams SSE no terminan solos: cerrarlos para que Shutdown no espere
+		srv.RegisterOnShutdown(hub.Close)
+	}
+
+	go func() {
+		log.Println("🚀 API escuchando en", addr)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal("❌ Error en el servidor HTTP:", err)
+		}
+	}()
+
+	<-ctx.Done()
+	log.Println("🛑 Apagando servidor...")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Println("⚠️ Error cerrando el servidor HTTP:", err)
+	}
+	// último flush del uso de flags
+	stopUsage()
+	<-usageDone
+	// snapshot final y cierre del WAL
+	stopPersist()
+	<-persistDone
+	if persisted != nil {
+		if err := persisted.Close(); err != nil {
+			log.Println("⚠️ Error cerrando el WAL:", err)
+		}
+	}
+	// flush de los spans pendientes
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		log.Println("⚠️ Error cerrando tracing:", err)
+	}
 }
 
 // Helpers -----------------
 
+// newOutboxSink arma el sink del outbox: stdout, file (OUTBOX_FILE) o webhook
+// (OUTBOX_WEBHOOK_URL, firmado con OUTBOX_WEBHOOK_SECRET si está)
+func newOutboxSink(kind string) (outbox.Sink, func() error, error) {
+	noop := func() error { return nil }
+	switch kind {
+	case "stdout":
+		return outbox.NewWriterSink(os.Stdout), noop, nil
+	case "file":
+		sink, err := outbox.NewFileSink(getEnv("OUTBOX_FILE", "flag-events.jsonl"))
+		if err != nil {
+			return nil, nil, err
+		}
+		return sink, sink.Close, nil
+	case "webhook":
+		url := os.Getenv("OUTBOX_WEBHOOK_URL")
+		if url == "" {
+			return nil, nil, errors.New("OUTBOX_WEBHOOK_URL is required")
+		}
+		return outbox.NewWebhookSink(url, os.Getenv("OUTBOX_WEBHOOK_SECRET")), noop, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown sink %q (stdout, file, webhook)", kind)
+	}
+}
+
 func getEnv(k, def string) string {
 	if v := os.Getenv(k); v != "" {
 		return v
 	}
 	return def
 }
-
-func writeJSON(w http.ResponseWriter, v any) {
-	w.Header().Set("Content-Type", "application/json")
-	enc := json.NewEncoder(w)
-	enc.SetIndent("", "  ")
-	_ = enc.Encode(v)
-}
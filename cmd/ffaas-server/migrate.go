@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+
+	"github.com/Franconl/ffaas/internal/migrate"
+	"github.com/Franconl/ffaas/internal/repo/postgres"
+	"github.com/Franconl/ffaas/internal/repo/sqlite"
+
+	_ "github.com/jackc/pgx/v5/stdlib" // driver para sql.Open("pgx", ...)
+)
+
+// runMigrate maneja el subcomando migrate. Usa la misma config que el server:
+// SQLITE_PATH si esta definido, si no Postgres con las DB_*.
+//
+//	ffaas-server migrate            aplica las pendientes (igual que "up")
+//	ffaas-server migrate down [n]   revierte las ultimas n (default 1)
+//	ffaas-server migrate status     lista las migraciones y si estan aplicadas
+func runMigrate(args []string) {
+	ctx := context.Background()
+
+	var m migrate.Migrator
+	if path := os.Getenv("SQLITE_PATH"); path != "" {
+		db, err := sqlite.Open(path)
+		if err != nil {
+			log.Fatal("❌ Error abriendo SQLite:", err)
+		}
+		defer db.Close()
+		if m, err = sqlite.Migrator(db); err != nil {
+			log.Fatal("❌ Error cargando migraciones:", err)
+		}
+	} else {
+		db, err := openPostgres()
+		if err != nil {
+			log.Fatal("❌ Error conectando a Postgres:", err)
+		}
+		defer db.Close()
+		if m, err = postgres.Migrator(db); err != nil {
+			log.Fatal("❌ Error cargando migraciones:", err)
+		}
+	}
+
+	cmd := "up"
+	if len(args) > 0 {
+		cmd = args[0]
+	}
+
+	switch cmd {
+	case "up":
+		applied, err := m.Up(ctx)
+		logApplied(applied)
+		if err != nil {
+			log.Fatal("❌ Error migrando:", err)
+		}
+		if len(applied) == 0 {
+			log.Println("✅ Schema al día")
+		}
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n <= 0 {
+				log.Fatal("❌ migrate down: la cantidad tiene que ser un entero positivo")
+			}
+			steps = n
+		}
+		reverted, err := m.Down(ctx, steps)
+		if len(reverted) > 0 {
+			log.Println("↩️ Migraciones revertidas:", reverted)
+		}
+		if err != nil {
+			log.Fatal("❌ Error revirtiendo:", err)
+		}
+	case "status":
+		states, err := m.Status(ctx)
+		if err != nil {
+			log.Fatal("❌ Error leyendo migraciones:", err)
+		}
+		for _, s := range states {
+			mark := "pending"
+			if s.Applied {
+				mark = "applied"
+			}
+			fmt.Printf("%04d_%s\t%s\n", s.Version, s.Name, mark)
+		}
+	default:
+		log.Fatalf("❌ migrate: comando desconocido %q (up, down [n], status)", cmd)
+	}
+}
+
+// openPostgres abre la conexion con la config DB_* y verifica que responda
+func openPostgres() (*sql.DB, error) {
+	dsn := fmt.Sprintf("postgres://%s:%s@%s:%s/%s",
+		getEnv("DB_USER", "app"),
+		getEnv("DB_PASS", "app"),
+		getEnv("DB_HOST", "localhost"),
+		getEnv("DB_PORT", "5432"),
+		getEnv("DB_NAME", "appdb"),
+	)
+
+	db, err := sql.Open("pgx", dsn)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+func logApplied(applied []int) {
+	if len(applied) > 0 {
+		log.Println("🗄️ Migraciones aplicadas:", applied)
+	}
+}
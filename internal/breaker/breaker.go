@@ -0,0 +1,160 @@
+// Package breaker implementa un circuit breaker simple: despues de N fallas
+// seguidas se abre y rechaza las llamadas durante un cooldown; despues deja
+// pasar una sola llamada de prueba (half-open) y segun como le vaya se cierra
+// o se vuelve a abrir.
+package breaker
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/Franconl/ffaas/internal/metrics"
+)
+
+// ErrOpen lo devuelve Allow/Do mientras el breaker esta abierto
+var ErrOpen = errors.New("circuit breaker open")
+
+type State int
+
+const (
+	Closed State = iota
+	Open
+	HalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case Open:
+		return "open"
+	case HalfOpen:
+		return "half-open"
+	default:
+		return "closed"
+	}
+}
+
+type Breaker struct {
+	name      string
+	threshold int
+	cooldown  time.Duration
+	isFailure func(error) bool
+
+	mu       sync.Mutex
+	state    State
+	failures int
+	openedAt time.Time
+	probing  bool
+	// gen sube con cada cambio de estado: los resultados de llamadas
+	// permitidas en un estado anterior se ignoran
+	gen uint64
+}
+
+// Ticket identifica una llamada permitida por Allow; se devuelve en Record
+type Ticket struct {
+	gen   uint64
+	probe bool
+}
+
+// New crea un breaker que se abre despues de threshold fallas seguidas y
+// prueba de nuevo pasado cooldown. isFailure decide que errores cuentan (los
+// de negocio, como not found, no deberian); nil cuenta cualquier error.
+func New(name string, threshold int, cooldown time.Duration, isFailure func(error) bool) *Breaker {
+	if isFailure == nil {
+		isFailure = func(err error) bool { return err != nil }
+	}
+	b := &Breaker{name: name, threshold: max(threshold, 1), cooldown: cooldown, isFailure: isFailure}
+	metrics.BreakerState.WithLabelValues(name).Set(float64(Closed))
+	return b
+}
+
+// Allow dice si se puede hacer la llamada. Si devuelve nil, el resultado se
+// tiene que informar con Record pasando el Ticket.
+func (b *Breaker) Allow() (Ticket, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case Open:
+		if time.Since(b.openedAt) < b.cooldown {
+			return Ticket{}, ErrOpen
+		}
+		b.setState(HalfOpen)
+		b.probing = true
+		return Ticket{gen: b.gen, probe: true}, nil
+	case HalfOpen:
+		// ya hay una llamada de prueba en vuelo
+		if b.probing {
+			return Ticket{}, ErrOpen
+		}
+		b.probing = true
+		return Ticket{gen: b.gen, probe: true}, nil
+	}
+	return Ticket{gen: b.gen}, nil
+}
+
+// Record informa el resultado de la llamada de t. Solo la llamada de prueba
+// saca al breaker de abierto/half-open: una llamada lenta que entro antes de
+// que se abriera no lo cierra. Una llamada que el cliente cancelo no dice
+// nada del backend: libera la prueba pero no cambia el estado ni el conteo.
+func (b *Breaker) Record(t Ticket, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if t.gen != b.gen {
+		return
+	}
+	if errors.Is(err, context.Canceled) {
+		if t.probe {
+			b.probing = false
+		}
+		return
+	}
+	if !b.isFailure(err) {
+		b.failures = 0
+		b.probing = false
+		b.setState(Closed)
+		return
+	}
+
+	b.failures++
+	if t.probe || b.failures >= b.threshold {
+		b.probing = false
+		b.openedAt = time.Now()
+		b.setState(Open)
+	}
+}
+
+// Do corre fn si el breaker lo permite y registra el resultado
+func (b *Breaker) Do(fn func() error) error {
+	t, err := b.Allow()
+	if err != nil {
+		return err
+	}
+	err = fn()
+	b.Record(t, err)
+	return err
+}
+
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+// setState se llama con el lock tomado
+func (b *Breaker) setState(s State) {
+	if b.state == s {
+		return
+	}
+	if s == Open {
+		log.Printf("🔌 Circuit breaker %s abierto (%d fallas seguidas)", b.name, b.failures)
+	} else if s == Closed {
+		log.Printf("🔌 Circuit breaker %s cerrado", b.name)
+	}
+	b.state = s
+	b.gen++
+	metrics.BreakerState.WithLabelValues(b.name).Set(float64(s))
+}
@@ -0,0 +1,123 @@
+package breaker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestBreakerOpensAndRecovers(t *testing.T) {
+	errDown := errors.New("connection refused")
+	errNotFound := errors.New("not found")
+	b := New("test", 3, 20*time.Millisecond, func(err error) bool {
+		return err != nil && !errors.Is(err, errNotFound)
+	})
+
+	// los errores que no son falla no cuentan
+	for range 5 {
+		b.Do(func() error { return errNotFound })
+	}
+	if b.State() != Closed {
+		t.Fatalf("state = %v, want closed", b.State())
+	}
+
+	for range 3 {
+		b.Do(func() error { return errDown })
+	}
+	if b.State() != Open {
+		t.Fatalf("state = %v, want open", b.State())
+	}
+	called := false
+	if err := b.Do(func() error { called = true; return nil }); !errors.Is(err, ErrOpen) || called {
+		t.Fatalf("open breaker let the call through (err = %v)", err)
+	}
+
+	// pasado el cooldown deja pasar una sola prueba; si falla se vuelve a abrir
+	time.Sleep(25 * time.Millisecond)
+	probe, err := b.Allow()
+	if err != nil {
+		t.Fatalf("probe not allowed: %v", err)
+	}
+	if _, err := b.Allow(); !errors.Is(err, ErrOpen) {
+		t.Errorf("second call during the probe: err = %v, want ErrOpen", err)
+	}
+	b.Record(probe, errDown)
+	if b.State() != Open {
+		t.Fatalf("state after failed probe = %v, want open", b.State())
+	}
+
+	time.Sleep(25 * time.Millisecond)
+	if err := b.Do(func() error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if b.State() != Closed {
+		t.Errorf("state after successful probe = %v, want closed", b.State())
+	}
+}
+
+func TestCancelledCallsAreNeutral(t *testing.T) {
+	errDown := errors.New("connection refused")
+	cancelled := fmt.Errorf("query: %w", context.Canceled)
+	b := New("test-cancel", 3, 20*time.Millisecond, nil)
+
+	// cerrado: una cancelacion no resetea las fallas seguidas
+	b.Do(func() error { return errDown })
+	b.Do(func() error { return errDown })
+	b.Do(func() error { return cancelled })
+	b.Do(func() error { return errDown })
+	if b.State() != Open {
+		t.Fatalf("state = %v, want open", b.State())
+	}
+
+	// una prueba cancelada no cierra el breaker pero libera el lugar de la prueba
+	time.Sleep(25 * time.Millisecond)
+	if err := b.Do(func() error { return cancelled }); !errors.Is(err, context.Canceled) {
+		t.Fatalf("probe: err = %v", err)
+	}
+	if b.State() != HalfOpen {
+		t.Fatalf("state after cancelled probe = %v, want half-open", b.State())
+	}
+	b.Do(func() error { return errDown })
+	if b.State() != Open {
+		t.Errorf("state after failed probe = %v, want open", b.State())
+	}
+}
+
+func TestLateResultsDoNotMoveTheBreaker(t *testing.T) {
+	errDown := errors.New("connection refused")
+	b := New("test-late", 2, 20*time.Millisecond, nil)
+
+	// una llamada lenta entra con el breaker cerrado
+	slow, err := b.Allow()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.Do(func() error { return errDown })
+	b.Do(func() error { return errDown })
+	if b.State() != Open {
+		t.Fatalf("state = %v, want open", b.State())
+	}
+
+	// termina bien con el breaker abierto: no lo cierra
+	b.Record(slow, nil)
+	if b.State() != Open {
+		t.Fatalf("state after late success = %v, want open", b.State())
+	}
+
+	// ni durante la prueba
+	time.Sleep(25 * time.Millisecond)
+	probe, err := b.Allow()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.Record(slow, nil)
+	if b.State() != HalfOpen {
+		t.Fatalf("state after late success during probe = %v, want half-open", b.State())
+	}
+	b.Record(probe, nil)
+	if b.State() != Closed {
+		t.Errorf("state after successful probe = %v, want closed", b.State())
+	}
+}
@@ -6,32 +6,119 @@ import (
 	"time"
 )
 
+// FlagKind clasifica la flag segun para que se usa
+type FlagKind string
+
+const (
+	KindRelease    FlagKind = "release"    // release gradual de una feature
+	KindExperiment FlagKind = "experiment" // A/B test
+	KindOps        FlagKind = "ops"        // kill switch operativo
+	KindPermission FlagKind = "permission" // habilita features a ciertos usuarios/planes
+)
+
+// Valid indica si el kind es uno de los conocidos
+func (k FlagKind) Valid() bool {
+	switch k {
+	case KindRelease, KindExperiment, KindOps, KindPermission:
+		return true
+	}
+	return false
+}
+
 type FeatureFlag struct {
-	ID          string    `json:"id"`
-	Key         string    `json:"key"`
-	Description string    `json:"description"`
-	Enabled     bool      `json:"enabled"`
-	Percentage  int       `json:"percentage"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID          string `json:"id"`
+	Key         string `json:"key"`
+	Description string `json:"description"`
+	Enabled     bool   `json:"enabled"`
+	Percentage  int    `json:"percentage"`
+
+	// metadata de ciclo de vida
+	Owner     string     `json:"owner,omitempty"` // equipo o persona responsable
+	Tags      []string   `json:"tags,omitempty"`
+	Kind      FlagKind   `json:"kind,omitempty"`
+	Temporary bool       `json:"temporary"`            // se espera borrarla (vs. flag permanente)
+	ExpiresAt *time.Time `json:"expires_at,omitempty"` // fecha planeada de remocion
+
+	// ArchivedAt != nil indica soft delete: la flag deja de servirse a los SDKs
+	// pero se conserva (con su uso) hasta que se purgue
+	ArchivedAt *time.Time `json:"archived_at,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	// RolloutChangedAt es la ultima vez que cambio enabled o percentage (lo que
+	// reciben los usuarios); UpdatedAt se mueve con cualquier edicion
+	RolloutChangedAt time.Time `json:"rollout_changed_at,omitzero"`
 }
 
-func (f FeatureFlag) Eval(userID string) bool {
-	if !f.Enabled {
-		return false
+// HasTag indica si la flag tiene el tag indicado
+func (f FeatureFlag) HasTag(tag string) bool {
+	for _, t := range f.Tags {
+		if t == tag {
+			return true
+		}
 	}
+	return false
+}
 
-	if f.Percentage >= 100 {
-		return true
-	}
+// Archived indica si la flag esta archivada
+func (f FeatureFlag) Archived() bool {
+	return f.ArchivedAt != nil
+}
 
-	if f.Percentage <= 0 {
-		return false
+// Expired indica si la flag paso su fecha de expiracion declarada
+func (f FeatureFlag) Expired(now time.Time) bool {
+	return f.ExpiresAt != nil && f.ExpiresAt.Before(now)
+}
+
+// Variantes de una evaluacion: las flags de ffaas son on/off
+const (
+	VariantOn  = "on"
+	VariantOff = "off"
+)
+
+// Reason explica de donde salio el resultado de una evaluacion. Los valores
+// son los de OpenFeature, asi los SDKs los pueden pasar tal cual.
+type Reason string
+
+const (
+	ReasonDisabled Reason = "DISABLED" // la flag esta apagada
+	ReasonStatic   Reason = "STATIC"   // prendida al 0% o al 100%: todos reciben lo mismo
+	ReasonSplit    Reason = "SPLIT"    // rollout parcial: decidio el bucket del usuario
+)
+
+// Evaluation es el resultado de evaluar una flag para un usuario
+type Evaluation struct {
+	Enabled bool
+	Variant string
+	Reason  Reason
+}
+
+func (f FeatureFlag) Eval(userID string) bool {
+	return f.Evaluate(userID).Enabled
+}
+
+// Evaluate evalua la flag para userID y dice por que dio lo que dio
+func (f FeatureFlag) Evaluate(userID string) Evaluation {
+	switch {
+	case !f.Enabled:
+		return evaluation(false, ReasonDisabled)
+	case f.Percentage >= 100:
+		return evaluation(true, ReasonStatic)
+	case f.Percentage <= 0:
+		return evaluation(false, ReasonStatic)
 	}
 
 	data := []byte(f.Key + ":" + userID)
 	h := sha1.Sum(data)
 	val := binary.BigEndian.Uint32(h[:4])
 	bucket := val % 100
-	return int(bucket) < f.Percentage
+	return evaluation(int(bucket) < f.Percentage, ReasonSplit)
+}
+
+func evaluation(enabled bool, reason Reason) Evaluation {
+	variant := VariantOff
+	if enabled {
+		variant = VariantOn
+	}
+	return Evaluation{Enabled: enabled, Variant: variant, Reason: reason}
 }
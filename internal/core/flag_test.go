@@ -1,6 +1,7 @@
 package core
 
 import (
+	"errors"
 	"testing"
 	"time"
 
@@ -33,6 +34,30 @@ func TestEvalBasics(t *testing.T) {
 	}
 }
 
+func TestEvaluateReasons(t *testing.T) {
+	cases := []struct {
+		flag FeatureFlag
+		want Evaluation
+	}{
+		{FeatureFlag{Key: "off", Enabled: false, Percentage: 100}, Evaluation{false, VariantOff, ReasonDisabled}},
+		{FeatureFlag{Key: "all", Enabled: true, Percentage: 100}, Evaluation{true, VariantOn, ReasonStatic}},
+		{FeatureFlag{Key: "none", Enabled: true, Percentage: 0}, Evaluation{false, VariantOff, ReasonStatic}},
+	}
+	for _, c := range cases {
+		if got := c.flag.Evaluate("user-1"); got != c.want {
+			t.Errorf("%s: expected %+v, got %+v", c.flag.Key, c.want, got)
+		}
+	}
+
+	split := FeatureFlag{Key: "half", Enabled: true, Percentage: 50}
+	for _, u := range GenerateUserID(50) {
+		got := split.Evaluate(u)
+		if got.Reason != ReasonSplit || got.Enabled != split.Eval(u) || (got.Variant == VariantOn) != got.Enabled {
+			t.Fatalf("user %s: inconsistent evaluation %+v", u, got)
+		}
+	}
+}
+
 func TestEval_Monontonicity_Approx(t *testing.T) {
 	users := GenerateUserID(1000)
 
@@ -90,3 +115,37 @@ func GenerateUserID(n int) []string {
 
 	return users
 }
+
+func TestValidate(t *testing.T) {
+	valid := FeatureFlag{Key: "checkout.new-flow_v2", Percentage: 50, Kind: KindOps, Tags: []string{"payments"}}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("expected valid flag, got %v", err)
+	}
+
+	cases := []struct {
+		name  string
+		flag  FeatureFlag
+		field string
+		err   error
+	}{
+		{"empty key", FeatureFlag{}, "key", ErrKeyRequired},
+		{"key with spaces", FeatureFlag{Key: "new checkout"}, "key", ErrInvalidKey},
+		{"negative percentage", FeatureFlag{Key: "a", Percentage: -1}, "percentage", ErrInvalidPercent},
+		{"unknown kind", FeatureFlag{Key: "a", Kind: "rollout"}, "kind", ErrInvalidKind},
+		{"duplicated tag", FeatureFlag{Key: "a", Tags: []string{"x", "x"}}, "tags[1]", ErrInvalidTag},
+	}
+	for _, c := range cases {
+		err := c.flag.Validate()
+		verr, ok := err.(*ValidationError)
+		if !ok {
+			t.Errorf("%s: expected *ValidationError, got %v", c.name, err)
+			continue
+		}
+		if verr.Fields[0].Field != c.field {
+			t.Errorf("%s: expected field %s, got %s", c.name, c.field, verr.Fields[0].Field)
+		}
+		if !errors.Is(err, c.err) {
+			t.Errorf("%s: expected %v, got %v", c.name, c.err, err)
+		}
+	}
+}
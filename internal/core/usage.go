@@ -0,0 +1,18 @@
+package core
+
+import "time"
+
+// FlagUsage acumula las evaluaciones de una flag (por ID, asi sobrevive a un rename de key)
+type FlagUsage struct {
+	FlagID          string    `json:"flag_id"`
+	Evaluations     int64     `json:"evaluations"`
+	LastEvaluatedAt time.Time `json:"last_evaluated_at"`
+}
+
+// Merge suma las evaluaciones de other y se queda con la ultima fecha
+func (u *FlagUsage) Merge(other FlagUsage) {
+	u.Evaluations += other.Evaluations
+	if other.LastEvaluatedAt.After(u.LastEvaluatedAt) {
+		u.LastEvaluatedAt = other.LastEvaluatedAt
+	}
+}
@@ -0,0 +1,137 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// Errores de validacion del modelo. Cada FieldError envuelve uno de estos,
+// asi errors.Is(err, ErrInvalidPercent) funciona sobre un *ValidationError.
+var (
+	ErrKeyRequired    = errors.New("key is required")
+	ErrInvalidKey     = errors.New("invalid key")
+	ErrInvalidPercent = errors.New("invalid percentage")
+	ErrInvalidKind    = errors.New("invalid flag kind")
+	ErrInvalidTag     = errors.New("invalid tag")
+	ErrTooLong        = errors.New("value is too long")
+)
+
+const (
+	MaxKeyLength         = 128
+	MaxDescriptionLength = 1024
+	MaxOwnerLength       = 128
+	MaxTags              = 20
+	MaxTagLength         = 64
+)
+
+// keyPattern: letras, numeros, '_', '-' y '.', empezando por letra o numero.
+// Las keys viajan en URLs y query strings, por eso no se permite nada mas.
+var keyPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+// FieldError es un error de validacion sobre un campo puntual (path estilo JSON: "tags[2]")
+type FieldError struct {
+	Field   string
+	Message string
+	Err     error
+}
+
+func (e FieldError) Error() string { return e.Field + ": " + e.Message }
+
+// ValidationError junta todos los errores de campo encontrados
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, 0, len(e.Fields))
+	for _, f := range e.Fields {
+		msgs = append(msgs, f.Error())
+	}
+	return "validation failed: " + strings.Join(msgs, "; ")
+}
+
+func (e *ValidationError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Fields))
+	for _, f := range e.Fields {
+		if f.Err != nil {
+			errs = append(errs, f.Err)
+		}
+	}
+	return errs
+}
+
+// Add agrega un error de campo
+func (e *ValidationError) Add(field string, err error, format string, args ...any) {
+	e.Fields = append(e.Fields, FieldError{Field: field, Message: fmt.Sprintf(format, args...), Err: err})
+}
+
+// OrNil devuelve nil si no hubo errores (para no devolver un error no-nil vacio)
+func (e *ValidationError) OrNil() error {
+	if len(e.Fields) == 0 {
+		return nil
+	}
+	return e
+}
+
+// Normalize completa los valores por defecto antes de validar/persistir
+func (f *FeatureFlag) Normalize() {
+	if f.Kind == "" {
+		f.Kind = KindRelease
+	}
+	if len(f.Tags) == 0 {
+		f.Tags = nil
+	}
+}
+
+// Validate chequea las reglas del modelo. Es la misma validacion para todos los
+// backends y para la API, asi memory y postgres aceptan exactamente lo mismo.
+func (f FeatureFlag) Validate() error {
+	verr := &ValidationError{}
+
+	switch {
+	case f.Key == "":
+		verr.Add("key", ErrKeyRequired, "is required")
+	case utf8.RuneCountInString(f.Key) > MaxKeyLength:
+		verr.Add("key", ErrTooLong, "must be at most %d characters", MaxKeyLength)
+	case !keyPattern.MatchString(f.Key):
+		verr.Add("key", ErrInvalidKey, "must start with a letter or digit and contain only letters, digits, '_', '-' or '.'")
+	}
+
+	if utf8.RuneCountInString(f.Description) > MaxDescriptionLength {
+		verr.Add("description", ErrTooLong, "must be at most %d characters", MaxDescriptionLength)
+	}
+
+	if f.Percentage < 0 || f.Percentage > 100 {
+		verr.Add("percentage", ErrInvalidPercent, "must be between 0 and 100")
+	}
+
+	if utf8.RuneCountInString(f.Owner) > MaxOwnerLength {
+		verr.Add("owner", ErrTooLong, "must be at most %d characters", MaxOwnerLength)
+	}
+
+	if f.Kind != "" && !f.Kind.Valid() {
+		verr.Add("kind", ErrInvalidKind, "must be one of release, experiment, ops, permission")
+	}
+
+	if len(f.Tags) > MaxTags {
+		verr.Add("tags", ErrInvalidTag, "must have at most %d tags", MaxTags)
+	}
+	seen := make(map[string]bool, len(f.Tags))
+	for i, tag := range f.Tags {
+		field := fmt.Sprintf("tags[%d]", i)
+		switch {
+		case strings.TrimSpace(tag) == "":
+			verr.Add(field, ErrInvalidTag, "must not be empty")
+		case utf8.RuneCountInString(tag) > MaxTagLength:
+			verr.Add(field, ErrTooLong, "must be at most %d characters", MaxTagLength)
+		case seen[tag]:
+			verr.Add(field, ErrInvalidTag, "duplicated tag %q", tag)
+		}
+		seen[tag] = true
+	}
+
+	return verr.OrNil()
+}
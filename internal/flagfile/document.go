@@ -0,0 +1,187 @@
+// Package flagfile define el documento versionado para exportar e importar
+// flags (YAML o JSON) y arma el plan de cambios de un import.
+package flagfile
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Franconl/ffaas/internal/core"
+	"gopkg.in/yaml.v3"
+)
+
+// Version es la version actual del formato. Un documento con otra version se rechaza.
+const Version = 1
+
+var (
+	ErrUnsupportedVersion = errors.New("unsupported document version")
+	ErrUnknownFormat      = errors.New("unknown format")
+)
+
+// Format es la codificacion del documento
+type Format string
+
+const (
+	FormatJSON Format = "json"
+	FormatYAML Format = "yaml"
+)
+
+// Document es el set completo de flags de una instancia
+type Document struct {
+	Version    int       `json:"version" yaml:"version"`
+	ExportedAt time.Time `json:"exported_at,omitzero" yaml:"exported_at,omitempty"`
+	Flags      []Flag    `json:"flags" yaml:"flags"`
+}
+
+// Flag es la definicion portable de una flag: sin id ni timestamps, que son
+// propios de cada instancia. Las flags se identifican por key.
+type Flag struct {
+	Key         string     `json:"key" yaml:"key"`
+	Description string     `json:"description,omitempty" yaml:"description,omitempty"`
+	Enabled     bool       `json:"enabled" yaml:"enabled"`
+	Percentage  int        `json:"percentage" yaml:"percentage"`
+	Owner       string     `json:"owner,omitempty" yaml:"owner,omitempty"`
+	Tags        []string   `json:"tags,omitempty" yaml:"tags,omitempty"`
+	Kind        string     `json:"kind,omitempty" yaml:"kind,omitempty"`
+	Temporary   bool       `json:"temporary,omitempty" yaml:"temporary,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty" yaml:"expires_at,omitempty"`
+}
+
+// FromFlags arma el documento a exportar, ordenado por key para que los diffs en git sean estables
+func FromFlags(flags []core.FeatureFlag, now time.Time) Document {
+	doc := Document{Version: Version, ExportedAt: now, Flags: make([]Flag, 0, len(flags))}
+	for _, f := range flags {
+		doc.Flags = append(doc.Flags, Flag{
+			Key:         f.Key,
+			Description: f.Description,
+			Enabled:     f.Enabled,
+			Percentage:  f.Percentage,
+			Owner:       f.Owner,
+			Tags:        slices.Clone(f.Tags),
+			Kind:        string(f.Kind),
+			Temporary:   f.Temporary,
+			ExpiresAt:   f.ExpiresAt,
+		})
+	}
+	sort.Slice(doc.Flags, func(i, j int) bool { return doc.Flags[i].Key < doc.Flags[j].Key })
+	return doc
+}
+
+// ToFlags convierte las definiciones del documento al modelo (sin id ni timestamps)
+func (doc Document) ToFlags() []core.FeatureFlag {
+	flags := make([]core.FeatureFlag, 0, len(doc.Flags))
+	for _, d := range doc.Flags {
+		var f core.FeatureFlag
+		d.apply(&f)
+		flags = append(flags, f)
+	}
+	return flags
+}
+
+// apply copia la definicion sobre f (sin tocar id, timestamps ni archived_at)
+func (d Flag) apply(f *core.FeatureFlag) {
+	f.Key = d.Key
+	f.Description = d.Description
+	f.Enabled = d.Enabled
+	f.Percentage = d.Percentage
+	f.Owner = d.Owner
+	f.Tags = slices.Clone(d.Tags)
+	f.Kind = core.FlagKind(d.Kind)
+	f.Temporary = d.Temporary
+	f.ExpiresAt = d.ExpiresAt
+	f.Normalize()
+}
+
+// Encode escribe el documento en el formato pedido
+func Encode(w io.Writer, doc Document, format Format) error {
+	switch format {
+	case FormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(doc)
+	case FormatYAML:
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(doc); err != nil {
+			return err
+		}
+		return enc.Close()
+	}
+	return ErrUnknownFormat
+}
+
+// Decode lee un documento de forma estricta (campos desconocidos son error) y
+// valida cada flag con las reglas del modelo. Los errores de campo vienen con
+// el path dentro del documento, ej: "flags[3].percentage".
+func Decode(data []byte, format Format) (Document, error) {
+	var doc Document
+	switch format {
+	case FormatJSON:
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&doc); err != nil {
+			return doc, fmt.Errorf("invalid json document: %w", err)
+		}
+	case FormatYAML:
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		if err := dec.Decode(&doc); err != nil && !errors.Is(err, io.EOF) {
+			return doc, fmt.Errorf("invalid yaml document: %w", err)
+		}
+	default:
+		return doc, ErrUnknownFormat
+	}
+
+	if doc.Version != Version {
+		return doc, fmt.Errorf("%w: %d (expected %d)", ErrUnsupportedVersion, doc.Version, Version)
+	}
+
+	return doc, doc.Validate()
+}
+
+// Validate valida todas las flags del documento y que no haya keys repetidas
+func (doc Document) Validate() error {
+	verr := &core.ValidationError{}
+	seen := make(map[string]int, len(doc.Flags))
+
+	for i, d := range doc.Flags {
+		prefix := fmt.Sprintf("flags[%d]", i)
+
+		var f core.FeatureFlag
+		d.apply(&f)
+		var ferr *core.ValidationError
+		if errors.As(f.Validate(), &ferr) {
+			for _, fe := range ferr.Fields {
+				fe.Field = prefix + "." + fe.Field
+				verr.Fields = append(verr.Fields, fe)
+			}
+		}
+
+		if first, dup := seen[d.Key]; dup && d.Key != "" {
+			verr.Add(prefix+".key", core.ErrInvalidKey, "duplicated key %q (also in flags[%d])", d.Key, first)
+		} else {
+			seen[d.Key] = i
+		}
+	}
+
+	return verr.OrNil()
+}
+
+// ParseFormat acepta el nombre del formato o un content type
+func ParseFormat(s string) (Format, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	switch s {
+	case "", "json", "application/json":
+		return FormatJSON, nil
+	case "yaml", "yml", "application/yaml", "application/x-yaml", "text/yaml":
+		return FormatYAML, nil
+	}
+	return "", fmt.Errorf("%w %q (use json or yaml)", ErrUnknownFormat, s)
+}
@@ -0,0 +1,97 @@
+package flagfile
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Franconl/ffaas/internal/core"
+	"github.com/Franconl/ffaas/internal/repo"
+	"github.com/Franconl/ffaas/internal/repo/memory"
+)
+
+const sampleYAML = `
+version: 1
+flags:
+  - key: new_checkout
+    enabled: true
+    percentage: 50
+    tags: [payments]
+  - key: dark_mode
+    percentage: 100
+`
+
+func TestDecodeYAML(t *testing.T) {
+	doc, err := Decode([]byte(sampleYAML), FormatYAML)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(doc.Flags) != 2 || doc.Flags[0].Key != "new_checkout" || doc.Flags[0].Percentage != 50 {
+		t.Errorf("unexpected document: %+v", doc)
+	}
+
+	_, err = Decode([]byte("version: 2\nflags: []\n"), FormatYAML)
+	if !errors.Is(err, ErrUnsupportedVersion) {
+		t.Errorf("expected ErrUnsupportedVersion, got %v", err)
+	}
+
+	_, err = Decode([]byte(`{"version":1,"flags":[{"key":"a","percentage":120},{"key":"a"}]}`), FormatJSON)
+	var verr *core.ValidationError
+	if !errors.As(err, &verr) || len(verr.Fields) != 2 || verr.Fields[0].Field != "flags[0].percentage" {
+		t.Errorf("expected field errors for flags[0].percentage and flags[1].key, got %v", err)
+	}
+}
+
+func TestBuildPlanModes(t *testing.T) {
+	current := []core.FeatureFlag{
+		{ID: "1", Key: "new_checkout", Percentage: 10, Kind: core.KindRelease},
+		{ID: "2", Key: "legacy_banner", Kind: core.KindRelease},
+	}
+	doc, err := Decode([]byte(sampleYAML), FormatYAML)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+
+	cases := []struct {
+		mode    Mode
+		actions map[string]Action
+	}{
+		{ModeCreateOnly, map[string]Action{"dark_mode": ActionCreate, "new_checkout": ActionSkip}},
+		{ModeUpsert, map[string]Action{"dark_mode": ActionCreate, "new_checkout": ActionUpdate}},
+		{ModeSync, map[string]Action{"dark_mode": ActionCreate, "new_checkout": ActionUpdate, "legacy_banner": ActionArchive}},
+	}
+	for _, c := range cases {
+		plan, err := BuildPlan(current, doc, c.mode)
+		if err != nil {
+			t.Fatalf("%s: %v", c.mode, err)
+		}
+		if len(plan.Diffs) != len(c.actions) {
+			t.Errorf("%s: expected %d diffs, got %+v", c.mode, len(c.actions), plan.Diffs)
+		}
+		for _, d := range plan.Diffs {
+			if c.actions[d.Key] != d.Action {
+				t.Errorf("%s: %s expected %s, got %s", c.mode, d.Key, c.actions[d.Key], d.Action)
+			}
+		}
+	}
+}
+
+func TestApplyIsAtomic(t *testing.T) {
+	ctx := context.Background()
+	store := memory.New()
+	existing := core.FeatureFlag{Key: "taken"}
+	if err := store.Create(ctx, &existing); err != nil {
+		t.Fatal(err)
+	}
+
+	err := store.Apply(ctx, []repo.Change{
+		{Op: repo.ChangeCreate, Flag: core.FeatureFlag{Key: "fresh"}},
+		{Op: repo.ChangeCreate, Flag: core.FeatureFlag{Key: "taken"}},
+	})
+	if !errors.Is(err, repo.ErrKeyAlreadyUsed) {
+		t.Fatalf("expected ErrKeyAlreadyUsed, got %v", err)
+	}
+	if _, err := store.GetByKey(ctx, "fresh"); !errors.Is(err, repo.ErrNotFound) {
+		t.Errorf("expected the first change to be rolled back, got %v", err)
+	}
+}
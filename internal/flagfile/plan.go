@@ -0,0 +1,150 @@
+package flagfile
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/Franconl/ffaas/internal/core"
+	"github.com/Franconl/ffaas/internal/repo"
+)
+
+// Mode define que hace el import con las flags que ya existen o que faltan
+type Mode string
+
+const (
+	// ModeCreateOnly crea las flags nuevas y no toca las existentes
+	ModeCreateOnly Mode = "create-only"
+	// ModeUpsert crea las nuevas y actualiza las existentes
+	ModeUpsert Mode = "upsert"
+	// ModeSync deja la instancia igual al documento: ademas archiva las flags que no estan en el
+	ModeSync Mode = "sync"
+)
+
+func (m Mode) Valid() bool {
+	switch m {
+	case ModeCreateOnly, ModeUpsert, ModeSync:
+		return true
+	}
+	return false
+}
+
+// Action es lo que el import hace (o haria, en dry run) con una flag
+type Action string
+
+const (
+	ActionCreate    Action = "create"
+	ActionUpdate    Action = "update"
+	ActionArchive   Action = "archive"
+	ActionUnchanged Action = "unchanged"
+	ActionSkip      Action = "skip"
+)
+
+// Diff describe el cambio sobre una key. Fields lista los campos que cambian en un update.
+type Diff struct {
+	Key    string
+	Action Action
+	Fields []string
+}
+
+// Plan es el resultado de comparar el documento con las flags actuales
+type Plan struct {
+	Diffs   []Diff
+	Changes []repo.Change
+}
+
+// BuildPlan compara el documento contra current (todas las flags, incluidas las
+// archivadas). Una key del documento que esta archivada en la instancia es un
+// conflicto: hay que restaurarla antes de importar.
+func BuildPlan(current []core.FeatureFlag, doc Document, mode Mode) (Plan, error) {
+	byKey := make(map[string]core.FeatureFlag, len(current))
+	for _, f := range current {
+		byKey[f.Key] = f
+	}
+
+	var plan Plan
+	inDoc := make(map[string]bool, len(doc.Flags))
+
+	for _, d := range doc.Flags {
+		inDoc[d.Key] = true
+
+		cur, exists := byKey[d.Key]
+		if !exists {
+			var f core.FeatureFlag
+			d.apply(&f)
+			plan.add(Diff{Key: d.Key, Action: ActionCreate}, repo.Change{Op: repo.ChangeCreate, Flag: f})
+			continue
+		}
+
+		if cur.Archived() {
+			return Plan{}, fmt.Errorf("flag %q: %w", d.Key, repo.ErrArchived)
+		}
+
+		next := cur
+		d.apply(&next)
+		fields := changedFields(cur, next)
+
+		switch {
+		case len(fields) == 0:
+			plan.Diffs = append(plan.Diffs, Diff{Key: d.Key, Action: ActionUnchanged})
+		case mode == ModeCreateOnly:
+			plan.Diffs = append(plan.Diffs, Diff{Key: d.Key, Action: ActionSkip, Fields: fields})
+		default:
+			plan.add(Diff{Key: d.Key, Action: ActionUpdate, Fields: fields}, repo.Change{Op: repo.ChangeUpdate, Flag: next})
+		}
+	}
+
+	if mode == ModeSync {
+		for _, f := range current {
+			if !inDoc[f.Key] && !f.Archived() {
+				plan.add(Diff{Key: f.Key, Action: ActionArchive}, repo.Change{Op: repo.ChangeArchive, Flag: f})
+			}
+		}
+	}
+
+	sort.SliceStable(plan.Diffs, func(i, j int) bool { return plan.Diffs[i].Key < plan.Diffs[j].Key })
+	return plan, nil
+}
+
+func (p *Plan) add(d Diff, c repo.Change) {
+	p.Diffs = append(p.Diffs, d)
+	p.Changes = append(p.Changes, c)
+}
+
+// changedFields devuelve los nombres (como en la API) de los campos que difieren
+func changedFields(a, b core.FeatureFlag) []string {
+	var fields []string
+	if a.Description != b.Description {
+		fields = append(fields, "description")
+	}
+	if a.Enabled != b.Enabled {
+		fields = append(fields, "enabled")
+	}
+	if a.Percentage != b.Percentage {
+		fields = append(fields, "percentage")
+	}
+	if a.Owner != b.Owner {
+		fields = append(fields, "owner")
+	}
+	if !slices.Equal(a.Tags, b.Tags) {
+		fields = append(fields, "tags")
+	}
+	if a.Kind != b.Kind {
+		fields = append(fields, "kind")
+	}
+	if a.Temporary != b.Temporary {
+		fields = append(fields, "temporary")
+	}
+	if !sameTime(a.ExpiresAt, b.ExpiresAt) {
+		fields = append(fields, "expires_at")
+	}
+	return fields
+}
+
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
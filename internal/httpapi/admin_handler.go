@@ -3,92 +3,89 @@ package httpapi
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/Franconl/ffaas/internal/core"
+	"github.com/Franconl/ffaas/internal/metrics"
 	"github.com/Franconl/ffaas/internal/repo"
+	"github.com/Franconl/ffaas/internal/usage"
 	"github.com/go-chi/chi/v5"
 )
 
 // AdminHandler agrupa los handlers http para la administracion de feature flags
 // Depende de un repositorio que implemente la interface repo.Flags
 type AdminHandler struct {
-	repo repo.Flags
+	repo      repo.Flags
+	usage     *usage.Tracker
+	retention time.Duration
 }
 
-// NewAdminHandler crea un nuevo admin handler usando el repositorio pasado por parametro
-func NewAdminHandler(r repo.Flags) *AdminHandler {
-	return &AdminHandler{repo: r}
+// NewAdminHandler crea un nuevo admin handler usando el repositorio pasado por parametro.
+// El tracker de uso es opcional (nil) y solo se usa para el reporte de flags viejas.
+// retention es cuanto tiempo tiene que estar archivada una flag para poder purgarla.
+func NewAdminHandler(r repo.Flags, u *usage.Tracker, retention time.Duration) *AdminHandler {
+	return &AdminHandler{repo: r, usage: u, retention: retention}
 }
 
 // Create maneja POST /flags
 func (h *AdminHandler) Create(w http.ResponseWriter, r *http.Request) {
 	var req CreateFlagRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeError(w, http.StatusBadRequest, repo.ErrInvalidBody.Error())
+	if err := decodeJSON(r, &req); err != nil {
+		writeDecodeError(w, err)
 		return
 	}
 
-	if req.Key == "" {
-		writeError(w, http.StatusBadRequest, repo.ErrKeyRequired.Error())
+	if err := req.Validate(); err != nil {
+		writeRepoError(w, err)
 		return
 	}
 
-	flag := core.FeatureFlag{
-		Key:         req.Key,
-		Description: req.Description,
-		Percentage:  req.Percentage,
-		CreatedAt:   time.Now(),
-		UpdatedAt:   time.Now(),
-	}
+	flag := req.toFlag()
 
-	err := h.repo.Create(&flag)
+	err := h.repo.Create(r.Context(), &flag)
+	recordMutation("create", err)
 	if err != nil {
-		if errors.Is(err, repo.ErrKeyAlreadyUsed) {
-			writeError(w, http.StatusConflict, err.Error())
-			return
-		}
-
-		writeError(w, http.StatusInternalServerError, err.Error())
+		writeRepoError(w, err)
+		return
 	}
 
 	// se mapea la flag al DTO de respuesta
 
-	writeJSON(w, http.StatusCreated, FlagResponse{
-		ID:          flag.ID,
-		Key:         flag.Key,
-		Description: flag.Description,
-		Enabled:     flag.Enabled,
-		Percentage:  flag.Percentage,
-		CreatedAt:   flag.CreatedAt,
-		UpdatedAt:   flag.UpdatedAt,
-	})
+	writeJSON(w, http.StatusCreated, toFlagResponse(flag))
 }
 
 // List maneja GET /flags
+// Filtros: owner, tag, kind, temporary, enabled, prefix, q (substring de la key), updated_since.
+// Orden: sort=created_at|updated_at|key (con "-" adelante para descendente).
+// Paginado: limit (default 50, max 500) y cursor (next_cursor de la pagina anterior).
 func (h *AdminHandler) List(w http.ResponseWriter, r *http.Request) {
-	val, err := h.repo.List()
+	q, err := parseQuery(r)
 	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	page, err := h.repo.Search(r.Context(), q)
+	if err != nil {
+		if errors.Is(err, repo.ErrInvalidCursor) {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
 		writeError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	flags := make([]FlagResponse, 0, len(val))
+	flags := make([]FlagResponse, 0, len(page.Items))
 
-	for _, f := range val {
-		flags = append(flags, FlagResponse{
-			ID:          f.ID,
-			Key:         f.Key,
-			Description: f.Description,
-			Enabled:     f.Enabled,
-			Percentage:  f.Percentage,
-			CreatedAt:   f.CreatedAt,
-			UpdatedAt:   f.UpdatedAt,
-		})
+	for _, f := range page.Items {
+		flags = append(flags, toFlagResponse(f))
 	}
 
-	resp := ListFlagsResponse{Items: flags}
+	resp := ListFlagsResponse{Items: flags, NextCursor: page.NextCursor}
 
 	writeJSON(w, http.StatusOK, resp)
 }
@@ -97,21 +94,15 @@ func (h *AdminHandler) List(w http.ResponseWriter, r *http.Request) {
 func (h *AdminHandler) GetByID(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 
-	val, err := h.repo.GetByID(id)
+	val, err := h.repo.GetByID(r.Context(), id)
 	if err != nil {
-		writeError(w, http.StatusNotFound, err.Error())
+		writeRepoError(w, err)
 		return
 	}
 
-	resp := FlagResponse{
-		ID:          val.ID,
-		Key:         val.Key,
-		Description: val.Description,
-		Enabled:     val.Enabled,
-		Percentage:  val.Percentage,
-		CreatedAt:   val.CreatedAt,
-		UpdatedAt:   val.UpdatedAt,
-	}
+	// ETag para PATCH con If-Match
+	w.Header().Set("ETag", flagETag(*val))
+	resp := toFlagResponse(*val)
 
 	writeJSON(w, http.StatusOK, resp)
 }
@@ -120,36 +111,61 @@ func (h *AdminHandler) GetByID(w http.ResponseWriter, r *http.Request) {
 func (h *AdminHandler) GetByKey(w http.ResponseWriter, r *http.Request) {
 	key := chi.URLParam(r, "key")
 
-	val, err := h.repo.GetByKey(key)
+	val, err := h.repo.GetByKey(r.Context(), key)
 	if err != nil {
-		writeError(w, http.StatusNotFound, err.Error())
+		writeRepoError(w, err)
 		return
 	}
 
-	resp := FlagResponse{
-		ID:          val.ID,
-		Key:         val.Key,
-		Description: val.Description,
-		Enabled:     val.Enabled,
-		Percentage:  val.Percentage,
-		CreatedAt:   val.CreatedAt,
-		UpdatedAt:   val.UpdatedAt,
-	}
+	resp := toFlagResponse(*val)
 
 	writeJSON(w, http.StatusOK, resp)
 }
 
 // DeleteByID maneja DELETE /flags/{id}
+// No borra: archiva la flag (deja de servirse a los SDKs) y se puede restaurar.
 func (h *AdminHandler) DeleteByID(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 
-	if _, err := h.repo.GetByID(id); err != nil {
-		writeError(w, http.StatusNotFound, err.Error())
+	err := h.repo.Archive(r.Context(), id)
+	recordMutation("archive", err)
+	if err != nil {
+		writeRepoError(w, err)
 		return
 	}
 
-	if err := h.repo.DeleteByID(id); err != nil {
-		writeError(w, http.StatusInternalServerError, err.Error())
+	writeJSON(w, http.StatusNoContent, "")
+}
+
+// Restore maneja POST /flags/{id}/restore
+func (h *AdminHandler) Restore(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	err := h.repo.Restore(r.Context(), id)
+	recordMutation("restore", err)
+	if err != nil {
+		writeRepoError(w, err)
+		return
+	}
+
+	flag, err := h.repo.GetByID(r.Context(), id)
+	if err != nil {
+		writeRepoError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, toFlagResponse(*flag))
+}
+
+// Purge maneja DELETE /flags/{id}/purge
+// Borra definitivamente una flag archivada hace mas del periodo de retencion.
+func (h *AdminHandler) Purge(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	err := h.repo.Purge(r.Context(), id, time.Now().Add(-h.retention))
+	recordMutation("purge", err)
+	if err != nil {
+		writeRepoError(w, err)
 		return
 	}
 
@@ -161,43 +177,212 @@ func (h *AdminHandler) Update(w http.ResponseWriter, r *http.Request) {
 
 	id := chi.URLParam(r, "id")
 
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeError(w, http.StatusBadRequest, err.Error())
+	if err := decodeJSON(r, &req); err != nil {
+		writeDecodeError(w, err)
 		return
 	}
 
-	flag, err := h.repo.GetByID(id)
+	flag, err := h.repo.GetByID(r.Context(), id)
 	if err != nil {
-		writeError(w, http.StatusNotFound, err.Error())
+		writeRepoError(w, err)
+		return
+	}
+
+	if err := req.Validate(*flag); err != nil {
+		writeRepoError(w, err)
 		return
 	}
 
-	flag.Description = req.Description
-	flag.Enabled = req.Enabled
-	flag.Percentage = req.Percentage
+	req.applyTo(flag)
 
-	if errUpdate := h.repo.Update(flag); errUpdate != nil {
-		writeError(w, http.StatusInternalServerError, errUpdate.Error())
+	errUpdate := h.repo.Update(r.Context(), flag)
+	recordMutation("update", errUpdate)
+	if errUpdate != nil {
+		writeRepoError(w, errUpdate)
 		return
 	}
 
-	writeJSON(w, http.StatusOK, FlagResponse{
-		ID:          flag.ID,
-		Key:         flag.Key,
-		Description: flag.Description,
-		Enabled:     flag.Enabled,
-		Percentage:  flag.Percentage,
-		CreatedAt:   flag.CreatedAt,
-		UpdatedAt:   flag.UpdatedAt,
+	writeJSON(w, http.StatusOK, toFlagResponse(*flag))
+}
+
+// StaleReport maneja GET /flags/reports/stale?unused_days=30&stuck_days=30
+func (h *AdminHandler) StaleReport(w http.ResponseWriter, r *http.Request) {
+	unusedDays, err := queryInt(r, "unused_days", 30)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	stuckDays, err := queryInt(r, "stuck_days", 30)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	flags, err := h.repo.List(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	stats, err := h.usage.Snapshot(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	now := time.Now().UTC()
+	rep := usage.BuildReport(flags, stats, usage.ReportOptions{
+		Now:       now,
+		UnusedFor: time.Duration(unusedDays) * 24 * time.Hour,
+		StuckFor:  time.Duration(stuckDays) * 24 * time.Hour,
+	})
+
+	writeJSON(w, http.StatusOK, StaleReportResponse{
+		GeneratedAt: now,
+		UnusedDays:  unusedDays,
+		StuckDays:   stuckDays,
+		Unused:      toStaleFlagResponses(rep.Unused),
+		Stuck:       toStaleFlagResponses(rep.Stuck),
+		Expired:     toStaleFlagResponses(rep.Expired),
 	})
 }
 
+// parseQuery arma los filtros del listado admin desde la query string
+func parseQuery(r *http.Request) (repo.Query, error) {
+	params := r.URL.Query()
+	q := repo.Query{
+		Owner: params.Get("owner"),
+		Tag:   params.Get("tag"),
+		Kind:  core.FlagKind(params.Get("kind")),
+	}
+	if q.Kind != "" && !q.Kind.Valid() {
+		return q, repo.ErrInvalidKind
+	}
+	var err error
+	if q.Temporary, err = queryBool(params.Get("temporary"), "temporary"); err != nil {
+		return q, err
+	}
+	if q.Enabled, err = queryBool(params.Get("enabled"), "enabled"); err != nil {
+		return q, err
+	}
+
+	q.KeyPrefix = params.Get("prefix")
+	q.Search = params.Get("q")
+
+	if raw := params.Get("updated_since"); raw != "" {
+		if q.UpdatedSince, err = time.Parse(time.RFC3339, raw); err != nil {
+			return q, fmt.Errorf("updated_since must be an RFC 3339 timestamp")
+		}
+	}
+
+	if sortBy := params.Get("sort"); sortBy != "" {
+		q.Desc = strings.HasPrefix(sortBy, "-")
+		q.SortBy = repo.SortField(strings.TrimPrefix(sortBy, "-"))
+		if !q.SortBy.Valid() {
+			return q, fmt.Errorf("sort must be one of created_at, updated_at, key")
+		}
+	}
+
+	// por defecto no se listan las archivadas
+	if q.Archived, err = queryBool(params.Get("archived"), "archived"); err != nil {
+		return q, err
+	}
+	if q.Archived == nil {
+		active := false
+		q.Archived = &active
+	}
+
+	if q.Limit, err = queryInt(r, "limit", defaultPageSize); err != nil {
+		return q, err
+	}
+	if q.Limit == 0 || q.Limit > maxPageSize {
+		q.Limit = maxPageSize
+	}
+	q.Cursor = params.Get("cursor")
+
+	return q, nil
+}
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 500
+)
+
+// queryBool parsea un filtro booleano opcional (vacio = sin filtro)
+func queryBool(raw, name string) (*bool, error) {
+	if raw == "" {
+		return nil, nil
+	}
+	v, err := strconv.ParseBool(raw)
+	if err != nil {
+		return nil, fmt.Errorf("%s must be a boolean", name)
+	}
+	return &v, nil
+}
+
+// queryInt lee un entero no negativo de la query string, con valor por defecto
+func queryInt(r *http.Request, name string, def int) (int, error) {
+	raw := r.URL.Query().Get(name)
+	if raw == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("%s must be a non-negative integer", name)
+	}
+	return n, nil
+}
+
+// recordMutation cuenta la mutacion en las metricas segun haya fallado o no
+func recordMutation(op string, err error) {
+	outcome := "ok"
+	if err != nil {
+		outcome = "error"
+	}
+	metrics.AdminMutations.WithLabelValues(op, outcome).Inc()
+}
+
 func writeJSON(w http.ResponseWriter, status int, v any) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
 	_ = json.NewEncoder(w).Encode(v)
 }
 
+// writeRepoError traduce los errores del repositorio a status HTTP
+func writeRepoError(w http.ResponseWriter, err error) {
+	var verr *core.ValidationError
+	if errors.As(err, &verr) {
+		writeJSON(w, http.StatusUnprocessableEntity, toValidationResponse(verr))
+		return
+	}
+	writeError(w, repoErrorStatus(err), err.Error())
+}
+
+// repoErrorStatus mapea un error del repo a su status HTTP
+func repoErrorStatus(err error) int {
+	var verr *core.ValidationError
+	switch {
+	case errors.As(err, &verr):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, repo.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, repo.ErrKeyAlreadyUsed),
+		errors.Is(err, repo.ErrArchived),
+		errors.Is(err, repo.ErrNotArchived),
+		errors.Is(err, repo.ErrRetention),
+		errors.Is(err, repo.ErrStale):
+		return http.StatusConflict
+	case errors.Is(err, repo.ErrReadOnly):
+		return http.StatusForbidden
+	case errors.Is(err, repo.ErrUnavailable):
+		return http.StatusServiceUnavailable
+	case repo.IsDomainError(err):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
 func writeError(w http.ResponseWriter, status int, msg string) {
 	writeJSON(w, status, ErrorResponse{Error: msg})
 }
@@ -0,0 +1,104 @@
+package httpapi
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Franconl/ffaas/internal/core"
+	"github.com/Franconl/ffaas/internal/repo/memory"
+)
+
+func sdkKeys(t *testing.T, h http.Handler) []string {
+	t.Helper()
+	rec := doRequest(h, http.MethodGet, "/sdk/flags", "", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("sdk flags: expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	resp := decodeBody[ListFlagsResponse](t, rec)
+	keys := make([]string, 0, len(resp.Items))
+	for _, f := range resp.Items {
+		keys = append(keys, f.Key)
+	}
+	return keys
+}
+
+func TestDeleteArchivesAndRestore(t *testing.T) {
+	store := memory.New()
+	flag := core.FeatureFlag{Key: "new_checkout", Enabled: true, Percentage: 100}
+	if err := store.Create(context.Background(), &flag); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	h := NewRouter(store, Options{})
+
+	if rec := doRequest(h, http.MethodDelete, "/flags/"+flag.ID, "", nil); rec.Code != http.StatusNoContent {
+		t.Fatalf("delete: expected 204, got %d: %s", rec.Code, rec.Body)
+	}
+
+	// archivada: sigue en la API de admin pero no se sirve a los SDKs
+	rec := doRequest(h, http.MethodGet, "/flags/"+flag.ID, "", nil)
+	resp := decodeBody[FlagResponse](t, rec)
+	if rec.Code != http.StatusOK || resp.ArchivedAt == nil {
+		t.Errorf("archived flag: expected 200 with archived_at, got %d: %s", rec.Code, rec.Body)
+	}
+	if keys := sdkKeys(t, h); len(keys) != 0 {
+		t.Errorf("archived flag still in /sdk/flags: %v", keys)
+	}
+	if rec := doRequest(h, http.MethodGet, "/sdk/eval?key=new_checkout&userId=u1", "", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("eval of archived flag: expected 404, got %d", rec.Code)
+	}
+
+	rec = doRequest(h, http.MethodPost, "/flags/"+flag.ID+"/restore", "", nil)
+	resp = decodeBody[FlagResponse](t, rec)
+	if rec.Code != http.StatusOK || resp.ArchivedAt != nil || resp.Key != "new_checkout" {
+		t.Fatalf("restore: expected 200 without archived_at, got %d: %s", rec.Code, rec.Body)
+	}
+	if keys := sdkKeys(t, h); len(keys) != 1 {
+		t.Errorf("restored flag not in /sdk/flags: %v", keys)
+	}
+	if rec := doRequest(h, http.MethodGet, "/sdk/eval?key=new_checkout&userId=u1", "", nil); rec.Code != http.StatusOK {
+		t.Errorf("eval of restored flag: expected 200, got %d", rec.Code)
+	}
+
+	// restaurar una flag activa es un conflicto
+	if rec := doRequest(h, http.MethodPost, "/flags/"+flag.ID+"/restore", "", nil); rec.Code != http.StatusConflict {
+		t.Errorf("restore of active flag: expected 409, got %d", rec.Code)
+	}
+	if rec := doRequest(h, http.MethodPost, "/flags/00000000-0000-0000-0000-000000000000/restore", "", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("restore of missing flag: expected 404, got %d", rec.Code)
+	}
+}
+
+func TestPurgeRespectsRetention(t *testing.T) {
+	store := memory.New()
+	flag := core.FeatureFlag{Key: "old_banner"}
+	if err := store.Create(context.Background(), &flag); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	retained := NewRouter(store, Options{ArchiveRetention: time.Hour})
+	h := NewRouter(store, Options{})
+
+	// solo se purgan flags archivadas
+	if rec := doRequest(h, http.MethodDelete, "/flags/"+flag.ID+"/purge", "", nil); rec.Code != http.StatusConflict {
+		t.Errorf("purge of active flag: expected 409, got %d", rec.Code)
+	}
+
+	if rec := doRequest(h, http.MethodDelete, "/flags/"+flag.ID, "", nil); rec.Code != http.StatusNoContent {
+		t.Fatalf("delete: expected 204, got %d", rec.Code)
+	}
+	if rec := doRequest(retained, http.MethodDelete, "/flags/"+flag.ID+"/purge", "", nil); rec.Code != http.StatusConflict {
+		t.Errorf("purge within retention: expected 409, got %d: %s", rec.Code, rec.Body)
+	}
+
+	// sin retencion ya paso el periodo
+	if rec := doRequest(h, http.MethodDelete, "/flags/"+flag.ID+"/purge", "", nil); rec.Code != http.StatusNoContent {
+		t.Fatalf("purge: expected 204, got %d: %s", rec.Code, rec.Body)
+	}
+	if rec := doRequest(h, http.MethodGet, "/flags/"+flag.ID, "", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("purged flag: expected 404, got %d", rec.Code)
+	}
+	if rec := doRequest(h, http.MethodPost, "/flags/"+flag.ID+"/restore", "", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("restore of purged flag: expected 404, got %d", rec.Code)
+	}
+}
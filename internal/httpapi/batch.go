@@ -0,0 +1,204 @@
+package httpapi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/Franconl/ffaas/internal/core"
+	"github.com/Franconl/ffaas/internal/repo"
+)
+
+const maxBatchOperations = 100
+
+// Resultados de cada operacion del lote
+const (
+	batchApplied    = "applied"
+	batchFailed     = "failed"
+	batchRolledBack = "rolled_back"
+)
+
+// Batch maneja POST /flags/batch: aplica todas las operaciones juntas con
+// repo.Apply (una transaccion en postgres/sqlite, un solo lock en memory) o
+// ninguna. Devuelve el resultado de cada operacion; si alguna falla, el status
+// de la respuesta es el de esa operacion.
+func (h *AdminHandler) Batch(w http.ResponseWriter, r *http.Request) {
+	var req BatchRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+	if n := len(req.Operations); n == 0 || n > maxBatchOperations {
+		verr := &core.ValidationError{}
+		verr.Add("operations", repo.ErrInvalidBody, "must have between 1 and %d operations", maxBatchOperations)
+		writeRepoError(w, verr)
+		return
+	}
+
+	resp := BatchResponse{Results: make([]BatchResultResponse, len(req.Operations))}
+	changes := make([]repo.Change, 0, len(req.Operations))
+	targets := make(map[string]int)
+	var failed error
+
+	// primero se arman y validan todos los cambios: si alguno no sirve no se
+	// toca el repo
+	for i, op := range req.Operations {
+		resp.Results[i] = BatchResultResponse{Index: i, Op: op.Op, Result: batchRolledBack}
+
+		change, err := h.batchChange(r.Context(), op)
+		if err == nil && change.Op != repo.ChangeCreate {
+			// dos operaciones sobre la misma flag verian el mismo estado inicial
+			if j, dup := targets[change.Flag.ID]; dup {
+				verr := &core.ValidationError{}
+				verr.Add("id", repo.ErrInvalidBody, "flag is already changed by operation %d", j)
+				err = verr
+			}
+			targets[change.Flag.ID] = i
+		}
+		if err != nil {
+			setBatchError(&resp.Results[i], err)
+			if failed == nil {
+				failed = err
+			}
+			continue
+		}
+		changes = append(changes, change)
+	}
+	if failed != nil {
+		resp.Error = "batch not applied: " + failed.Error()
+		writeJSON(w, repoErrorStatus(failed), resp)
+		return
+	}
+
+	err := h.repo.Apply(r.Context(), changes)
+	recordMutation("batch", err)
+	if err != nil {
+		var cerr *repo.ChangeError
+		if !errors.As(err, &cerr) || cerr.Index >= len(resp.Results) {
+			writeRepoError(w, err)
+			return
+		}
+		setBatchError(&resp.Results[cerr.Index], cerr.Err)
+		resp.Error = "batch rolled back: " + err.Error()
+		writeJSON(w, repoErrorStatus(cerr.Err), resp)
+		return
+	}
+
+	resp.Applied = true
+	for i, c := range changes {
+		res := &resp.Results[i]
+		res.Result = batchApplied
+		res.Status = http.StatusOK
+		if c.Op == repo.ChangeCreate {
+			res.Status = http.StatusCreated
+		}
+		// Apply deja en cada cambio lo que quedo guardado (ids y timestamps)
+		fr := toFlagResponse(c.Flag)
+		res.Flag = &fr
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// batchChange arma el cambio de una operacion contra el estado actual de la flag
+func (h *AdminHandler) batchChange(ctx context.Context, op BatchOperation) (repo.Change, error) {
+	switch op.Op {
+	case "create":
+		if op.ID != "" || op.Key != "" {
+			verr := &core.ValidationError{}
+			verr.Add("id", repo.ErrInvalidBody, "create takes the key inside flag, not id or key")
+			return repo.Change{}, verr
+		}
+		var req CreateFlagRequest
+		if err := decodeBatchFlag(op.Flag, &req); err != nil {
+			return repo.Change{}, err
+		}
+		f := req.toFlag()
+		if err := f.Validate(); err != nil {
+			return repo.Change{}, prefixFields(err, "flag.")
+		}
+		return repo.Change{Op: repo.ChangeCreate, Flag: f}, nil
+
+	case "update":
+		cur, err := h.batchTarget(ctx, op)
+		if err != nil {
+			return repo.Change{}, err
+		}
+		var req UpdateFlagRequest
+		if err := decodeBatchFlag(op.Flag, &req); err != nil {
+			return repo.Change{}, err
+		}
+		if err := req.Validate(*cur); err != nil {
+			return repo.Change{}, prefixFields(err, "flag.")
+		}
+		req.applyTo(cur)
+		return repo.Change{Op: repo.ChangeUpdate, Flag: *cur}, nil
+
+	case "delete":
+		if len(op.Flag) > 0 {
+			verr := &core.ValidationError{}
+			verr.Add("flag", repo.ErrInvalidBody, "not allowed for delete")
+			return repo.Change{}, verr
+		}
+		cur, err := h.batchTarget(ctx, op)
+		if err != nil {
+			return repo.Change{}, err
+		}
+		return repo.Change{Op: repo.ChangeArchive, Flag: *cur}, nil
+
+	default:
+		verr := &core.ValidationError{}
+		verr.Add("op", repo.ErrInvalidBody, "must be one of create, update, delete")
+		return repo.Change{}, verr
+	}
+}
+
+// batchTarget busca la flag de un update/delete por id o por key (uno de los dos)
+func (h *AdminHandler) batchTarget(ctx context.Context, op BatchOperation) (*core.FeatureFlag, error) {
+	switch {
+	case op.ID != "" && op.Key != "":
+		verr := &core.ValidationError{}
+		verr.Add("id", repo.ErrInvalidBody, "use either id or key, not both")
+		return nil, verr
+	case op.ID != "":
+		return h.repo.GetByID(ctx, op.ID)
+	case op.Key != "":
+		return h.repo.GetByKey(ctx, op.Key)
+	default:
+		verr := &core.ValidationError{}
+		verr.Add("id", repo.ErrInvalidBody, "id or key is required")
+		return nil, verr
+	}
+}
+
+func decodeBatchFlag(raw []byte, dst any) error {
+	if len(raw) == 0 {
+		verr := &core.ValidationError{}
+		verr.Add("flag", repo.ErrInvalidBody, "is required")
+		return verr
+	}
+	return prefixFields(decodeStrict(raw, dst), "flag.")
+}
+
+// prefixFields ubica los errores de campo dentro de la operacion ("flag.percentage")
+func prefixFields(err error, prefix string) error {
+	var verr *core.ValidationError
+	if !errors.As(err, &verr) {
+		return err
+	}
+	out := &core.ValidationError{Fields: make([]core.FieldError, len(verr.Fields))}
+	for i, f := range verr.Fields {
+		f.Field = prefix + f.Field
+		out.Fields[i] = f
+	}
+	return out
+}
+
+func setBatchError(res *BatchResultResponse, err error) {
+	res.Result = batchFailed
+	res.Status = repoErrorStatus(err)
+	res.Error = err.Error()
+	var verr *core.ValidationError
+	if errors.As(err, &verr) {
+		res.Fields = toValidationResponse(verr).Fields
+	}
+}
@@ -0,0 +1,76 @@
+package httpapi
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/Franconl/ffaas/internal/core"
+	"github.com/Franconl/ffaas/internal/repo/memory"
+)
+
+func TestBatch(t *testing.T) {
+	ctx := context.Background()
+	store := memory.New()
+	checkout := core.FeatureFlag{Key: "new_checkout", Percentage: 10}
+	legacy := core.FeatureFlag{Key: "legacy_checkout", Enabled: true}
+	for _, f := range []*core.FeatureFlag{&checkout, &legacy} {
+		if err := store.Create(ctx, f); err != nil {
+			t.Fatal(err)
+		}
+	}
+	h := NewRouter(store, Options{})
+
+	// la segunda operacion falla (key repetida): no se aplica ninguna
+	rec := doRequest(h, http.MethodPost, "/flags/batch", `{"operations": [
+		{"op": "update", "key": "new_checkout", "flag": {"enabled": true, "percentage": 100}},
+		{"op": "create", "flag": {"key": "legacy_checkout"}}
+	]}`, nil)
+	resp := decodeBody[BatchResponse](t, rec)
+	if rec.Code != http.StatusConflict || resp.Applied {
+		t.Fatalf("expected 409 not applied, got %d %+v", rec.Code, resp)
+	}
+	if r := resp.Results; r[0].Result != batchRolledBack || r[1].Result != batchFailed || r[1].Status != http.StatusConflict {
+		t.Errorf("unexpected results: %+v", r)
+	}
+	if f, _ := store.GetByKey(ctx, "new_checkout"); f.Enabled {
+		t.Error("update was applied despite the rollback")
+	}
+
+	// validacion: 422 con el campo dentro de la operacion
+	rec = doRequest(h, http.MethodPost, "/flags/batch", `{"operations": [
+		{"op": "update", "id": "`+checkout.ID+`", "flag": {"percentage": 101}}
+	]}`, nil)
+	resp = decodeBody[BatchResponse](t, rec)
+	if rec.Code != http.StatusUnprocessableEntity || len(resp.Results[0].Fields) != 1 || resp.Results[0].Fields[0].Field != "flag.percentage" {
+		t.Fatalf("expected 422 on flag.percentage, got %d %+v", rec.Code, resp)
+	}
+
+	rec = doRequest(h, http.MethodPost, "/flags/batch", `{"operations": [
+		{"op": "update", "key": "new_checkout", "flag": {"enabled": true, "percentage": 100}},
+		{"op": "create", "flag": {"key": "checkout_banner", "enabled": true, "percentage": 100}},
+		{"op": "delete", "id": "`+legacy.ID+`"}
+	]}`, nil)
+	resp = decodeBody[BatchResponse](t, rec)
+	if rec.Code != http.StatusOK || !resp.Applied {
+		t.Fatalf("expected 200 applied, got %d %+v", rec.Code, resp)
+	}
+	for _, r := range resp.Results {
+		if r.Result != batchApplied || r.Flag == nil {
+			t.Errorf("unexpected result: %+v", r)
+		}
+	}
+	if resp.Results[1].Status != http.StatusCreated || resp.Results[1].Flag.ID == "" {
+		t.Errorf("create result: %+v", resp.Results[1])
+	}
+	if f, _ := store.GetByID(ctx, legacy.ID); !f.Archived() {
+		t.Error("expected legacy_checkout archived")
+	}
+	// la respuesta es lo que quedo guardado, no lo que se mando
+	if f := resp.Results[2].Flag; f.ID != legacy.ID || f.ArchivedAt == nil {
+		t.Errorf("delete result: %+v", f)
+	}
+	if f, _ := store.GetByKey(ctx, "checkout_banner"); f == nil || f.ID != resp.Results[1].Flag.ID {
+		t.Errorf("created id %q does not match the stored flag", resp.Results[1].Flag.ID)
+	}
+}
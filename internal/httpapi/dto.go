@@ -1,42 +1,80 @@
 package httpapi
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/Franconl/ffaas/internal/core"
+	"github.com/Franconl/ffaas/internal/usage"
+)
 
 // --- Requests ---
 
 type CreateFlagRequest struct {
-	Key         string `json:"key"`
-	Description string `json:"description"`
-	Enabled     bool   `json:"enabled"`
-	Percentage  int    `json:"percentage"`
+	Key         string     `json:"key"`
+	Description string     `json:"description"`
+	Enabled     bool       `json:"enabled"`
+	Percentage  int        `json:"percentage"`
+	Owner       string     `json:"owner"`
+	Tags        []string   `json:"tags"`
+	Kind        string     `json:"kind"`
+	Temporary   bool       `json:"temporary"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
 }
 
 type UpdateFlagRequest struct {
-	Key         string `json:"key"`
-	Description string `json:"description"`
-	Enabled     bool   `json:"enabled"`
-	Percentage  int    `json:"percentage"`
+	Key         string     `json:"key"`
+	Description string     `json:"description"`
+	Enabled     bool       `json:"enabled"`
+	Percentage  int        `json:"percentage"`
+	Owner       string     `json:"owner"`
+	Tags        []string   `json:"tags"`
+	Kind        string     `json:"kind"`
+	Temporary   bool       `json:"temporary"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
 }
 
 // --- Responses ---
 
 type FlagResponse struct {
-	ID          string    `json:"id"`
-	Key         string    `json:"key"`
-	Description string    `json:"description"`
-	Enabled     bool      `json:"enabled"`
-	Percentage  int       `json:"percentage"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID          string     `json:"id"`
+	Key         string     `json:"key"`
+	Description string     `json:"description"`
+	Enabled     bool       `json:"enabled"`
+	Percentage  int        `json:"percentage"`
+	Owner       string     `json:"owner"`
+	Tags        []string   `json:"tags"`
+	Kind        string     `json:"kind"`
+	Temporary   bool       `json:"temporary"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	ArchivedAt  *time.Time `json:"archived_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	// ultima vez que cambio enabled o percentage
+	RolloutChangedAt time.Time `json:"rollout_changed_at,omitzero"`
 }
 
 type ErrorResponse struct {
 	Error string `json:"error"`
 }
 
+// 422: un error por campo invalido, con el path del campo (ej: "tags[2]")
+type ValidationErrorResponse struct {
+	Error  string               `json:"error"`
+	Fields []FieldErrorResponse `json:"fields"`
+}
+
+type FieldErrorResponse struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
 // Para listas (ej: GET /api/flags)
 type ListFlagsResponse struct {
-	Items []FlagResponse `json:"items"`
+	Items      []FlagResponse `json:"items"`
+	NextCursor string         `json:"next_cursor,omitempty"`
+	// Version del snapshot en /sdk/flags (0 si el backend no versiona)
+	Version uint64 `json:"version,omitempty"`
 }
 
 // Para SDK /sdk/eval
@@ -44,4 +82,173 @@ type EvalResponse struct {
 	Key     string `json:"key"`
 	UserID  string `json:"user_id"`
 	Enabled bool   `json:"enabled"`
+	// Variant es "on" u "off"; Reason es DISABLED, STATIC o SPLIT (ver core.Evaluation)
+	Variant string `json:"variant"`
+	Reason  string `json:"reason"`
+}
+
+// Para SDK POST /sdk/usage: evaluaciones locales agregadas por el SDK desde el
+// ultimo envio
+type UsageReportRequest struct {
+	Items []UsageReportItem `json:"items"`
+}
+
+type UsageReportItem struct {
+	Key             string    `json:"key"`
+	Evaluations     int64     `json:"evaluations"`
+	LastEvaluatedAt time.Time `json:"last_evaluated_at"`
+}
+
+// Para OFREP (/ofrep/v1/evaluate/flags): OpenFeature Remote Evaluation Protocol
+type OfrepRequest struct {
+	Context json.RawMessage `json:"context"`
+}
+
+// OfrepEvaluation es el resultado de una flag: Value/Reason/Variant si se
+// pudo evaluar, ErrorCode/ErrorDetails si no
+type OfrepEvaluation struct {
+	Key          string `json:"key"`
+	Value        *bool  `json:"value,omitempty"`
+	Reason       string `json:"reason,omitempty"`
+	Variant      string `json:"variant,omitempty"`
+	ErrorCode    string `json:"errorCode,omitempty"`
+	ErrorDetails string `json:"errorDetails,omitempty"`
+}
+
+type OfrepBulkResponse struct {
+	Flags []OfrepEvaluation `json:"flags"`
+}
+
+// OfrepErrorResponse es el error de un request entero (contexto invalido en
+// bulk, o 5xx); el de una flag va en OfrepEvaluation
+type OfrepErrorResponse struct {
+	ErrorCode    string `json:"errorCode,omitempty"`
+	ErrorDetails string `json:"errorDetails"`
+}
+
+// Para GET /flags/reports/stale
+type StaleReportResponse struct {
+	GeneratedAt time.Time           `json:"generated_at"`
+	UnusedDays  int                 `json:"unused_days"`
+	StuckDays   int                 `json:"stuck_days"`
+	Unused      []StaleFlagResponse `json:"unused"`
+	Stuck       []StaleFlagResponse `json:"stuck"`
+	Expired     []StaleFlagResponse `json:"expired"`
+}
+
+type StaleFlagResponse struct {
+	ID              string     `json:"id"`
+	Key             string     `json:"key"`
+	Enabled         bool       `json:"enabled"`
+	Percentage      int        `json:"percentage"`
+	Evaluations     int64      `json:"evaluations"`
+	LastEvaluatedAt *time.Time `json:"last_evaluated_at,omitempty"`
+	ExpiresAt       *time.Time `json:"expires_at,omitempty"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+	// Stuck se calcula con esta fecha, no con updated_at
+	RolloutChangedAt time.Time `json:"rollout_changed_at,omitzero"`
+}
+
+// --- Mappers ---
+
+func toFlagResponse(f core.FeatureFlag) FlagResponse {
+	return FlagResponse{
+		ID:          f.ID,
+		Key:         f.Key,
+		Description: f.Description,
+		Enabled:     f.Enabled,
+		Percentage:  f.Percentage,
+		Owner:       f.Owner,
+		Tags:        tagsOrEmpty(f.Tags),
+		Kind:        string(f.Kind),
+		Temporary:   f.Temporary,
+		ExpiresAt:   f.ExpiresAt,
+		ArchivedAt:  f.ArchivedAt,
+		CreatedAt:   f.CreatedAt,
+		UpdatedAt:   f.UpdatedAt,
+
+		RolloutChangedAt: f.RolloutChangedAt,
+	}
+}
+
+// tagsOrEmpty hace que tags se serialice como [] y no como null
+func tagsOrEmpty(tags []string) []string {
+	if tags == nil {
+		return []string{}
+	}
+	return tags
+}
+
+func toStaleFlagResponses(list []usage.StaleFlag) []StaleFlagResponse {
+	out := make([]StaleFlagResponse, 0, len(list))
+	for _, item := range list {
+		resp := StaleFlagResponse{
+			ID:          item.Flag.ID,
+			Key:         item.Flag.Key,
+			Enabled:     item.Flag.Enabled,
+			Percentage:  item.Flag.Percentage,
+			Evaluations: item.Usage.Evaluations,
+			ExpiresAt:   item.Flag.ExpiresAt,
+			UpdatedAt:   item.Flag.UpdatedAt,
+
+			RolloutChangedAt: item.Flag.RolloutChangedAt,
+		}
+		if !item.Usage.LastEvaluatedAt.IsZero() {
+			last := item.Usage.LastEvaluatedAt
+			resp.LastEvaluatedAt = &last
+		}
+		out = append(out, resp)
+	}
+	return out
+}
+
+// Import: resumen y detalle por key de lo que se aplico (o se aplicaria en dry run)
+type ImportResponse struct {
+	Mode      string               `json:"mode"`
+	DryRun    bool                 `json:"dry_run"`
+	Created   int                  `json:"created"`
+	Updated   int                  `json:"updated"`
+	Archived  int                  `json:"archived"`
+	Unchanged int                  `json:"unchanged"`
+	Skipped   int                  `json:"skipped"`
+	Diff      []ImportDiffResponse `json:"diff"`
+}
+
+type ImportDiffResponse struct {
+	Key    string   `json:"key"`
+	Action string   `json:"action"`
+	Fields []string `json:"fields,omitempty"`
+}
+
+// Para POST /flags/batch
+type BatchRequest struct {
+	Operations []BatchOperation `json:"operations"`
+}
+
+// BatchOperation: create lleva la flag completa en flag (como POST /flags);
+// update lleva los campos en flag (como PUT /flags/{id}); delete archiva. update
+// y delete identifican la flag por id o por key.
+type BatchOperation struct {
+	Op   string          `json:"op"`
+	ID   string          `json:"id,omitempty"`
+	Key  string          `json:"key,omitempty"`
+	Flag json.RawMessage `json:"flag,omitempty"`
+}
+
+type BatchResponse struct {
+	Applied bool                  `json:"applied"`
+	Error   string                `json:"error,omitempty"`
+	Results []BatchResultResponse `json:"results"`
+}
+
+// BatchResultResponse: Result es applied, failed (la operacion que corto el
+// lote, con su Status y Error) o rolled_back (no se aplico por otra operacion)
+type BatchResultResponse struct {
+	Index  int                  `json:"index"`
+	Op     string               `json:"op"`
+	Result string               `json:"result"`
+	Status int                  `json:"status,omitempty"`
+	Error  string               `json:"error,omitempty"`
+	Fields []FieldErrorResponse `json:"fields,omitempty"`
+	Flag   *FlagResponse        `json:"flag,omitempty"`
 }
@@ -0,0 +1,186 @@
+package httpapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Franconl/ffaas/internal/core"
+	"github.com/Franconl/ffaas/internal/metrics"
+	"github.com/Franconl/ffaas/internal/repo"
+	"github.com/go-chi/chi/v5"
+)
+
+// OpenFeature Remote Evaluation Protocol: cualquier provider OFREP puede
+// evaluar contra ffaas sin un SDK propio. El targeting key del contexto es el
+// userId de /sdk/eval; el resto de los atributos se ignoran (las flags no
+// tienen reglas de targeting).
+
+// Codigos de error de OFREP
+const (
+	ofrepFlagNotFound        = "FLAG_NOT_FOUND"
+	ofrepParseError          = "PARSE_ERROR"
+	ofrepTargetingKeyMissing = "TARGETING_KEY_MISSING"
+	ofrepInvalidContext      = "INVALID_CONTEXT"
+	ofrepGeneral             = "GENERAL"
+)
+
+// ofrepError es un error de request con su codigo OFREP (siempre 400)
+type ofrepError struct {
+	code    string
+	details string
+}
+
+func (e *ofrepError) Error() string { return e.code + ": " + e.details }
+
+// ofrepTargetingKey lee el body ({"context": {...}}) y devuelve el targeting key
+func ofrepTargetingKey(r *http.Request) (string, error) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxBodyBytes))
+	if err != nil {
+		return "", &ofrepError{ofrepParseError, "could not read body"}
+	}
+
+	// body vacio es un contexto vacio
+	var req OfrepRequest
+	if len(bytes.TrimSpace(body)) > 0 {
+		if err := json.Unmarshal(body, &req); err != nil {
+			return "", &ofrepError{ofrepParseError, "invalid JSON body"}
+		}
+	}
+
+	var evalCtx map[string]any
+	if len(req.Context) > 0 && string(req.Context) != "null" {
+		if err := json.Unmarshal(req.Context, &evalCtx); err != nil {
+			return "", &ofrepError{ofrepInvalidContext, "context must be an object"}
+		}
+	}
+
+	raw, ok := evalCtx["targetingKey"]
+	if !ok {
+		return "", &ofrepError{ofrepTargetingKeyMissing, "targetingKey is required"}
+	}
+	key, ok := raw.(string)
+	if !ok {
+		return "", &ofrepError{ofrepInvalidContext, "targetingKey must be a string"}
+	}
+	if key == "" {
+		return "", &ofrepError{ofrepTargetingKeyMissing, "targetingKey is required"}
+	}
+	return key, nil
+}
+
+func toOfrepEvaluation(key string, e core.Evaluation) OfrepEvaluation {
+	return OfrepEvaluation{Key: key, Value: &e.Enabled, Reason: string(e.Reason), Variant: e.Variant}
+}
+
+// writeOfrepServerError: backend caido es 503, cualquier otra falla 500
+func writeOfrepServerError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	if errors.Is(err, repo.ErrUnavailable) {
+		status = http.StatusServiceUnavailable
+	}
+	writeJSON(w, status, OfrepErrorResponse{ErrorCode: ofrepGeneral, ErrorDetails: err.Error()})
+}
+
+// OfrepEvaluate sirve POST /ofrep/v1/evaluate/flags/{key}: lo mismo que
+// /sdk/eval (cuenta para metricas y para el reporte de uso) en formato OFREP
+func (h *SdkHandler) OfrepEvaluate(w http.ResponseWriter, r *http.Request) {
+	key := chi.URLParam(r, "key")
+
+	userID, err := ofrepTargetingKey(r)
+	if err != nil {
+		var oerr *ofrepError
+		errors.As(err, &oerr)
+		writeJSON(w, http.StatusBadRequest, OfrepEvaluation{Key: key, ErrorCode: oerr.code, ErrorDetails: oerr.details})
+		return
+	}
+
+	ctx, stale := repo.TrackStale(r.Context())
+	f, err := h.repo.GetByKey(ctx, key)
+	if errors.Is(err, repo.ErrNotFound) || (err == nil && f.Archived()) {
+		writeJSON(w, http.StatusNotFound, OfrepEvaluation{Key: key, ErrorCode: ofrepFlagNotFound, ErrorDetails: "flag not found"})
+		return
+	}
+	if err != nil {
+		writeOfrepServerError(w, err)
+		return
+	}
+	writeStaleHeaders(w, stale)
+
+	eval := f.Evaluate(userID)
+	metrics.FlagEvaluations.WithLabelValues(f.Key, strconv.FormatBool(eval.Enabled)).Inc()
+	h.usage.Record(f.ID, time.Now().UTC())
+
+	writeJSON(w, http.StatusOK, toOfrepEvaluation(f.Key, eval))
+}
+
+// OfrepBulk sirve POST /ofrep/v1/evaluate/flags: todas las flags activas
+// evaluadas para el targeting key. Como /sdk/flags, no cuenta para el reporte
+// de uso (el cliente evalua todo aunque use una sola).
+//
+// El resultado depende solo de la lista de flags y del targeting key: con
+// Snapshotter el ETag es la version mas un hash del targeting key y el 304 se
+// responde sin evaluar nada; sin version se hashea la respuesta.
+func (h *SdkHandler) OfrepBulk(w http.ResponseWriter, r *http.Request) {
+	userID, err := ofrepTargetingKey(r)
+	if err != nil {
+		var oerr *ofrepError
+		errors.As(err, &oerr)
+		writeJSON(w, http.StatusBadRequest, OfrepErrorResponse{ErrorCode: oerr.code, ErrorDetails: oerr.details})
+		return
+	}
+
+	ctx, stale := repo.TrackStale(r.Context())
+	snap, err := h.snapshot(r.WithContext(ctx))
+	if err != nil {
+		writeOfrepServerError(w, err)
+		return
+	}
+	writeStaleHeaders(w, stale)
+	w.Header().Set("Cache-Control", "no-cache")
+
+	var etag string
+	if snap.Version != 0 {
+		etag = fmt.Sprintf(`"%d-%x"`, snap.Version, hash64([]byte(userID)))
+		w.Header().Set("ETag", etag)
+		if etagMatches(r.Header.Get("If-None-Match"), etag) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+
+	resp := OfrepBulkResponse{Flags: make([]OfrepEvaluation, 0, len(snap.Flags))}
+	for _, f := range snap.Flags {
+		resp.Flags = append(resp.Flags, toOfrepEvaluation(f.Key, f.Evaluate(userID)))
+	}
+
+	body, err := json.Marshal(resp)
+	if err != nil {
+		writeOfrepServerError(w, err)
+		return
+	}
+	if etag == "" {
+		etag = fmt.Sprintf(`"%x"`, hash64(body))
+		w.Header().Set("ETag", etag)
+		if etagMatches(r.Header.Get("If-None-Match"), etag) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(append(body, '\n'))
+}
+
+func hash64(b []byte) uint64 {
+	h := fnv.New64a()
+	h.Write(b)
+	return h.Sum64()
+}
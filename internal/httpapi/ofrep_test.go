@@ -0,0 +1,114 @@
+package httpapi
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/Franconl/ffaas/internal/core"
+	"github.com/Franconl/ffaas/internal/repo"
+	"github.com/Franconl/ffaas/internal/repo/memory"
+)
+
+func TestOfrepEvaluate(t *testing.T) {
+	store := memory.New()
+	ctx := context.Background()
+	half := core.FeatureFlag{Key: "half", Enabled: true, Percentage: 50}
+	for _, f := range []*core.FeatureFlag{&half, {Key: "archived", Enabled: true, Percentage: 100}} {
+		if err := store.Create(ctx, f); err != nil {
+			t.Fatal(err)
+		}
+	}
+	archived, _ := store.GetByKey(ctx, "archived")
+	store.Archive(ctx, archived.ID)
+	h := NewRouter(store, Options{})
+
+	rec := doRequest(h, http.MethodPost, "/ofrep/v1/evaluate/flags/half", `{"context":{"targetingKey":"user-7","plan":"pro"}}`, nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	got := decodeBody[OfrepEvaluation](t, rec)
+	want := half.Evaluate("user-7")
+	if got.Key != "half" || got.Value == nil || *got.Value != want.Enabled || got.Variant != want.Variant || got.Reason != "SPLIT" {
+		t.Errorf("unexpected evaluation %s", rec.Body)
+	}
+
+	cases := []struct {
+		path, body string
+		status     int
+		code       string
+	}{
+		{"/ofrep/v1/evaluate/flags/missing", `{"context":{"targetingKey":"u1"}}`, http.StatusNotFound, ofrepFlagNotFound},
+		{"/ofrep/v1/evaluate/flags/archived", `{"context":{"targetingKey":"u1"}}`, http.StatusNotFound, ofrepFlagNotFound},
+		{"/ofrep/v1/evaluate/flags/half", `{"context":{}}`, http.StatusBadRequest, ofrepTargetingKeyMissing},
+		{"/ofrep/v1/evaluate/flags/half", ``, http.StatusBadRequest, ofrepTargetingKeyMissing},
+		{"/ofrep/v1/evaluate/flags/half", `{"context":`, http.StatusBadRequest, ofrepParseError},
+		{"/ofrep/v1/evaluate/flags/half", `{"context":"u1"}`, http.StatusBadRequest, ofrepInvalidContext},
+		{"/ofrep/v1/evaluate/flags/half", `{"context":{"targetingKey":42}}`, http.StatusBadRequest, ofrepInvalidContext},
+	}
+	for _, c := range cases {
+		rec := doRequest(h, http.MethodPost, c.path, c.body, nil)
+		resp := decodeBody[OfrepEvaluation](t, rec)
+		if rec.Code != c.status || resp.ErrorCode != c.code || resp.Value != nil {
+			t.Errorf("%s %q: expected %d %s, got %d %s", c.path, c.body, c.status, c.code, rec.Code, rec.Body)
+		}
+	}
+}
+
+func TestOfrepBulkETag(t *testing.T) {
+	flags := []core.FeatureFlag{
+		{Key: "all", Enabled: true, Percentage: 100},
+		{Key: "off", Enabled: false, Percentage: 100},
+	}
+	body := `{"context":{"targetingKey":"user-1"}}`
+
+	// con version: ETag sin evaluar; sin version: hash de la respuesta
+	for name, opts := range map[string]Options{
+		"versioned": {Snapshots: fixedSnapshots{repo.Snapshot{Version: 7, Flags: flags}}},
+		"hashed":    {},
+	} {
+		store := memory.New()
+		if name == "hashed" {
+			for _, f := range flags {
+				store.Create(context.Background(), &f)
+			}
+		}
+		h := NewRouter(store, opts)
+
+		rec := doRequest(h, http.MethodPost, "/ofrep/v1/evaluate/flags", body, nil)
+		etag := rec.Header().Get("ETag")
+		if rec.Code != http.StatusOK || etag == "" {
+			t.Fatalf("%s: expected 200 with ETag, got %d %q", name, rec.Code, etag)
+		}
+		resp := decodeBody[OfrepBulkResponse](t, rec)
+		if len(resp.Flags) != 2 {
+			t.Fatalf("%s: unexpected body %s", name, rec.Body)
+		}
+		for _, f := range resp.Flags {
+			if f.Value == nil || *f.Value != (f.Key == "all") {
+				t.Errorf("%s: unexpected evaluation %+v", name, f)
+			}
+		}
+
+		rec = doRequest(h, http.MethodPost, "/ofrep/v1/evaluate/flags", body, http.Header{"If-None-Match": {etag}})
+		if rec.Code != http.StatusNotModified {
+			t.Errorf("%s: expected 304, got %d", name, rec.Code)
+		}
+		// otro targeting key es otro ETag (sin version depende del body: con estas
+		// flags estaticas la respuesta es la misma y el 304 seria correcto)
+		other := `{"context":{"targetingKey":"user-2"}}`
+		if name == "versioned" {
+			rec = doRequest(h, http.MethodPost, "/ofrep/v1/evaluate/flags", other, http.Header{"If-None-Match": {etag}})
+			if rec.Code != http.StatusOK {
+				t.Errorf("%s: other targeting key expected 200, got %d", name, rec.Code)
+			}
+		}
+	}
+
+	h := NewRouter(memory.New(), Options{})
+	rec := doRequest(h, http.MethodPost, "/ofrep/v1/evaluate/flags", `{"context":{"plan":"pro"}}`, nil)
+	resp := decodeBody[OfrepErrorResponse](t, rec)
+	if rec.Code != http.StatusBadRequest || resp.ErrorCode != ofrepTargetingKeyMissing {
+		t.Errorf("expected 400 TARGETING_KEY_MISSING, got %d %s", rec.Code, rec.Body)
+	}
+}
@@ -0,0 +1,231 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Franconl/ffaas/internal/core"
+	"github.com/Franconl/ffaas/internal/repo"
+	jsonpatch "github.com/evanphx/json-patch/v5"
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	contentTypeMergePatch = "application/merge-patch+json"
+	contentTypeJSONPatch  = "application/json-patch+json"
+
+	maxPatchBytes = 1 << 20
+	// maxPatchAttempts acota los reintentos de un patch sin If-Match que choca
+	// con escrituras concurrentes
+	maxPatchAttempts = 3
+)
+
+var errReadOnlyField = errors.New("field is read-only")
+
+// flagDocument es la representacion de la flag sobre la que se aplican los patches.
+// A diferencia de FlagResponse no usa omitempty, asi todos los paths existen
+// y un JSON Patch "replace /expires_at" funciona aunque la flag no tenga fecha.
+type flagDocument struct {
+	ID          string     `json:"id"`
+	Key         string     `json:"key"`
+	Description string     `json:"description"`
+	Enabled     bool       `json:"enabled"`
+	Percentage  int        `json:"percentage"`
+	Owner       string     `json:"owner"`
+	Tags        []string   `json:"tags"`
+	Kind        string     `json:"kind"`
+	Temporary   bool       `json:"temporary"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+	ArchivedAt  *time.Time `json:"archived_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+
+	RolloutChangedAt time.Time `json:"rollout_changed_at"`
+}
+
+func toFlagDocument(f core.FeatureFlag) flagDocument {
+	return flagDocument{
+		ID:          f.ID,
+		Key:         f.Key,
+		Description: f.Description,
+		Enabled:     f.Enabled,
+		Percentage:  f.Percentage,
+		Owner:       f.Owner,
+		Tags:        tagsOrEmpty(f.Tags),
+		Kind:        string(f.Kind),
+		Temporary:   f.Temporary,
+		ExpiresAt:   f.ExpiresAt,
+		ArchivedAt:  f.ArchivedAt,
+		CreatedAt:   f.CreatedAt,
+		UpdatedAt:   f.UpdatedAt,
+
+		RolloutChangedAt: f.RolloutChangedAt,
+	}
+}
+
+// Patch maneja PATCH /flags/{id}
+// Acepta RFC 7396 (application/merge-patch+json) y RFC 6902 (application/json-patch+json)
+// sobre el modelo completo de la flag; id, created_at, updated_at,
+// rollout_changed_at y archived_at son de solo lectura.
+//
+// El resultado se guarda con un update condicional al updated_at leido, asi
+// dos patches concurrentes no se pisan. Con If-Match (el ETag de GET /flags/{id})
+// el patch se aplica solo sobre esa version y si no, 412. Sin If-Match, si la
+// flag cambio entre la lectura y la escritura se vuelve a leer y aplicar el
+// patch (los "test" de JSON Patch se evaluan sobre la version nueva).
+func (h *AdminHandler) Patch(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != contentTypeMergePatch && mediaType != contentTypeJSONPatch {
+		writeError(w, http.StatusUnsupportedMediaType,
+			fmt.Sprintf("content type must be %s or %s", contentTypeMergePatch, contentTypeJSONPatch))
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxPatchBytes))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, repo.ErrInvalidBody.Error())
+		return
+	}
+	ifMatch := r.Header.Get("If-Match")
+
+	for attempt := 1; ; attempt++ {
+		flag, err := h.repo.GetByID(r.Context(), id)
+		if err != nil {
+			writeRepoError(w, err)
+			return
+		}
+		if ifMatch != "" && !etagMatches(ifMatch, flagETag(*flag)) {
+			writeError(w, http.StatusPreconditionFailed, repo.ErrStale.Error())
+			return
+		}
+
+		read := flag.UpdatedAt
+		if !patchFlag(w, flag, mediaType, body) {
+			return
+		}
+
+		err = h.repo.Apply(r.Context(), []repo.Change{{Op: repo.ChangeUpdate, Flag: *flag, IfUpdatedAt: read}})
+		if errors.Is(err, repo.ErrStale) && ifMatch == "" && attempt < maxPatchAttempts {
+			continue
+		}
+		recordMutation("patch", err)
+		if errors.Is(err, repo.ErrStale) && ifMatch != "" {
+			writeError(w, http.StatusPreconditionFailed, repo.ErrStale.Error())
+			return
+		}
+		if err != nil {
+			writeRepoError(w, err)
+			return
+		}
+		break
+	}
+
+	updated, err := h.repo.GetByID(r.Context(), id)
+	if err != nil {
+		writeRepoError(w, err)
+		return
+	}
+
+	w.Header().Set("ETag", flagETag(*updated))
+	writeJSON(w, http.StatusOK, toFlagResponse(*updated))
+}
+
+// patchFlag aplica el patch sobre flag y valida el resultado. Si falla escribe
+// la respuesta de error y devuelve false.
+func patchFlag(w http.ResponseWriter, flag *core.FeatureFlag, mediaType string, body []byte) bool {
+	original := toFlagDocument(*flag)
+	doc, err := json.Marshal(original)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return false
+	}
+
+	var patched []byte
+	if mediaType == contentTypeMergePatch {
+		patched, err = jsonpatch.MergePatch(doc, body)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid merge patch: "+err.Error())
+			return false
+		}
+	} else {
+		ops, decodeErr := jsonpatch.DecodePatch(body)
+		if decodeErr != nil {
+			writeError(w, http.StatusBadRequest, "invalid JSON patch: "+decodeErr.Error())
+			return false
+		}
+		patched, err = ops.Apply(doc)
+		if errors.Is(err, jsonpatch.ErrTestFailed) {
+			writeError(w, http.StatusConflict, err.Error())
+			return false
+		}
+		if err != nil {
+			writeError(w, http.StatusUnprocessableEntity, "patch cannot be applied: "+err.Error())
+			return false
+		}
+	}
+
+	var next flagDocument
+	if err := decodeStrict(patched, &next); err != nil {
+		writeDecodeError(w, err)
+		return false
+	}
+
+	if err := checkReadOnly(original, next); err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+		return false
+	}
+
+	flag.Key = next.Key
+	flag.Description = next.Description
+	flag.Enabled = next.Enabled
+	flag.Percentage = next.Percentage
+	flag.Owner = next.Owner
+	flag.Tags = next.Tags
+	flag.Kind = core.FlagKind(next.Kind)
+	flag.Temporary = next.Temporary
+	flag.ExpiresAt = next.ExpiresAt
+	flag.Normalize()
+
+	if err := flag.Validate(); err != nil {
+		writeRepoError(w, err)
+		return false
+	}
+	return true
+}
+
+// flagETag identifica la version de la flag (su updated_at) para If-Match
+func flagETag(f core.FeatureFlag) string {
+	return `"` + strconv.FormatInt(f.UpdatedAt.UnixNano(), 10) + `"`
+}
+
+// checkReadOnly rechaza patches que tocan campos que administra el servidor
+func checkReadOnly(before, after flagDocument) error {
+	switch {
+	case after.ID != before.ID:
+		return fmt.Errorf("id: %w", errReadOnlyField)
+	case !after.CreatedAt.Equal(before.CreatedAt):
+		return fmt.Errorf("created_at: %w", errReadOnlyField)
+	case !after.UpdatedAt.Equal(before.UpdatedAt):
+		return fmt.Errorf("updated_at: %w", errReadOnlyField)
+	case !after.RolloutChangedAt.Equal(before.RolloutChangedAt):
+		return fmt.Errorf("rollout_changed_at: %w", errReadOnlyField)
+	case !sameTime(after.ArchivedAt, before.ArchivedAt):
+		return fmt.Errorf("archived_at: %w (use /restore)", errReadOnlyField)
+	}
+	return nil
+}
+
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
@@ -0,0 +1,155 @@
+package httpapi
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Franconl/ffaas/internal/core"
+	"github.com/Franconl/ffaas/internal/repo/memory"
+)
+
+func newPatchTestServer(t *testing.T) (http.Handler, string) {
+	t.Helper()
+	store := memory.New()
+	flag := core.FeatureFlag{
+		Key:         "new_checkin",
+		Description: "nuevo flujo checkin",
+		Percentage:  30,
+		Owner:       "checkin",
+		Tags:        []string{"web"},
+	}
+	if err := store.Create(context.Background(), &flag); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	return NewRouter(store, Options{}), flag.ID
+}
+
+var mergePatch = http.Header{"Content-Type": {contentTypeMergePatch}}
+
+func TestPatchMergeKeepsOtherFields(t *testing.T) {
+	h, id := newPatchTestServer(t)
+
+	rec := doRequest(h, http.MethodPatch, "/flags/"+id, `{"enabled": true}`, mergePatch)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+
+	resp := decodeBody[FlagResponse](t, rec)
+	if !resp.Enabled {
+		t.Errorf("expected enabled")
+	}
+	if resp.Percentage != 30 || resp.Description != "nuevo flujo checkin" || resp.Owner != "checkin" {
+		t.Errorf("merge patch overwrote other fields: %+v", resp)
+	}
+}
+
+func TestPatchJSONPatch(t *testing.T) {
+	h, id := newPatchTestServer(t)
+
+	rec := doRequest(h, http.MethodPatch, "/flags/"+id, `[
+		{"op": "test", "path": "/percentage", "value": 30},
+		{"op": "replace", "path": "/percentage", "value": 50},
+		{"op": "add", "path": "/tags/-", "value": "mobile"}
+	]`, http.Header{"Content-Type": {contentTypeJSONPatch}})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+
+	resp := decodeBody[FlagResponse](t, rec)
+	if resp.Percentage != 50 {
+		t.Errorf("expected percentage 50, got %d", resp.Percentage)
+	}
+	if len(resp.Tags) != 2 || resp.Tags[1] != "mobile" {
+		t.Errorf("expected tags [web mobile], got %v", resp.Tags)
+	}
+}
+
+func TestPatchRejections(t *testing.T) {
+	cases := []struct {
+		name        string
+		contentType string
+		body        string
+		want        int
+	}{
+		{"unsupported content type", "application/json", `{}`, http.StatusUnsupportedMediaType},
+		{"read-only field", contentTypeMergePatch, `{"id": "other"}`, http.StatusUnprocessableEntity},
+		{"unknown field", contentTypeMergePatch, `{"colour": "red"}`, http.StatusUnprocessableEntity},
+		{"invalid percentage", contentTypeMergePatch, `{"percentage": 150}`, http.StatusUnprocessableEntity},
+		{"failed test op", contentTypeJSONPatch, `[{"op": "test", "path": "/enabled", "value": true}]`, http.StatusConflict},
+		{"missing path", contentTypeJSONPatch, `[{"op": "remove", "path": "/variants/0"}]`, http.StatusUnprocessableEntity},
+	}
+	for _, c := range cases {
+		h, id := newPatchTestServer(t)
+		rec := doRequest(h, http.MethodPatch, "/flags/"+id, c.body, http.Header{"Content-Type": {c.contentType}})
+		if rec.Code != c.want {
+			t.Errorf("%s: expected %d, got %d: %s", c.name, c.want, rec.Code, rec.Body)
+		}
+	}
+}
+
+func TestPatchIfMatch(t *testing.T) {
+	h, id := newPatchTestServer(t)
+
+	etag := doRequest(h, http.MethodGet, "/flags/"+id, "", nil).Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("GET /flags/{id} without ETag")
+	}
+
+	patch := func(ifMatch, body string) *httptest.ResponseRecorder {
+		header := http.Header{"Content-Type": {contentTypeMergePatch}, "If-Match": {ifMatch}}
+		return doRequest(h, http.MethodPatch, "/flags/"+id, body, header)
+	}
+
+	rec := patch(etag, `{"enabled": true}`)
+	if rec.Code != http.StatusOK || rec.Header().Get("ETag") == etag {
+		t.Fatalf("expected 200 with a new ETag, got %d %q: %s", rec.Code, rec.Header().Get("ETag"), rec.Body)
+	}
+	// la version ya no es la del ETag viejo
+	if rec := patch(etag, `{"percentage": 20}`); rec.Code != http.StatusPreconditionFailed {
+		t.Errorf("stale If-Match: expected 412, got %d: %s", rec.Code, rec.Body)
+	}
+}
+
+// racingRepo hace otra escritura justo despues de la primera lectura, como un
+// PATCH concurrente que gana la carrera
+type racingRepo struct {
+	*memory.Repo
+	race func()
+}
+
+func (r *racingRepo) GetByID(ctx context.Context, id string) (*core.FeatureFlag, error) {
+	f, err := r.Repo.GetByID(ctx, id)
+	if race := r.race; race != nil {
+		r.race = nil
+		race()
+	}
+	return f, err
+}
+
+func TestPatchConcurrentDoesNotLoseUpdates(t *testing.T) {
+	ctx := context.Background()
+	store := &racingRepo{Repo: memory.New()}
+	flag := core.FeatureFlag{Key: "new_checkin", Percentage: 30}
+	if err := store.Create(ctx, &flag); err != nil {
+		t.Fatal(err)
+	}
+	h := NewRouter(store, Options{})
+
+	store.race = func() {
+		other, _ := store.Repo.GetByID(ctx, flag.ID)
+		other.Percentage = 20
+		time.Sleep(time.Millisecond)
+		if err := store.Repo.Update(ctx, other); err != nil {
+			t.Error(err)
+		}
+	}
+
+	rec := doRequest(h, http.MethodPatch, "/flags/"+flag.ID, `{"enabled": true}`, mergePatch)
+	resp := decodeBody[FlagResponse](t, rec)
+	if rec.Code != http.StatusOK || !resp.Enabled || resp.Percentage != 20 {
+		t.Errorf("expected both changes, got %d: %s", rec.Code, rec.Body)
+	}
+}
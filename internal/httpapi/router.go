@@ -2,15 +2,34 @@ package httpapi
 
 import (
 	"net/http"
+	"time"
 
+	"github.com/Franconl/ffaas/internal/metrics"
 	"github.com/Franconl/ffaas/internal/repo"
+	"github.com/Franconl/ffaas/internal/stream"
+	"github.com/Franconl/ffaas/internal/tracing"
+	"github.com/Franconl/ffaas/internal/usage"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 )
 
-func NewRouter(store repo.Flags) http.Handler {
+// Options agrupa las dependencias opcionales del router
+type Options struct {
+	// Usage registra las evaluaciones (/sdk/eval, OFREP y las que reportan los
+	// SDKs en /sdk/usage) para el reporte de flags viejas
+	Usage *usage.Tracker
+	// ArchiveRetention es el tiempo minimo que una flag tiene que estar archivada para purgarla
+	ArchiveRetention time.Duration
+	// Snapshots, si esta, sirve /sdk/flags con version (ETag y 304)
+	Snapshots repo.Snapshotter
+	// Stream, si esta, habilita GET /sdk/stream (cambios de flags por SSE)
+	Stream *stream.Hub
+}
+
+func NewRouter(store repo.Flags, opts Options) http.Handler {
 	r := chi.NewRouter()
 	r.Use(middleware.RequestID, middleware.RealIP, middleware.Logger, middleware.Recoverer)
+	r.Use(tracing.Middleware, metrics.Middleware)
 
 	r.Get("/healthz", func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
@@ -18,9 +37,11 @@ func NewRouter(store repo.Flags) http.Handler {
 		w.Write([]byte("ok"))
 	})
 
-	handlerAdmin := NewAdminHandler(store)
+	r.Handle("/metrics", metrics.Handler())
 
-	handlerSdk := NewSdkHandler(store)
+	handlerAdmin := NewAdminHandler(store, opts.Usage, opts.ArchiveRetention)
+
+	handlerSdk := NewSdkHandler(store, opts.Usage, opts.Snapshots)
 
 	r.Post("/flags", handlerAdmin.Create)
 
@@ -28,15 +49,40 @@ func NewRouter(store repo.Flags) http.Handler {
 
 	r.Get("/flags/{id}", handlerAdmin.GetByID)
 
+	r.Get("/flags/reports/stale", handlerAdmin.StaleReport)
+
+	r.Get("/flags/export", handlerAdmin.Export)
+
+	r.Post("/flags/import", handlerAdmin.Import)
+
+	r.Post("/flags/batch", handlerAdmin.Batch)
+
 	r.Get("/flags/key/{key}", handlerAdmin.GetByKey)
 
 	r.Delete("/flags/{id}", handlerAdmin.DeleteByID)
 
+	r.Post("/flags/{id}/restore", handlerAdmin.Restore)
+
+	r.Delete("/flags/{id}/purge", handlerAdmin.Purge)
+
 	r.Put("/flags/{id}", handlerAdmin.Update)
 
+	r.Patch("/flags/{id}", handlerAdmin.Patch)
+
 	r.Get("/sdk/flags", handlerSdk.List)
 
 	r.Get("/sdk/eval", handlerSdk.Eval)
 
+	r.Post("/sdk/usage", handlerSdk.Usage)
+
+	// OpenFeature Remote Evaluation Protocol
+	r.Post("/ofrep/v1/evaluate/flags", handlerSdk.OfrepBulk)
+
+	r.Post("/ofrep/v1/evaluate/flags/{key}", handlerSdk.OfrepEvaluate)
+
+	if opts.Stream != nil {
+		r.Get("/sdk/stream", NewStreamHandler(opts.Stream).Stream)
+	}
+
 	return r
 }
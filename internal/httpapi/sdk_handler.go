@@ -1,26 +1,56 @@
 package httpapi
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/Franconl/ffaas/internal/core"
+	"github.com/Franconl/ffaas/internal/metrics"
 	"github.com/Franconl/ffaas/internal/repo"
+	"github.com/Franconl/ffaas/internal/usage"
 )
 
 type SdkHandler struct {
-	repo repo.Flags
+	repo      repo.Flags
+	usage     *usage.Tracker
+	snapshots repo.Snapshotter
 }
 
-// NewSdkHandler constructor, recibe un repo que cumpla la interfaz Repo.Flags
-func NewSdkHandler(repo repo.Flags) *SdkHandler {
+// NewSdkHandler constructor, recibe un repo que cumpla la interfaz Repo.Flags,
+// opcionalmente un tracker donde registrar cada evaluacion y opcionalmente
+// un Snapshotter para servir /sdk/flags con version
+func NewSdkHandler(repo repo.Flags, u *usage.Tracker, snapshots repo.Snapshotter) *SdkHandler {
 	return &SdkHandler{
-		repo: repo,
+		repo:      repo,
+		usage:     u,
+		snapshots: snapshots,
 	}
 }
 
+// List devuelve las flags activas. Con Snapshotter manda la version como ETag
+// y responde 304 si el SDK ya tiene esa version (If-None-Match).
 func (h *SdkHandler) List(w http.ResponseWriter, r *http.Request) {
-	list, err := h.repo.List()
+	ctx, stale := repo.TrackStale(r.Context())
+	snap, err := h.snapshot(r.WithContext(ctx))
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, err.Error())
+		writeError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+	writeStaleHeaders(w, stale)
+	list := snap.Flags
+
+	if snap.Version != 0 {
+		etag := `"` + strconv.FormatUint(snap.Version, 10) + `"`
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Cache-Control", "no-cache")
+		if etagMatches(r.Header.Get("If-None-Match"), etag) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
 	}
 
 	flags := make([]FlagResponse, 0, len(list))
@@ -36,11 +66,41 @@ func (h *SdkHandler) List(w http.ResponseWriter, r *http.Request) {
 		})
 	}
 
-	resp := ListFlagsResponse{Items: flags}
+	resp := ListFlagsResponse{Items: flags, Version: snap.Version}
 
 	writeJSON(w, http.StatusOK, resp)
 }
 
+func (h *SdkHandler) snapshot(r *http.Request) (repo.Snapshot, error) {
+	if h.snapshots != nil {
+		return h.snapshots.Snapshot(r.Context())
+	}
+	list, err := h.repo.List(r.Context())
+	return repo.Snapshot{Flags: list}, err
+}
+
+// writeStaleHeaders marca la respuesta si salio de la ultima copia buena de las
+// flags (backend caido): X-Flags-Stale y Age con los segundos de la copia
+func writeStaleHeaders(w http.ResponseWriter, stale func() (time.Time, bool)) {
+	asOf, ok := stale()
+	if !ok {
+		return
+	}
+	w.Header().Set("X-Flags-Stale", "true")
+	w.Header().Set("Age", strconv.Itoa(int(time.Since(asOf).Seconds())))
+}
+
+// etagMatches compara If-None-Match (lista separada por comas, acepta W/ y *) con el ETag
+func etagMatches(header, etag string) bool {
+	for _, v := range strings.Split(header, ",") {
+		v = strings.TrimPrefix(strings.TrimSpace(v), "W/")
+		if v == etag || v == "*" {
+			return true
+		}
+	}
+	return false
+}
+
 func (h *SdkHandler) Eval(w http.ResponseWriter, r *http.Request) {
 	key := r.URL.Query().Get("key")
 	userID := r.URL.Query().Get("userId")
@@ -54,23 +114,88 @@ func (h *SdkHandler) Eval(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	f, err := h.repo.GetByKey(key)
-	if err != nil {
+	ctx, stale := repo.TrackStale(r.Context())
+	f, err := h.repo.GetByKey(ctx, key)
+	// las flags archivadas no existen para los SDKs
+	if errors.Is(err, repo.ErrNotFound) || (err == nil && f.Archived()) {
 		writeError(w, http.StatusNotFound, "flag not found")
 		return
 	}
+	// backend caido y sin copia de la flag: 503, no 404 (el SDK usa su default
+	// sin concluir que la flag no existe)
+	if err != nil {
+		writeError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+	writeStaleHeaders(w, stale)
 
-	enabled := f.Eval(userID)
+	eval := f.Evaluate(userID)
+	metrics.FlagEvaluations.WithLabelValues(f.Key, strconv.FormatBool(eval.Enabled)).Inc()
+	h.usage.Record(f.ID, time.Now().UTC())
 
-	resp := struct {
-		Key     string `json:"key"`
-		UserID  string `json:"user_id"`
-		Enabled bool   `json:"enabled"`
-	}{
+	resp := EvalResponse{
 		Key:     f.Key,
 		UserID:  userID,
-		Enabled: enabled,
+		Enabled: eval.Enabled,
+		Variant: eval.Variant,
+		Reason:  string(eval.Reason),
 	}
 
 	writeJSON(w, http.StatusOK, resp)
 }
+
+// maxUsageItems limita las flags de un reporte de uso (un SDK manda a lo sumo
+// una entrada por flag)
+const maxUsageItems = 1000
+
+// Usage maneja POST /sdk/usage: los SDKs que evaluan local mandan cada tanto
+// cuantas evaluaciones hicieron por flag, para que el reporte de flags viejas
+// no las de por no usadas. Las keys que no existen se ignoran.
+func (h *SdkHandler) Usage(w http.ResponseWriter, r *http.Request) {
+	var req UsageReportRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+	verr := &core.ValidationError{}
+	if len(req.Items) > maxUsageItems {
+		verr.Add("items", repo.ErrInvalidBody, "must have at most %d items", maxUsageItems)
+	}
+	for i, it := range req.Items {
+		if it.Key == "" {
+			verr.Add(fmt.Sprintf("items[%d].key", i), repo.ErrInvalidBody, "is required")
+		}
+		if it.Evaluations <= 0 {
+			verr.Add(fmt.Sprintf("items[%d].evaluations", i), repo.ErrInvalidBody, "must be greater than 0")
+		}
+	}
+	if len(verr.Fields) > 0 {
+		writeRepoError(w, verr)
+		return
+	}
+
+	snap, err := h.snapshot(r)
+	if err != nil {
+		writeError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+	ids := make(map[string]string, len(snap.Flags))
+	for _, f := range snap.Flags {
+		ids[f.Key] = f.ID
+	}
+
+	now := time.Now().UTC()
+	for _, it := range req.Items {
+		id, ok := ids[it.Key]
+		if !ok {
+			continue
+		}
+		// un reloj adelantado en el cliente no puede dejar la flag "usada" a futuro
+		at := it.LastEvaluatedAt.UTC()
+		if at.IsZero() || at.After(now) {
+			at = now
+		}
+		h.usage.Add(core.FlagUsage{FlagID: id, Evaluations: it.Evaluations, LastEvaluatedAt: at})
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,106 @@
+package httpapi
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Franconl/ffaas/internal/core"
+	"github.com/Franconl/ffaas/internal/repo"
+	"github.com/Franconl/ffaas/internal/repo/memory"
+	"github.com/Franconl/ffaas/internal/usage"
+)
+
+type fixedSnapshots struct{ snap repo.Snapshot }
+
+func (f fixedSnapshots) Snapshot(ctx context.Context) (repo.Snapshot, error) { return f.snap, nil }
+
+func TestSdkListConditional(t *testing.T) {
+	snaps := fixedSnapshots{repo.Snapshot{Version: 42, Flags: []core.FeatureFlag{{Key: "new_checkout"}}}}
+	h := NewRouter(memory.New(), Options{Snapshots: snaps})
+
+	rec := doRequest(h, http.MethodGet, "/sdk/flags", "", nil)
+	if rec.Code != http.StatusOK || rec.Header().Get("ETag") != `"42"` {
+		t.Fatalf("expected 200 with ETag \"42\", got %d %q", rec.Code, rec.Header().Get("ETag"))
+	}
+	resp := decodeBody[ListFlagsResponse](t, rec)
+	if resp.Version != 42 || len(resp.Items) != 1 {
+		t.Errorf("unexpected body: %+v", resp)
+	}
+
+	rec = doRequest(h, http.MethodGet, "/sdk/flags", "", http.Header{"If-None-Match": {`"41", W/"42"`}})
+	if rec.Code != http.StatusNotModified || rec.Body.Len() != 0 {
+		t.Errorf("expected empty 304, got %d: %s", rec.Code, rec.Body)
+	}
+}
+
+// downRepo simula un backend caido para las lecturas por id y por key
+type downRepo struct {
+	*memory.Repo
+}
+
+func (d downRepo) GetByKey(ctx context.Context, key string) (*core.FeatureFlag, error) {
+	return nil, repo.ErrUnavailable
+}
+
+func (d downRepo) GetByID(ctx context.Context, id string) (*core.FeatureFlag, error) {
+	return nil, repo.ErrUnavailable
+}
+
+func TestSdkEvalBackendDown(t *testing.T) {
+	h := NewRouter(downRepo{memory.New()}, Options{})
+
+	rec := doRequest(h, http.MethodGet, "/sdk/eval?key=new_checkout&userId=u1", "", nil)
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected 503, got %d: %s", rec.Code, rec.Body)
+	}
+}
+
+// con el backend caido la API de admin no puede decir que la flag no existe
+func TestAdminGetBackendDown(t *testing.T) {
+	h := NewRouter(downRepo{memory.New()}, Options{})
+
+	for _, path := range []string{"/flags/00000000-0000-0000-0000-000000000000", "/flags/key/new_checkout"} {
+		rec := doRequest(h, http.MethodGet, path, "", nil)
+		if rec.Code != http.StatusServiceUnavailable {
+			t.Errorf("%s: expected 503, got %d: %s", path, rec.Code, rec.Body)
+		}
+	}
+}
+
+func TestSdkUsage(t *testing.T) {
+	ctx := context.Background()
+	store := memory.New()
+	f := core.FeatureFlag{Key: "new_checkout", Enabled: true}
+	if err := store.Create(ctx, &f); err != nil {
+		t.Fatal(err)
+	}
+	tracker := usage.NewTracker(store)
+	h := NewRouter(store, Options{Usage: tracker})
+
+	post := func(body string) int {
+		return doRequest(h, http.MethodPost, "/sdk/usage", body, nil).Code
+	}
+
+	if code := post(`{"items":[{"key":"new_checkout","evaluations":0}]}`); code != http.StatusUnprocessableEntity {
+		t.Errorf("zero evaluations: expected 422, got %d", code)
+	}
+
+	// las keys desconocidas se ignoran y una fecha a futuro se lleva a ahora
+	future := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	body := `{"items":[
+		{"key":"new_checkout","evaluations":5,"last_evaluated_at":"` + future + `"},
+		{"key":"gone","evaluations":2}
+	]}`
+	if code := post(body); code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", code)
+	}
+	snap, err := tracker.Snapshot(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snap) != 1 || snap[f.ID].Evaluations != 5 || snap[f.ID].LastEvaluatedAt.After(time.Now()) {
+		t.Errorf("usage = %+v", snap)
+	}
+}
@@ -0,0 +1,70 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Franconl/ffaas/internal/stream"
+)
+
+// streamHeartbeat mantiene viva la conexion a traves de proxies que cortan
+// las conexiones inactivas
+const streamHeartbeat = 15 * time.Second
+
+// StreamHandler sirve GET /sdk/stream con Server-Sent Events: un evento
+// "change" por cada flag que cambia. Al conectar manda "ready"; el SDK pide
+// la lista completa despues de eso (y despues de cada reconexion), asi no se
+// pierde lo que cambio mientras no estaba conectado.
+type StreamHandler struct {
+	hub *stream.Hub
+}
+
+func NewStreamHandler(hub *stream.Hub) *StreamHandler {
+	return &StreamHandler{hub: hub}
+}
+
+func (h *StreamHandler) Stream(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "streaming not supported")
+		return
+	}
+
+	events, unsubscribe := h.hub.Subscribe()
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	// nginx: no bufferear la respuesta
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	fmt.Fprint(w, "retry: 3000\nevent: ready\ndata: {}\n\n")
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": ping\n\n")
+		case e, ok := <-events:
+			if !ok {
+				// atrasado o apagando: el SDK reconecta
+				return
+			}
+			data, err := json.Marshal(e)
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(w, "event: change\ndata: %s\n\n", data)
+		}
+		flusher.Flush()
+	}
+}
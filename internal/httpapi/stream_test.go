@@ -0,0 +1,66 @@
+package httpapi
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Franconl/ffaas/internal/repo/memory"
+	"github.com/Franconl/ffaas/internal/stream"
+)
+
+func TestStreamSendsChanges(t *testing.T) {
+	hub := stream.NewHub()
+	srv := httptest.NewServer(NewRouter(memory.New(), Options{Stream: hub}))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/sdk/stream")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Content-Type = %q", ct)
+	}
+
+	lines := make(chan string)
+	go func() {
+		sc := bufio.NewScanner(resp.Body)
+		for sc.Scan() {
+			lines <- sc.Text()
+		}
+		close(lines)
+	}()
+	next := func(prefix string) string {
+		t.Helper()
+		timeout := time.After(3 * time.Second)
+		for {
+			select {
+			case l, ok := <-lines:
+				if !ok {
+					t.Fatalf("stream closed waiting for %q", prefix)
+				}
+				if strings.HasPrefix(l, prefix) {
+					return l
+				}
+			case <-timeout:
+				t.Fatalf("timeout waiting for %q", prefix)
+			}
+		}
+	}
+
+	next("event: ready")
+	hub.Publish(stream.Event{Op: "update", ID: "1", Key: "new_checkout"})
+	next("event: change")
+	if data := next("data: "); !strings.Contains(data, `"key":"new_checkout"`) {
+		t.Errorf("unexpected data line: %s", data)
+	}
+
+	// cerrar el hub termina el stream
+	hub.Close()
+	for range lines {
+	}
+}
@@ -0,0 +1,136 @@
+package httpapi
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"time"
+
+	"github.com/Franconl/ffaas/internal/flagfile"
+	"github.com/Franconl/ffaas/internal/repo"
+)
+
+const maxImportBytes = 10 << 20
+
+// Export maneja GET /flags/export?format=json|yaml
+// Exporta las flags activas como documento versionado (ver internal/flagfile).
+func (h *AdminHandler) Export(w http.ResponseWriter, r *http.Request) {
+	format, err := flagfile.ParseFormat(r.URL.Query().Get("format"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	flags, err := h.repo.List(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	doc := flagfile.FromFlags(flags, time.Now().UTC())
+
+	contentType := "application/json"
+	if format == flagfile.FormatYAML {
+		contentType = "application/yaml"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="flags.%s"`, format))
+	w.WriteHeader(http.StatusOK)
+	_ = flagfile.Encode(w, doc, format)
+}
+
+// Import maneja POST /flags/import?mode=create-only|upsert|sync&dry_run=true
+// El formato sale del Content-Type (application/json o application/yaml).
+// Con dry_run solo devuelve el diff; si no, aplica todos los cambios de forma atomica.
+func (h *AdminHandler) Import(w http.ResponseWriter, r *http.Request) {
+	mode := flagfile.Mode(r.URL.Query().Get("mode"))
+	if mode == "" {
+		mode = flagfile.ModeUpsert
+	}
+	if !mode.Valid() {
+		writeError(w, http.StatusBadRequest, "mode must be one of create-only, upsert, sync")
+		return
+	}
+
+	dryRun, err := queryBool(r.URL.Query().Get("dry_run"), "dry_run")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	format, err := flagfile.ParseFormat(mediaType)
+	if err != nil {
+		writeError(w, http.StatusUnsupportedMediaType, err.Error())
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxImportBytes))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, repo.ErrInvalidBody.Error())
+		return
+	}
+
+	doc, err := flagfile.Decode(body, format)
+	if err != nil {
+		if repo.IsDomainError(err) {
+			writeRepoError(w, err)
+			return
+		}
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// el plan se arma contra todas las flags, incluidas las archivadas
+	current, err := h.repo.Search(r.Context(), repo.Query{})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	plan, err := flagfile.BuildPlan(current.Items, doc, mode)
+	if err != nil {
+		writeRepoError(w, err)
+		return
+	}
+
+	resp := toImportResponse(mode, dryRun != nil && *dryRun, plan)
+	if resp.DryRun || len(plan.Changes) == 0 {
+		writeJSON(w, http.StatusOK, resp)
+		return
+	}
+
+	err = h.repo.Apply(r.Context(), plan.Changes)
+	recordMutation("import", err)
+	if err != nil {
+		writeRepoError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func toImportResponse(mode flagfile.Mode, dryRun bool, plan flagfile.Plan) ImportResponse {
+	resp := ImportResponse{
+		Mode:   string(mode),
+		DryRun: dryRun,
+		Diff:   make([]ImportDiffResponse, 0, len(plan.Diffs)),
+	}
+	for _, d := range plan.Diffs {
+		switch d.Action {
+		case flagfile.ActionCreate:
+			resp.Created++
+		case flagfile.ActionUpdate:
+			resp.Updated++
+		case flagfile.ActionArchive:
+			resp.Archived++
+		case flagfile.ActionUnchanged:
+			resp.Unchanged++
+		case flagfile.ActionSkip:
+			resp.Skipped++
+		}
+		resp.Diff = append(resp.Diff, ImportDiffResponse{Key: d.Key, Action: string(d.Action), Fields: d.Fields})
+	}
+	return resp
+}
@@ -0,0 +1,123 @@
+package httpapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/Franconl/ffaas/internal/core"
+	"github.com/Franconl/ffaas/internal/repo"
+)
+
+const maxBodyBytes = 1 << 20
+
+// decodeJSON decodifica el body de forma estricta: campos desconocidos o con
+// tipo incorrecto son errores de validacion (422), JSON roto es ErrInvalidBody (400)
+func decodeJSON(r *http.Request, dst any) error {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxBodyBytes))
+	if err != nil {
+		return repo.ErrInvalidBody
+	}
+	return decodeStrict(body, dst)
+}
+
+func decodeStrict(body []byte, dst any) error {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.DisallowUnknownFields()
+
+	err := dec.Decode(dst)
+	if err == nil {
+		// un solo objeto por body
+		if dec.More() {
+			return repo.ErrInvalidBody
+		}
+		return nil
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		verr := &core.ValidationError{}
+		verr.Add(typeErr.Field, repo.ErrInvalidBody, "must be of type %s", typeErr.Type.String())
+		return verr
+	}
+	// encoding/json no exporta el error de campo desconocido
+	if field, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+		verr := &core.ValidationError{}
+		verr.Add(strings.Trim(field, `"`), repo.ErrInvalidBody, "unknown field")
+		return verr
+	}
+	return repo.ErrInvalidBody
+}
+
+// --- DTOs -> modelo ---
+
+// toFlag arma la flag a crear; Validate aplica las mismas reglas que los backends
+func (req CreateFlagRequest) toFlag() core.FeatureFlag {
+	f := core.FeatureFlag{
+		Key:         req.Key,
+		Description: req.Description,
+		Enabled:     req.Enabled,
+		Percentage:  req.Percentage,
+		Owner:       req.Owner,
+		Tags:        req.Tags,
+		Kind:        core.FlagKind(req.Kind),
+		Temporary:   req.Temporary,
+		ExpiresAt:   req.ExpiresAt,
+	}
+	f.Normalize()
+	return f
+}
+
+func (req CreateFlagRequest) Validate() error {
+	return req.toFlag().Validate()
+}
+
+// applyTo reemplaza los campos editables de f. key y kind vacios mantienen los actuales.
+func (req UpdateFlagRequest) applyTo(f *core.FeatureFlag) {
+	if req.Key != "" {
+		f.Key = req.Key
+	}
+	f.Description = req.Description
+	f.Enabled = req.Enabled
+	f.Percentage = req.Percentage
+	f.Owner = req.Owner
+	f.Tags = req.Tags
+	f.Temporary = req.Temporary
+	f.ExpiresAt = req.ExpiresAt
+	if req.Kind != "" {
+		f.Kind = core.FlagKind(req.Kind)
+	}
+	f.Normalize()
+}
+
+// Validate valida el request aplicado sobre la flag actual
+func (req UpdateFlagRequest) Validate(current core.FeatureFlag) error {
+	req.applyTo(&current)
+	return current.Validate()
+}
+
+// --- respuestas ---
+
+func toValidationResponse(verr *core.ValidationError) ValidationErrorResponse {
+	resp := ValidationErrorResponse{
+		Error:  "validation failed",
+		Fields: make([]FieldErrorResponse, 0, len(verr.Fields)),
+	}
+	for _, f := range verr.Fields {
+		resp.Fields = append(resp.Fields, FieldErrorResponse{Field: f.Field, Message: f.Message})
+	}
+	return resp
+}
+
+// writeDecodeError responde el error de decodeJSON con el status que corresponde
+func writeDecodeError(w http.ResponseWriter, err error) {
+	var verr *core.ValidationError
+	if errors.As(err, &verr) {
+		writeJSON(w, http.StatusUnprocessableEntity, toValidationResponse(verr))
+		return
+	}
+	writeError(w, http.StatusBadRequest, repo.ErrInvalidBody.Error())
+}
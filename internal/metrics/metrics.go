@@ -0,0 +1,142 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "ffaas"
+
+var (
+	// FlagEvaluations cuenta las evaluaciones hechas por /sdk/eval, por flag y resultado
+	FlagEvaluations = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "flag_evaluations_total",
+		Help:      "Evaluaciones de feature flags por flag y resultado.",
+	}, []string{"flag", "result"})
+
+	// AdminMutations cuenta las operaciones de escritura de la API admin
+	AdminMutations = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "admin_mutations_total",
+		Help:      "Mutaciones de la API admin por operacion y resultado.",
+	}, []string{"operation", "outcome"})
+
+	// HTTPRequestDuration mide la latencia de cada request por ruta (patron de chi)
+	HTTPRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Latencia de requests HTTP por metodo, ruta y status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	// RepoCallDuration mide la latencia de cada metodo del repositorio por backend
+	RepoCallDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "repo_call_duration_seconds",
+		Help:      "Latencia de llamadas al repositorio por backend y metodo.",
+		Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+	}, []string{"backend", "method"})
+
+	// RepoCallErrors cuenta los errores inesperados del repositorio (not found no cuenta)
+	RepoCallErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "repo_call_errors_total",
+		Help:      "Errores de llamadas al repositorio por backend y metodo.",
+	}, []string{"backend", "method"})
+
+	// CacheRequests cuenta hits y misses de cached.Repo por tier (local = LRU en proceso, redis)
+	CacheRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cache_requests_total",
+		Help:      "Lecturas de cache por tier (local/redis) y resultado (hit/miss).",
+	}, []string{"tier", "result"})
+
+	// CacheCoalesced cuenta las lecturas a la base que se evitaron porque ya habia
+	// una igual en vuelo (singleflight en cached.Repo)
+	CacheCoalesced = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cache_coalesced_total",
+		Help:      "Lecturas de cache miss que esperaron a otra lectura en vuelo en vez de ir a la base.",
+	})
+
+	// BreakerState es el estado de cada circuit breaker: 0 cerrado, 1 abierto, 2 half-open
+	BreakerState = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "breaker_state",
+		Help:      "Estado del circuit breaker (0 cerrado, 1 abierto, 2 half-open).",
+	}, []string{"name"})
+
+	// StaleResponses cuenta las lecturas servidas desde la ultima copia buena
+	// porque el backend no respondia
+	StaleResponses = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "stale_responses_total",
+		Help:      "Lecturas servidas desde el ultimo snapshot bueno por metodo.",
+	}, []string{"method"})
+
+	// OutboxEvents cuenta los eventos del outbox por resultado (published/failed)
+	OutboxEvents = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "outbox_events_total",
+		Help:      "Eventos del outbox entregados al sink por resultado.",
+	}, []string{"outcome"})
+
+	// CacheInvalidations cuenta las invalidaciones de cache por origen:
+	// local (escritura en esta instancia), remote (pub/sub) o resync (reconexion)
+	CacheInvalidations = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cache_invalidations_total",
+		Help:      "Invalidaciones de cache por origen (local, remote, resync).",
+	}, []string{"source"})
+)
+
+// CacheHit registra un hit o un miss de cache en el tier dado
+func CacheHit(tier string, hit bool) {
+	if hit {
+		CacheRequests.WithLabelValues(tier, "hit").Inc()
+		return
+	}
+	CacheRequests.WithLabelValues(tier, "miss").Inc()
+}
+
+// CacheNegativeHit registra un hit de una entrada "no existe" cacheada
+func CacheNegativeHit(tier string) {
+	CacheRequests.WithLabelValues(tier, "negative").Inc()
+}
+
+// Handler expone las metricas en formato Prometheus (GET /metrics)
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// Middleware mide la latencia de cada request usando el patron de ruta de chi
+// como label, para no explotar la cardinalidad con ids en el path
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+		next.ServeHTTP(ww, r)
+
+		route := "unmatched"
+		if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+			route = rctx.RoutePattern()
+		}
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		HTTPRequestDuration.
+			WithLabelValues(r.Method, route, strconv.Itoa(status)).
+			Observe(time.Since(start).Seconds())
+	})
+}
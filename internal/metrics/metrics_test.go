@@ -0,0 +1,58 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// requestCount devuelve cuantas observaciones tiene HTTPRequestDuration por ruta
+func requestCount(t *testing.T) map[string]uint64 {
+	t.Helper()
+	families, err := prometheus.DefaultGatherer.Gather()
+	if err != nil {
+		t.Fatalf("gather: %v", err)
+	}
+	counts := map[string]uint64{}
+	for _, mf := range families {
+		if mf.GetName() != namespace+"_http_request_duration_seconds" {
+			continue
+		}
+		for _, m := range mf.GetMetric() {
+			for _, l := range m.GetLabel() {
+				if l.GetName() == "route" {
+					counts[l.GetValue()] += m.GetHistogram().GetSampleCount()
+				}
+			}
+		}
+	}
+	return counts
+}
+
+func TestMiddlewareLabelsByRoutePattern(t *testing.T) {
+	r := chi.NewRouter()
+	r.Use(Middleware)
+	r.Get("/flags/{id}", func(w http.ResponseWriter, r *http.Request) {})
+
+	before := requestCount(t)
+	for _, path := range []string{"/flags/a1", "/flags/b2", "/flags/c3", "/nope"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+	after := requestCount(t)
+
+	// los ids no pueden terminar en la etiqueta: una serie por ruta
+	if got := after["/flags/{id}"] - before["/flags/{id}"]; got != 3 {
+		t.Errorf("route /flags/{id}: %d observations, want 3", got)
+	}
+	if got := after["unmatched"] - before["unmatched"]; got != 1 {
+		t.Errorf("route unmatched: %d observations, want 1", got)
+	}
+	for route := range after {
+		if route == "/flags/a1" || route == "/nope" {
+			t.Errorf("raw path %q used as route label", route)
+		}
+	}
+}
@@ -0,0 +1,258 @@
+// Package migrate aplica migraciones SQL embebidas y registra las aplicadas en
+// la tabla schema_migrations. Lo usan los backends SQL (sqlite, postgres).
+//
+// Los archivos se llaman NNNN_nombre.up.sql y, opcionalmente, NNNN_nombre.down.sql.
+// Cada migracion corre en su propia transaccion junto con el registro de su version.
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DB lo cumplen *sql.DB y *sql.Conn. Con locks de sesion (advisory locks de
+// postgres) hay que migrar sobre el mismo *sql.Conn que tomo el lock.
+type DB interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+}
+
+// Migration es una version del schema
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Load lee las migraciones de fsys (en la raiz) ordenadas por version
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasSuffix(name, ".sql") {
+			continue
+		}
+
+		base := strings.TrimSuffix(name, ".sql")
+		var direction string
+		switch {
+		case strings.HasSuffix(base, ".up"):
+			direction, base = "up", strings.TrimSuffix(base, ".up")
+		case strings.HasSuffix(base, ".down"):
+			direction, base = "down", strings.TrimSuffix(base, ".down")
+		default:
+			return nil, fmt.Errorf("migration %s: name must end in .up.sql or .down.sql", name)
+		}
+
+		rawVersion, label, _ := strings.Cut(base, "_")
+		version, err := strconv.Atoi(rawVersion)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("migration %s: name must start with a positive version number", name)
+		}
+
+		body, err := fs.ReadFile(fsys, path.Join(".", name))
+		if err != nil {
+			return nil, err
+		}
+
+		m := byVersion[version]
+		if m == nil {
+			m = &Migration{Version: version, Name: label}
+			byVersion[version] = m
+		}
+		if direction == "up" {
+			m.Up = string(body)
+		} else {
+			m.Down = string(body)
+		}
+	}
+
+	list := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %04d_%s: missing .up.sql", m.Version, m.Name)
+		}
+		list = append(list, *m)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Version < list[j].Version })
+	return list, nil
+}
+
+const createTable = `
+	CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    INTEGER PRIMARY KEY,
+		applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`
+
+// Applied devuelve las versiones ya aplicadas en la base
+func Applied(ctx context.Context, db DB) (map[int]bool, error) {
+	if _, err := db.ExecContext(ctx, createTable); err != nil {
+		return nil, err
+	}
+
+	rows, err := db.QueryContext(ctx, `SELECT version FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int]bool)
+	for rows.Next() {
+		var v int
+		if err := rows.Scan(&v); err != nil {
+			return nil, err
+		}
+		applied[v] = true
+	}
+	return applied, rows.Err()
+}
+
+// Up aplica, en orden, las migraciones que faltan y devuelve las versiones aplicadas
+func Up(ctx context.Context, db DB, migrations []Migration) ([]int, error) {
+	applied, err := Applied(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	var done []int
+	for _, m := range migrations {
+		if applied[m.Version] {
+			continue
+		}
+		// la version es un int propio, no input del usuario: se interpola para no
+		// depender del estilo de placeholders de cada driver
+		record := fmt.Sprintf(`INSERT INTO schema_migrations (version) VALUES (%d)`, m.Version)
+		if err := run(ctx, db, m.Up, record); err != nil {
+			return done, fmt.Errorf("migration %04d_%s: %w", m.Version, m.Name, err)
+		}
+		done = append(done, m.Version)
+	}
+	return done, nil
+}
+
+// Down revierte las ultimas steps migraciones aplicadas (de la mas nueva a la
+// mas vieja) y devuelve las versiones revertidas. Falla si alguna no tiene .down.sql.
+func Down(ctx context.Context, db DB, migrations []Migration, steps int) ([]int, error) {
+	applied, err := Applied(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	var done []int
+	for i := len(migrations) - 1; i >= 0 && len(done) < steps; i-- {
+		m := migrations[i]
+		if !applied[m.Version] {
+			continue
+		}
+		if m.Down == "" {
+			return done, fmt.Errorf("migration %04d_%s: no .down.sql, cannot be reverted", m.Version, m.Name)
+		}
+		record := fmt.Sprintf(`DELETE FROM schema_migrations WHERE version = %d`, m.Version)
+		if err := run(ctx, db, m.Down, record); err != nil {
+			return done, fmt.Errorf("migration %04d_%s (down): %w", m.Version, m.Name, err)
+		}
+		done = append(done, m.Version)
+	}
+	return done, nil
+}
+
+// State es el estado de una migracion en la base
+type State struct {
+	Migration
+	Applied bool
+}
+
+// Status lista todas las migraciones conocidas y si estan aplicadas
+func Status(ctx context.Context, db DB, migrations []Migration) ([]State, error) {
+	applied, err := Applied(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	states := make([]State, 0, len(migrations))
+	for _, m := range migrations {
+		states = append(states, State{Migration: m, Applied: applied[m.Version]})
+	}
+	return states, nil
+}
+
+func run(ctx context.Context, db DB, stmts ...string) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, stmt := range stmts {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// Migrator junta las migraciones de un backend con su base. Todas las
+// operaciones corren sobre una unica conexion y, si Lock no es nil, con el
+// lock tomado: dos replicas que arrancan a la vez no migran en paralelo.
+type Migrator struct {
+	DB         *sql.DB
+	Migrations []Migration
+	// Lock bloquea la base para el resto de los migradores y devuelve como liberarla
+	Lock func(ctx context.Context, conn *sql.Conn) (unlock func() error, err error)
+}
+
+func (m Migrator) Up(ctx context.Context) ([]int, error) {
+	var done []int
+	err := m.withConn(ctx, func(conn *sql.Conn) (err error) {
+		done, err = Up(ctx, conn, m.Migrations)
+		return err
+	})
+	return done, err
+}
+
+func (m Migrator) Down(ctx context.Context, steps int) ([]int, error) {
+	var done []int
+	err := m.withConn(ctx, func(conn *sql.Conn) (err error) {
+		done, err = Down(ctx, conn, m.Migrations, steps)
+		return err
+	})
+	return done, err
+}
+
+func (m Migrator) Status(ctx context.Context) ([]State, error) {
+	var states []State
+	err := m.withConn(ctx, func(conn *sql.Conn) (err error) {
+		states, err = Status(ctx, conn, m.Migrations)
+		return err
+	})
+	return states, err
+}
+
+func (m Migrator) withConn(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.DB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if m.Lock != nil {
+		unlock, err := m.Lock(ctx, conn)
+		if err != nil {
+			return fmt.Errorf("acquiring migration lock: %w", err)
+		}
+		defer unlock()
+	}
+	return fn(conn)
+}
@@ -0,0 +1,67 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	_ "modernc.org/sqlite"
+)
+
+var testFS = fstest.MapFS{
+	"0001_flags.up.sql":   {Data: []byte("CREATE TABLE flags (id TEXT PRIMARY KEY);")},
+	"0001_flags.down.sql": {Data: []byte("DROP TABLE flags;")},
+	"0002_usage.up.sql":   {Data: []byte("CREATE TABLE usage (flag_id TEXT); CREATE INDEX usage_idx ON usage (flag_id);")},
+	"0002_usage.down.sql": {Data: []byte("DROP TABLE usage;")},
+	"README.md":           {Data: []byte("ignored")},
+}
+
+func TestUpDownStatus(t *testing.T) {
+	ctx := context.Background()
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "m.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	migrations, err := Load(testFS)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	m := Migrator{DB: db, Migrations: migrations}
+
+	applied, err := m.Up(ctx)
+	if err != nil || len(applied) != 2 {
+		t.Fatalf("expected 2 migrations applied, got %v (%v)", applied, err)
+	}
+	if applied, _ := m.Up(ctx); len(applied) != 0 {
+		t.Errorf("expected second up to be a no-op, got %v", applied)
+	}
+
+	reverted, err := m.Down(ctx, 1)
+	if err != nil || len(reverted) != 1 || reverted[0] != 2 {
+		t.Fatalf("expected version 2 reverted, got %v (%v)", reverted, err)
+	}
+	if _, err := db.Exec("SELECT 1 FROM usage"); err == nil {
+		t.Error("expected usage table to be dropped")
+	}
+
+	states, err := m.Status(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(states) != 2 || !states[0].Applied || states[1].Applied {
+		t.Errorf("unexpected status %+v", states)
+	}
+}
+
+func TestLoadRejectsBadNames(t *testing.T) {
+	for _, name := range []string{"init.up.sql", "0001_init.sql", "0002_x.down.sql"} {
+		fsys := fstest.MapFS{name: {Data: []byte("SELECT 1;")}}
+		if _, err := Load(fsys); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+// Package outbox define los eventos de cambio de flags que se entregan a
+// consumidores externos y los sinks donde se publican. Los eventos se escriben
+// en la misma transaccion que el cambio (ver postgres.Repo.EnableOutbox) y un
+// relay los publica despues: la entrega es at-least-once, asi que un consumidor
+// puede recibir el mismo evento mas de una vez y tiene que deduplicar por ID.
+package outbox
+
+import (
+	"context"
+	"time"
+
+	"github.com/Franconl/ffaas/internal/core"
+)
+
+// Tipos de evento
+const (
+	FlagCreated  = "flag.created"
+	FlagUpdated  = "flag.updated"
+	FlagArchived = "flag.archived"
+	FlagRestored = "flag.restored"
+	FlagDeleted  = "flag.deleted"
+)
+
+// Event es un cambio de flag. ID crece con cada evento y es la clave para
+// deduplicar. Flag es el estado despues del cambio (nil en flag.deleted).
+type Event struct {
+	ID         int64             `json:"id"`
+	Type       string            `json:"type"`
+	FlagID     string            `json:"flag_id"`
+	FlagKey    string            `json:"flag_key"`
+	Flag       *core.FeatureFlag `json:"flag,omitempty"`
+	OccurredAt time.Time         `json:"occurred_at"`
+}
+
+// Sink publica un lote de eventos en orden. Si devuelve error el relay
+// reintenta el lote entero mas tarde.
+type Sink interface {
+	Publish(ctx context.Context, events []Event) error
+}
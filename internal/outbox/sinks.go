@@ -0,0 +1,118 @@
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// WriterSink escribe cada evento como una linea JSON (stdout, por ejemplo)
+type WriterSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func NewWriterSink(w io.Writer) *WriterSink {
+	return &WriterSink{w: w}
+}
+
+func (s *WriterSink) Publish(ctx context.Context, events []Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return writeLines(s.w, events)
+}
+
+// FileSink agrega los eventos a un archivo JSONL y hace fsync antes de
+// confirmar el lote
+type FileSink struct {
+	mu sync.Mutex
+	f  *os.File
+}
+
+func NewFileSink(path string) (*FileSink, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &FileSink{f: f}, nil
+}
+
+func (s *FileSink) Publish(ctx context.Context, events []Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := writeLines(s.f, events); err != nil {
+		return err
+	}
+	return s.f.Sync()
+}
+
+func (s *FileSink) Close() error {
+	return s.f.Close()
+}
+
+func writeLines(w io.Writer, events []Event) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, e := range events {
+		if err := enc.Encode(e); err != nil {
+			return err
+		}
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// SignatureHeader lleva la firma HMAC-SHA256 del body cuando el webhook tiene secreto
+const SignatureHeader = "X-FFaaS-Signature"
+
+// WebhookSink manda cada lote como un POST con un array JSON. Cualquier
+// respuesta que no sea 2xx es un error y el lote se reintenta.
+type WebhookSink struct {
+	url    string
+	secret []byte
+	client *http.Client
+}
+
+// NewWebhookSink crea el sink; con secret, cada request lleva
+// X-FFaaS-Signature: sha256=<hmac hex del body>
+func NewWebhookSink(url, secret string) *WebhookSink {
+	return &WebhookSink{url: url, secret: []byte(secret), client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+func (s *WebhookSink) Publish(ctx context.Context, events []Event) error {
+	body, err := json.Marshal(events)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if len(s.secret) > 0 {
+		mac := hmac.New(sha256.New, s.secret)
+		mac.Write(body)
+		req.Header.Set(SignatureHeader, "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook %s: unexpected status %d", s.url, resp.StatusCode)
+	}
+	return nil
+}
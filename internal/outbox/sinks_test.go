@@ -0,0 +1,87 @@
+package outbox
+
+import (
+	"bufio"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Franconl/ffaas/internal/core"
+)
+
+var testEvents = []Event{
+	{ID: 1, Type: FlagCreated, FlagID: "a", FlagKey: "new_checkout", Flag: &core.FeatureFlag{ID: "a", Key: "new_checkout"}},
+	{ID: 2, Type: FlagDeleted, FlagID: "a", FlagKey: "new_checkout"},
+}
+
+func TestWebhookSink(t *testing.T) {
+	var got []Event
+	status := http.StatusNoContent
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mac := hmac.New(sha256.New, []byte("s3cret"))
+		mac.Write(body)
+		if want := "sha256=" + hex.EncodeToString(mac.Sum(nil)); r.Header.Get(SignatureHeader) != want {
+			t.Errorf("signature = %q, want %q", r.Header.Get(SignatureHeader), want)
+		}
+		if err := json.Unmarshal(body, &got); err != nil {
+			t.Errorf("decode: %v", err)
+		}
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	sink := NewWebhookSink(srv.URL, "s3cret")
+	if err := sink.Publish(context.Background(), testEvents); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].Flag == nil || got[1].Flag != nil {
+		t.Errorf("unexpected events: %+v", got)
+	}
+
+	// un status que no es 2xx hace que el lote se reintente
+	status = http.StatusBadGateway
+	if err := sink.Publish(context.Background(), testEvents); err == nil {
+		t.Error("expected error on 502")
+	}
+}
+
+func TestFileSinkAppends(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	for range 2 {
+		sink, err := NewFileSink(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := sink.Publish(context.Background(), testEvents); err != nil {
+			t.Fatal(err)
+		}
+		sink.Close()
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var ids []int64
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		var e Event
+		if err := json.Unmarshal(sc.Bytes(), &e); err != nil {
+			t.Fatalf("line %q: %v", sc.Text(), err)
+		}
+		ids = append(ids, e.ID)
+	}
+	if len(ids) != 4 || ids[0] != 1 || ids[3] != 2 {
+		t.Errorf("ids = %v, want [1 2 1 2]", ids)
+	}
+}
@@ -0,0 +1,65 @@
+package cached
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Franconl/ffaas/internal/breaker"
+	"github.com/redis/go-redis/v9"
+)
+
+// UseBreaker pasa los comandos a Redis por el circuit breaker b: con Redis
+// caido, cada lectura falla al instante (y va a la base) en vez de esperar el
+// timeout de conexion. La suscripcion de invalidaciones no pasa por el breaker,
+// tiene su propio reintento. Llamar antes de empezar a servir.
+func (r *Repo) UseBreaker(b *breaker.Breaker) {
+	r.rdb.AddHook(breakerHook{b: b})
+}
+
+type breakerHook struct {
+	b *breaker.Breaker
+}
+
+// redisFailure descarta los errores que no son una falla de Redis: las
+// respuestas de error del server (redis.Nil, WATCH fallido, NOSCRIPT...) dicen
+// que Redis esta vivo. Las cancelaciones pasan tal cual: el breaker no las
+// cuenta ni como exito ni como falla.
+func redisFailure(err error) error {
+	var rerr redis.Error
+	if errors.As(err, &rerr) {
+		return nil
+	}
+	return err
+}
+
+func (h breakerHook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (h breakerHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		t, err := h.b.Allow()
+		if err != nil {
+			cmd.SetErr(err)
+			return err
+		}
+		err = next(ctx, cmd)
+		h.b.Record(t, redisFailure(err))
+		return err
+	}
+}
+
+func (h breakerHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		t, err := h.b.Allow()
+		if err != nil {
+			for _, cmd := range cmds {
+				cmd.SetErr(err)
+			}
+			return err
+		}
+		err = next(ctx, cmds)
+		h.b.Record(t, redisFailure(err))
+		return err
+	}
+}
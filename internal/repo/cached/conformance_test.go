@@ -0,0 +1,31 @@
+package cached
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Franconl/ffaas/internal/repo"
+	"github.com/Franconl/ffaas/internal/repo/memory"
+	"github.com/Franconl/ffaas/internal/repo/repotest"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func newConformanceRepo(t *testing.T) *Repo {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+	return New(memory.New(), rdb, time.Minute)
+}
+
+func TestConformance(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) repo.Flags { return newConformanceRepo(t) })
+}
+
+func TestConformanceWithLocalTier(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) repo.Flags {
+		r := newConformanceRepo(t)
+		r.EnableLocal(100, time.Minute)
+		return r
+	})
+}
@@ -0,0 +1,148 @@
+package cached
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/Franconl/ffaas/internal/metrics"
+	"github.com/redis/go-redis/v9"
+)
+
+// InvalidationChannel es el canal de Redis donde cada instancia publica las
+// flags que cambio, para que el resto descarte sus caches locales
+const InvalidationChannel = "ff:invalidate"
+
+// Invalidation describe que entradas hay que descartar. All pide descartar
+// todo: se usa al reconectar, porque mientras no habia suscripcion se pudieron
+// perder mensajes.
+type Invalidation struct {
+	IDs  []string `json:"ids,omitempty"`
+	Keys []string `json:"keys,omitempty"`
+	All  bool     `json:"all,omitempty"`
+}
+
+// message es lo que viaja por el canal; Origin evita procesar los propios
+type message struct {
+	Origin string `json:"origin"`
+	Invalidation
+}
+
+// OnInvalidate registra una funcion que se llama con cada invalidacion, tanto
+// de escrituras locales como de otras instancias. Es el punto de enganche de
+// las caches en proceso. Registrar antes de empezar a servir: no es thread-safe.
+func (r *Repo) OnInvalidate(fn func(Invalidation)) {
+	r.hooks = append(r.hooks, fn)
+}
+
+func (r *Repo) runHooks(inv Invalidation, source string) {
+	metrics.CacheInvalidations.WithLabelValues(source).Inc()
+	for _, fn := range r.hooks {
+		fn(inv)
+	}
+}
+
+// notify sube la version del snapshot, avisa a las caches locales y publica
+// la invalidacion para el resto. Si Redis falla solo se loguea: la escritura ya
+// se hizo y las otras instancias se terminan enterando por TTL o por el resync
+// al reconectar.
+func (r *Repo) notify(ctx context.Context, inv Invalidation) {
+	if _, err := r.bumpSnapshot(ctx); err != nil {
+		log.Println("⚠️ Error subiendo la version del snapshot:", err)
+	}
+	r.runHooks(inv, "local")
+
+	b, err := json.Marshal(message{Origin: r.instanceID, Invalidation: inv})
+	if err != nil {
+		return
+	}
+	if err := r.rdb.Publish(ctx, InvalidationChannel, b).Err(); err != nil {
+		log.Println("⚠️ Error publicando invalidacion de cache:", err)
+	}
+}
+
+// Invalidate descarta flags que cambiaron por fuera de este cached.Repo (p. ej.
+// el change feed de Postgres): borra sus entradas de Redis, sube la version del
+// snapshot y avisa a las caches locales. No publica: cada instancia recibe el
+// feed por su cuenta.
+//
+// changeID identifica el cambio: todas las instancias reciben el mismo, y la
+// version del snapshot sube una sola vez por changeID (si no, con N replicas
+// cada cambio la subiria N veces y los SDKs bajarian la lista N veces). Vacio
+// sube siempre. Borrar las keys y avisar a las caches locales se hace igual en
+// cada instancia: es idempotente.
+func (r *Repo) Invalidate(ctx context.Context, changeID string, inv Invalidation) {
+	for _, id := range inv.IDs {
+		_ = r.rdb.Del(ctx, keyByID(id)).Err()
+	}
+	for _, k := range inv.Keys {
+		_ = r.rdb.Del(ctx, keyByKey(k)).Err()
+	}
+	var err error
+	if changeID == "" {
+		_, err = r.bumpSnapshot(ctx)
+	} else {
+		_, err = r.bumpSnapshotOnce(ctx, changeID)
+	}
+	if err != nil {
+		log.Println("⚠️ Error subiendo la version del snapshot:", err)
+	}
+	r.runHooks(inv, "feed")
+}
+
+// RunInvalidations se suscribe al canal y aplica las invalidaciones de las
+// otras instancias hasta que se cancela ctx. go-redis reconecta y se vuelve a
+// suscribir solo; cada nueva confirmacion de suscripcion despues de la primera
+// es una reconexion y dispara un resync (invalidar todo).
+func (r *Repo) RunInvalidations(ctx context.Context) {
+	pubsub := r.rdb.Subscribe(ctx, InvalidationChannel)
+	defer pubsub.Close()
+
+	subscribed := false
+	backoff := 100 * time.Millisecond
+
+	for {
+		msg, err := pubsub.Receive(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			if errors.Is(err, redis.ErrClosed) {
+				return
+			}
+			log.Println("⚠️ Suscripcion de invalidaciones caida, reintentando:", err)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(backoff):
+			}
+			backoff = min(backoff*2, 5*time.Second)
+			continue
+		}
+		backoff = 100 * time.Millisecond
+
+		switch m := msg.(type) {
+		case *redis.Subscription:
+			if m.Kind != "subscribe" {
+				continue
+			}
+			if subscribed {
+				log.Println("🔄 Suscripcion de invalidaciones restablecida, resync de caches locales")
+				r.runHooks(Invalidation{All: true}, "resync")
+			}
+			subscribed = true
+		case *redis.Message:
+			var in message
+			if err := json.Unmarshal([]byte(m.Payload), &in); err != nil {
+				log.Println("⚠️ Invalidacion de cache invalida:", err)
+				continue
+			}
+			if in.Origin == r.instanceID {
+				continue
+			}
+			r.runHooks(in.Invalidation, "remote")
+		}
+	}
+}
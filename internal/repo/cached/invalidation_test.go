@@ -0,0 +1,113 @@
+package cached
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Franconl/ffaas/internal/core"
+	"github.com/Franconl/ffaas/internal/repo/memory"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// waitFor espera una invalidacion que cumpla match
+func waitFor(t *testing.T, ch <-chan Invalidation, match func(Invalidation) bool) {
+	t.Helper()
+	timeout := time.After(3 * time.Second)
+	for {
+		select {
+		case inv := <-ch:
+			if match(inv) {
+				return
+			}
+		case <-timeout:
+			t.Fatal("timeout waiting for invalidation")
+		}
+	}
+}
+
+func TestInvalidationsReachOtherInstances(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	mr := miniredis.RunT(t)
+	base := memory.New()
+
+	writer := New(base, redis.NewClient(&redis.Options{Addr: mr.Addr()}), time.Minute)
+	reader := New(base, redis.NewClient(&redis.Options{Addr: mr.Addr(), MaxRetries: -1}), time.Minute)
+
+	got := make(chan Invalidation, 16)
+	reader.OnInvalidate(func(inv Invalidation) { got <- inv })
+	go reader.RunInvalidations(ctx)
+
+	// esperar a que la suscripcion este activa antes de escribir
+	deadline := time.Now().Add(3 * time.Second)
+	for len(mr.PubSubChannels("")) == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	f := core.FeatureFlag{Key: "new_checkout"}
+	if err := writer.Create(ctx, &f); err != nil {
+		t.Fatal(err)
+	}
+	f.Key = "checkout_v2"
+	if err := writer.Update(ctx, &f); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, got, func(inv Invalidation) bool {
+		return len(inv.Keys) == 2 && inv.Keys[1] == "new_checkout"
+	})
+
+	// al volver Redis, la instancia se resuscribe e invalida todo
+	mr.Close()
+	if err := mr.Restart(); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, got, func(inv Invalidation) bool { return inv.All })
+}
+
+func TestFeedInvalidationBumpsOncePerChange(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	base := memory.New()
+
+	// dos replicas que reciben el mismo change feed
+	a := New(base, redis.NewClient(&redis.Options{Addr: mr.Addr()}), time.Minute)
+	b := New(base, redis.NewClient(&redis.Options{Addr: mr.Addr()}), time.Minute)
+
+	f := core.FeatureFlag{Key: "new_checkout"}
+	if err := base.Create(ctx, &f); err != nil {
+		t.Fatal(err)
+	}
+	before, err := a.Snapshot(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// escritura por fuera de la app: llega a las dos
+	f.Enabled = true
+	if err := base.Update(ctx, &f); err != nil {
+		t.Fatal(err)
+	}
+	inv := Invalidation{IDs: []string{f.ID}, Keys: []string{f.Key}}
+	time.Sleep(2 * time.Millisecond)
+	a.Invalidate(ctx, "change-1", inv)
+	after, _ := b.Snapshot(ctx)
+	b.Invalidate(ctx, "change-1", inv)
+	again, _ := a.Snapshot(ctx)
+
+	if after.Version <= before.Version || len(after.Flags) != 1 || !after.Flags[0].Enabled {
+		t.Fatalf("snapshot after change = %+v (previous version %d)", after, before.Version)
+	}
+	if again.Version != after.Version {
+		t.Errorf("second replica bumped the version again: %d -> %d", after.Version, again.Version)
+	}
+
+	// otro cambio si sube
+	time.Sleep(2 * time.Millisecond)
+	b.Invalidate(ctx, "change-2", inv)
+	if next, _ := a.Snapshot(ctx); next.Version <= again.Version {
+		t.Errorf("new change did not bump: %d -> %d", again.Version, next.Version)
+	}
+}
@@ -0,0 +1,117 @@
+package cached
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/Franconl/ffaas/internal/core"
+	"github.com/Franconl/ffaas/internal/repo"
+)
+
+const (
+	tierLocal = "local"
+	tierRedis = "redis"
+
+	listKey = "all"
+)
+
+// local es el tier en proceso delante de Redis: flags por key y, aparte, el
+// snapshot de la lista completa como una sola entrada.
+type local struct {
+	flags *lru[core.FeatureFlag]
+	list  *lru[repo.Snapshot]
+}
+
+// EnableLocal agrega un LRU en proceso de hasta size flags con el TTL dado
+// delante de Redis para GetByKey y List/Snapshot. Se invalida con las escrituras
+// locales y con las de otras instancias (RunInvalidations). El TTL acota lo
+// viejo que puede quedar un valor si se pierde una invalidacion.
+// Llamar antes de empezar a servir.
+func (r *Repo) EnableLocal(size int, ttl time.Duration) {
+	if size <= 0 || ttl <= 0 {
+		return
+	}
+	l := &local{
+		flags: newLRU[core.FeatureFlag](size, ttl),
+		list:  newLRU[repo.Snapshot](1, ttl),
+	}
+	r.local = l
+
+	r.OnInvalidate(func(inv Invalidation) {
+		// cualquier cambio puede cambiar la lista
+		l.list.purge()
+		if inv.All {
+			l.flags.purge()
+			return
+		}
+		l.flags.delete(inv.Keys...)
+	})
+}
+
+func (r *Repo) localGet(ctx context.Context, key string) (*core.FeatureFlag, bool) {
+	if r.local == nil {
+		return nil, false
+	}
+	f, ok := r.local.flags.get(key)
+	recordCache(ctx, tierLocal, ok)
+	if !ok {
+		return nil, false
+	}
+	f.Tags = slices.Clone(f.Tags)
+	return &f, true
+}
+
+func (r *Repo) localSnapshot(ctx context.Context) (repo.Snapshot, bool) {
+	if r.local == nil {
+		return repo.Snapshot{}, false
+	}
+	snap, ok := r.local.list.get(listKey)
+	recordCache(ctx, tierLocal, ok)
+	if !ok {
+		return repo.Snapshot{}, false
+	}
+	return repo.Snapshot{Version: snap.Version, Flags: cloneFlags(snap.Flags)}, true
+}
+
+// localGeneration y localListGeneration devuelven la generacion del LRU para
+// llenarlo despues con localSet / localSetSnapshot
+func (r *Repo) localGeneration() uint64 {
+	if r.local == nil {
+		return 0
+	}
+	return r.local.flags.generation()
+}
+
+func (r *Repo) localListGeneration() uint64 {
+	if r.local == nil {
+		return 0
+	}
+	return r.local.list.generation()
+}
+
+func (r *Repo) localSet(gen uint64, f *core.FeatureFlag) {
+	if r.local == nil {
+		return
+	}
+	v := *f
+	v.Tags = slices.Clone(f.Tags)
+	r.local.flags.setIf(gen, v.Key, v)
+}
+
+func (r *Repo) localSetSnapshot(gen uint64, snap repo.Snapshot) {
+	if r.local == nil {
+		return
+	}
+	snap.Flags = cloneFlags(snap.Flags)
+	r.local.list.setIf(gen, listKey, snap)
+}
+
+func cloneFlags(list []core.FeatureFlag) []core.FeatureFlag {
+	out := make([]core.FeatureFlag, len(list))
+	for i, f := range list {
+		f.Tags = slices.Clone(f.Tags)
+		out[i] = f
+	}
+	return out
+}
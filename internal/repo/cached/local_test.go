@@ -0,0 +1,97 @@
+package cached
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Franconl/ffaas/internal/core"
+	"github.com/Franconl/ffaas/internal/repo/memory"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestLRUEvictsAndExpires(t *testing.T) {
+	c := newLRU[int](2, 50*time.Millisecond)
+	c.setIf(0, "a", 1)
+	c.setIf(0, "b", 2)
+	c.get("a") // "b" pasa a ser la menos usada
+	c.setIf(0, "c", 3)
+
+	if _, ok := c.get("b"); ok {
+		t.Error("expected b to be evicted")
+	}
+	if v, ok := c.get("a"); !ok || v != 1 {
+		t.Errorf("get(a) = %d, %v", v, ok)
+	}
+
+	gen := c.generation()
+	c.delete("a")
+	c.setIf(gen, "a", 10)
+	if _, ok := c.get("a"); ok {
+		t.Error("fill started before an invalidation should be dropped")
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if _, ok := c.get("c"); ok {
+		t.Error("expected c to expire")
+	}
+}
+
+func TestLocalTierInvalidatedByRemoteWrites(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	mr := miniredis.RunT(t)
+	base := memory.New()
+
+	writer := New(base, redis.NewClient(&redis.Options{Addr: mr.Addr()}), time.Minute)
+	reader := New(base, redis.NewClient(&redis.Options{Addr: mr.Addr()}), time.Minute)
+	reader.EnableLocal(100, time.Minute)
+
+	got := make(chan Invalidation, 16)
+	reader.OnInvalidate(func(inv Invalidation) { got <- inv })
+	go reader.RunInvalidations(ctx)
+
+	deadline := time.Now().Add(3 * time.Second)
+	for len(mr.PubSubChannels("")) == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	f := core.FeatureFlag{Key: "new_checkout", Tags: []string{"web"}}
+	if err := writer.Create(ctx, &f); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := reader.GetByKey(ctx, "new_checkout"); err != nil {
+		t.Fatal(err)
+	}
+	if list, err := reader.List(ctx); err != nil || len(list) != 1 {
+		t.Fatalf("List = %v, %v", list, err)
+	}
+
+	// sin Redis, la lectura sale del tier local; lo devuelto no comparte memoria
+	mr.FlushAll()
+	v, err := reader.GetByKey(ctx, "new_checkout")
+	if err != nil || v == nil {
+		t.Fatalf("GetByKey from local tier = %v, %v", v, err)
+	}
+	v.Tags[0] = "mutated"
+	if v, _ := reader.GetByKey(ctx, "new_checkout"); v.Tags[0] != "web" {
+		t.Errorf("local entry was mutated through a returned value: %v", v.Tags)
+	}
+
+	f.Enabled = true
+	if err := writer.Update(ctx, &f); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, got, func(inv Invalidation) bool { return len(inv.Keys) > 0 })
+
+	v, err = reader.GetByKey(ctx, "new_checkout")
+	if err != nil || !v.Enabled {
+		t.Errorf("GetByKey after remote update = %+v, %v", v, err)
+	}
+	list, err := reader.List(ctx)
+	if err != nil || len(list) != 1 || !list[0].Enabled {
+		t.Errorf("List after remote update = %+v, %v", list, err)
+	}
+}
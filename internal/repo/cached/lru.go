@@ -0,0 +1,111 @@
+package cached
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// lru es una cache en proceso acotada por cantidad de entradas y con TTL.
+//
+// gen cuenta las invalidaciones: quien lee de un tier mas lento toma gen antes
+// de leer y llena con setIf, que descarta el valor si hubo una invalidacion en
+// el medio (si no, podria volver a cachear la version vieja hasta el TTL).
+type lru[V any] struct {
+	mu    sync.Mutex
+	size  int
+	ttl   time.Duration
+	ll    *list.List
+	items map[string]*list.Element
+	gen   uint64
+}
+
+type lruEntry[V any] struct {
+	key     string
+	value   V
+	expires time.Time
+}
+
+func newLRU[V any](size int, ttl time.Duration) *lru[V] {
+	return &lru[V]{size: size, ttl: ttl, ll: list.New(), items: make(map[string]*list.Element)}
+}
+
+func (c *lru[V]) get(key string) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var zero V
+	el, ok := c.items[key]
+	if !ok {
+		return zero, false
+	}
+	e := el.Value.(*lruEntry[V])
+	if time.Now().After(e.expires) {
+		c.removeElement(el)
+		return zero, false
+	}
+	c.ll.MoveToFront(el)
+	return e.value, true
+}
+
+// generation devuelve el contador de invalidaciones actual (ver setIf)
+func (c *lru[V]) generation() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.gen
+}
+
+// setIf guarda el valor solo si no hubo invalidaciones desde gen
+func (c *lru[V]) setIf(gen uint64, key string, value V) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if gen != c.gen {
+		return
+	}
+
+	expires := time.Now().Add(c.ttl)
+	if el, ok := c.items[key]; ok {
+		e := el.Value.(*lruEntry[V])
+		e.value, e.expires = value, expires
+		c.ll.MoveToFront(el)
+		return
+	}
+
+	c.items[key] = c.ll.PushFront(&lruEntry[V]{key: key, value: value, expires: expires})
+	for c.ll.Len() > c.size {
+		c.removeElement(c.ll.Back())
+	}
+}
+
+func (c *lru[V]) delete(keys ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.gen++
+	for _, k := range keys {
+		if el, ok := c.items[k]; ok {
+			c.removeElement(el)
+		}
+	}
+}
+
+func (c *lru[V]) purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.gen++
+	c.ll.Init()
+	clear(c.items)
+}
+
+func (c *lru[V]) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ll.Len()
+}
+
+func (c *lru[V]) removeElement(el *list.Element) {
+	c.ll.Remove(el)
+	delete(c.items, el.Value.(*lruEntry[V]).key)
+}
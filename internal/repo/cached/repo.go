@@ -5,18 +5,30 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"math/rand/v2"
+	"slices"
 	"time"
 
 	"github.com/Franconl/ffaas/internal/core"
+	"github.com/Franconl/ffaas/internal/metrics"
+	"github.com/Franconl/ffaas/internal/repo"
+	"github.com/google/uuid"
 	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/sync/singleflight"
 )
 
 // Errores (mismos contratos que otras capas)
 var (
-	ErrNotFound       = errors.New("not found")
-	ErrKeyAlreadyUsed = errors.New("flag key already exists")
-	ErrKeyRequired    = errors.New("key is required")
-	ErrInvalidPercent = errors.New("invalid percentage")
+	ErrNotFound       = repo.ErrNotFound
+	ErrKeyAlreadyUsed = repo.ErrKeyAlreadyUsed
+	ErrKeyRequired    = repo.ErrKeyRequired
+	ErrInvalidPercent = repo.ErrInvalidPercent
+	ErrInvalidKind    = repo.ErrInvalidKind
+	ErrArchived       = repo.ErrArchived
+	ErrNotArchived    = repo.ErrNotArchived
+	ErrRetention      = repo.ErrRetention
 )
 
 // Contrato que debe cumplir el backend (memory, postgres, etc.)
@@ -24,19 +36,57 @@ type BaseRepo interface {
 	Create(ctx context.Context, f *core.FeatureFlag) error
 	Update(ctx context.Context, f *core.FeatureFlag) error
 	DeleteByID(ctx context.Context, id string) error
+	Archive(ctx context.Context, id string) error
+	Restore(ctx context.Context, id string) error
+	Purge(ctx context.Context, id string, archivedBefore time.Time) error
 	GetByID(ctx context.Context, id string) (*core.FeatureFlag, error)
 	GetByKey(ctx context.Context, key string) (*core.FeatureFlag, error)
 	List(ctx context.Context) ([]core.FeatureFlag, error)
+	Search(ctx context.Context, q repo.Query) (repo.Page, error)
+	Apply(ctx context.Context, changes []repo.Change) error
 }
 
+const (
+	// DefaultNegativeTTL es cuanto se recuerda en Redis que una key no existe
+	DefaultNegativeTTL = 5 * time.Second
+
+	// los TTL se reparten en ±ttlJitter para que las entradas cargadas juntas
+	// (p. ej. despues de un deploy) no venzan todas en el mismo segundo
+	ttlJitter = 0.1
+
+	// valor guardado en ff:key:<k> cuando la key no existe (no es JSON valido,
+	// asi que no se confunde con una flag)
+	notFoundMarker = "\x00notfound"
+)
+
 type Repo struct {
-	base BaseRepo
-	rdb  *redis.Client
-	ttl  time.Duration
+	base   BaseRepo
+	rdb    *redis.Client
+	ttl    time.Duration
+	negTTL time.Duration
+
+	// fills agrupa los cache miss concurrentes de la misma key en una sola lectura a la base
+	fills singleflight.Group
+	// snapshotFills hace lo mismo con el snapshot. Es otro grupo porque las keys
+	// de fills vienen del cliente: una flag "ff:snapshot" no puede pisarlo.
+	snapshotFills singleflight.Group
+
+	// instanceID identifica los mensajes de invalidacion propios (ver invalidation.go)
+	instanceID string
+	hooks      []func(Invalidation)
+
+	// tier en proceso opcional (ver EnableLocal en local.go)
+	local *local
 }
 
 func New(base BaseRepo, rdb *redis.Client, ttl time.Duration) *Repo {
-	return &Repo{base: base, rdb: rdb, ttl: ttl}
+	return &Repo{base: base, rdb: rdb, ttl: ttl, negTTL: DefaultNegativeTTL, instanceID: uuid.NewString()}
+}
+
+// SetNegativeTTL cambia cuanto se cachea un "no existe" en GetByKey (0 lo apaga).
+// Llamar antes de empezar a servir.
+func (r *Repo) SetNegativeTTL(d time.Duration) {
+	r.negTTL = d
 }
 
 // --- keys ---
@@ -45,7 +95,16 @@ func keyByID(id string) string { return fmt.Sprintf("ff:id:%s", id) }
 func keyByKey(k string) string { return fmt.Sprintf("ff:key:%s", k) }
 func setJSON(ctx context.Context, rdb *redis.Client, k string, v any, ttl time.Duration) {
 	b, _ := json.Marshal(v)
-	_ = rdb.Set(ctx, k, b, ttl).Err()
+	_ = rdb.Set(ctx, k, b, jitter(ttl)).Err()
+}
+
+// jitter devuelve ttl ± ttlJitter al azar
+func jitter(ttl time.Duration) time.Duration {
+	spread := time.Duration(float64(ttl) * ttlJitter)
+	if spread <= 0 {
+		return ttl
+	}
+	return ttl - spread + rand.N(2*spread)
 }
 
 func getJSON[T any](ctx context.Context, rdb *redis.Client, k string, dst *T) (bool, error) {
@@ -62,6 +121,12 @@ func getJSON[T any](ctx context.Context, rdb *redis.Client, k string, dst *T) (b
 	return true, nil
 }
 
+// recordCache registra el hit/miss del tier en las metricas y como atributo del span actual
+func recordCache(ctx context.Context, tier string, hit bool) {
+	metrics.CacheHit(tier, hit)
+	trace.SpanFromContext(ctx).SetAttributes(attribute.Bool("cache."+tier+".hit", hit))
+}
+
 // --- CRUD con caché ---
 
 func (r *Repo) Create(ctx context.Context, f *core.FeatureFlag) error {
@@ -71,6 +136,7 @@ func (r *Repo) Create(ctx context.Context, f *core.FeatureFlag) error {
 	// popular caché
 	setJSON(ctx, r.rdb, keyByID(f.ID), f, r.ttl)
 	setJSON(ctx, r.rdb, keyByKey(f.Key), f, r.ttl)
+	r.notify(ctx, Invalidation{IDs: []string{f.ID}, Keys: []string{f.Key}})
 	return nil
 }
 
@@ -83,12 +149,17 @@ func (r *Repo) Update(ctx context.Context, f *core.FeatureFlag) error {
 	}
 
 	// invalidar y reescribir caché
+	inv := Invalidation{IDs: []string{f.ID}, Keys: []string{f.Key}}
 	if old != nil {
 		_ = r.rdb.Del(ctx, keyByID(old.ID)).Err()
 		_ = r.rdb.Del(ctx, keyByKey(old.Key)).Err()
+		if old.Key != f.Key {
+			inv.Keys = append(inv.Keys, old.Key)
+		}
 	}
 	setJSON(ctx, r.rdb, keyByID(f.ID), f, r.ttl)
 	setJSON(ctx, r.rdb, keyByKey(f.Key), f, r.ttl)
+	r.notify(ctx, inv)
 	return nil
 }
 
@@ -98,16 +169,95 @@ func (r *Repo) DeleteByID(ctx context.Context, id string) error {
 	if err := r.base.DeleteByID(ctx, id); err != nil {
 		return err
 	}
+	r.invalidate(ctx, id, old)
+	return nil
+}
+
+// Archive, Restore y Purge cambian lo que ven los SDKs: se invalida la flag
+// por id y por key para que el proximo GetByKey vaya a la base
+
+func (r *Repo) Archive(ctx context.Context, id string) error {
+	old, _ := r.base.GetByID(ctx, id)
+	if err := r.base.Archive(ctx, id); err != nil {
+		return err
+	}
+	r.invalidate(ctx, id, old)
+	return nil
+}
+
+func (r *Repo) Restore(ctx context.Context, id string) error {
+	old, _ := r.base.GetByID(ctx, id)
+	if err := r.base.Restore(ctx, id); err != nil {
+		return err
+	}
+	r.invalidate(ctx, id, old)
+	return nil
+}
+
+func (r *Repo) Purge(ctx context.Context, id string, archivedBefore time.Time) error {
+	old, _ := r.base.GetByID(ctx, id)
+	if err := r.base.Purge(ctx, id, archivedBefore); err != nil {
+		return err
+	}
+	r.invalidate(ctx, id, old)
+	return nil
+}
+
+// Apply no repuebla la cache: solo invalida las flags tocadas (y sus keys viejas)
+func (r *Repo) Apply(ctx context.Context, changes []repo.Change) error {
+	olds := make(map[string]*core.FeatureFlag, len(changes))
+	for _, c := range changes {
+		if c.Op != repo.ChangeCreate {
+			olds[c.Flag.ID], _ = r.base.GetByID(ctx, c.Flag.ID)
+		}
+	}
+
+	if err := r.base.Apply(ctx, changes); err != nil {
+		return err
+	}
+
+	inv := Invalidation{}
+	for id, old := range olds {
+		r.drop(ctx, id, old)
+		inv.IDs = append(inv.IDs, id)
+		if old != nil {
+			inv.Keys = append(inv.Keys, old.Key)
+		}
+	}
+	for _, c := range changes {
+		if c.Flag.Key != "" {
+			// la key nueva puede tener cacheado un "no existe"
+			_ = r.rdb.Del(ctx, keyByKey(c.Flag.Key)).Err()
+			inv.Keys = append(inv.Keys, c.Flag.Key)
+		}
+	}
+	r.notify(ctx, inv)
+	return nil
+}
+
+// invalidate borra la flag de Redis y avisa al resto de las caches
+func (r *Repo) invalidate(ctx context.Context, id string, old *core.FeatureFlag) {
+	r.drop(ctx, id, old)
+	inv := Invalidation{IDs: []string{id}}
+	if old != nil {
+		inv.Keys = []string{old.Key}
+	}
+	r.notify(ctx, inv)
+}
+
+// drop borra la flag de Redis por id y por key
+func (r *Repo) drop(ctx context.Context, id string, old *core.FeatureFlag) {
 	_ = r.rdb.Del(ctx, keyByID(id)).Err()
 	if old != nil {
 		_ = r.rdb.Del(ctx, keyByKey(old.Key)).Err()
 	}
-	return nil
 }
 
 func (r *Repo) GetByID(ctx context.Context, id string) (*core.FeatureFlag, error) {
 	var ff core.FeatureFlag
-	if ok, err := getJSON(ctx, r.rdb, keyByID(id), &ff); err == nil && ok {
+	ok, err := getJSON(ctx, r.rdb, keyByID(id), &ff)
+	recordCache(ctx, tierRedis, err == nil && ok)
+	if err == nil && ok {
 		return &ff, nil
 	}
 	v, err := r.base.GetByID(ctx, id)
@@ -119,21 +269,81 @@ func (r *Repo) GetByID(ctx context.Context, id string) (*core.FeatureFlag, error
 	return v, nil
 }
 
+// GetByKey es el camino de /sdk/eval: primero el LRU en proceso (si esta
+// habilitado), despues Redis y por ultimo la base. Los miss concurrentes de la
+// misma key comparten una sola lectura a la base, y un "no existe" se cachea
+// en Redis por negTTL.
 func (r *Repo) GetByKey(ctx context.Context, k string) (*core.FeatureFlag, error) {
+	if f, ok := r.localGet(ctx, k); ok {
+		return f, nil
+	}
+	gen := r.localGeneration()
+
+	raw, err := r.rdb.Get(ctx, keyByKey(k)).Bytes()
+	if err == nil && string(raw) == notFoundMarker {
+		metrics.CacheNegativeHit(tierRedis)
+		trace.SpanFromContext(ctx).SetAttributes(attribute.Bool("cache.negative", true))
+		return nil, ErrNotFound
+	}
 	var ff core.FeatureFlag
-	if ok, err := getJSON(ctx, r.rdb, keyByKey(k), &ff); err == nil && ok {
+	hit := err == nil && json.Unmarshal(raw, &ff) == nil
+	recordCache(ctx, tierRedis, hit)
+	if hit {
+		r.localSet(gen, &ff)
 		return &ff, nil
 	}
+
+	ch := r.fills.DoChan(k, func() (any, error) {
+		// la lectura la comparten varios requests: no se corta si el primero cancela
+		return r.fill(context.WithoutCancel(ctx), k)
+	})
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case res := <-ch:
+		if res.Shared {
+			metrics.CacheCoalesced.Inc()
+		}
+		if res.Err != nil {
+			return nil, res.Err
+		}
+		v := res.Val.(*core.FeatureFlag)
+		r.localSet(gen, v)
+		// cada caller recibe su copia
+		out := *v
+		out.Tags = slices.Clone(v.Tags)
+		return &out, nil
+	}
+}
+
+// fill lee la key de la base y llena Redis: la flag, o la marca de "no existe"
+func (r *Repo) fill(ctx context.Context, k string) (*core.FeatureFlag, error) {
 	v, err := r.base.GetByKey(ctx, k)
-	if err != nil || v == nil {
-		return v, err
+	if errors.Is(err, ErrNotFound) && r.negTTL > 0 {
+		// SetNX: si en el medio se creo la flag y ya esta en Redis, no se pisa
+		_ = r.rdb.SetNX(ctx, keyByKey(k), notFoundMarker, jitter(r.negTTL)).Err()
+	}
+	if err != nil {
+		return nil, err
+	}
+	if v == nil {
+		return nil, ErrNotFound
 	}
 	setJSON(ctx, r.rdb, keyByID(v.ID), v, r.ttl)
 	setJSON(ctx, r.rdb, keyByKey(v.Key), v, r.ttl)
 	return v, nil
 }
 
+// List (lo que bajan los SDKs) sale del snapshot versionado (ver snapshot.go)
 func (r *Repo) List(ctx context.Context) ([]core.FeatureFlag, error) {
-	// Podrías cachear páginas, pero para mantenerlo simple, vamos directo a base.
-	return r.base.List(ctx)
+	snap, err := r.Snapshot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return snap.Flags, nil
+}
+
+// Search es para la API admin (poco trafico y filtros variables): sin caché
+func (r *Repo) Search(ctx context.Context, q repo.Query) (repo.Page, error) {
+	return r.base.Search(ctx, q)
 }
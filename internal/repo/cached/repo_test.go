@@ -0,0 +1,148 @@
+package cached
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Franconl/ffaas/internal/breaker"
+	"github.com/Franconl/ffaas/internal/core"
+	"github.com/Franconl/ffaas/internal/repo/memory"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// countingBase cuenta las lecturas por key y puede frenarlas hasta que se cierre release
+type countingBase struct {
+	*memory.Repo
+	reads   atomic.Int32
+	release chan struct{}
+}
+
+func (b *countingBase) GetByKey(ctx context.Context, key string) (*core.FeatureFlag, error) {
+	b.reads.Add(1)
+	if b.release != nil {
+		<-b.release
+	}
+	return b.Repo.GetByKey(ctx, key)
+}
+
+func TestGetByKeyCoalescesMisses(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	base := &countingBase{Repo: memory.New(), release: make(chan struct{})}
+	f := core.FeatureFlag{Key: "new_checkout", Tags: []string{"web"}}
+	if err := base.Create(ctx, &f); err != nil {
+		t.Fatal(err)
+	}
+	r := New(base, redis.NewClient(&redis.Options{Addr: mr.Addr()}), time.Minute)
+
+	const callers = 20
+	var wg sync.WaitGroup
+	results := make([]*core.FeatureFlag, callers)
+	for i := range callers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err := r.GetByKey(ctx, "new_checkout")
+			if err != nil {
+				t.Error(err)
+			}
+			results[i] = v
+		}()
+	}
+	// dar tiempo a que todos lleguen al miss antes de soltar la lectura
+	time.Sleep(50 * time.Millisecond)
+	close(base.release)
+	wg.Wait()
+
+	if n := base.reads.Load(); n != 1 {
+		t.Errorf("base reads = %d, want 1", n)
+	}
+	// cada caller tiene su propia copia
+	results[0].Tags[0] = "mutated"
+	if results[1].Tags[0] != "web" {
+		t.Errorf("callers share the returned flag: %v", results[1].Tags)
+	}
+}
+
+func TestGetByKeyCachesNotFound(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	base := &countingBase{Repo: memory.New()}
+	r := New(base, redis.NewClient(&redis.Options{Addr: mr.Addr()}), time.Minute)
+
+	for range 3 {
+		if _, err := r.GetByKey(ctx, "missing"); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("GetByKey(missing) err = %v, want ErrNotFound", err)
+		}
+	}
+	if n := base.reads.Load(); n != 1 {
+		t.Errorf("base reads = %d, want 1", n)
+	}
+
+	// crear la flag pisa la entrada negativa
+	f := core.FeatureFlag{Key: "missing"}
+	if err := r.Create(ctx, &f); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := r.GetByKey(ctx, "missing"); err != nil || v.ID != f.ID {
+		t.Fatalf("GetByKey after create = %v, %v", v, err)
+	}
+
+	// la entrada negativa vence sola
+	if _, err := r.GetByKey(ctx, "other"); !errors.Is(err, ErrNotFound) {
+		t.Fatal(err)
+	}
+	mr.FastForward(DefaultNegativeTTL + time.Second)
+	if _, err := r.GetByKey(ctx, "other"); !errors.Is(err, ErrNotFound) {
+		t.Fatal(err)
+	}
+	if n := base.reads.Load(); n != 3 {
+		t.Errorf("base reads = %d, want 3", n)
+	}
+}
+
+func TestJitter(t *testing.T) {
+	ttl := time.Minute
+	for range 100 {
+		d := jitter(ttl)
+		if d < 54*time.Second || d >= 66*time.Second {
+			t.Fatalf("jitter(%v) = %v out of range", ttl, d)
+		}
+	}
+}
+
+func TestBreakerSkipsRedisWhenDown(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	base := &countingBase{Repo: memory.New()}
+	f := core.FeatureFlag{Key: "new_checkout"}
+	if err := base.Create(ctx, &f); err != nil {
+		t.Fatal(err)
+	}
+	b := breaker.New("test-redis", 2, time.Minute, nil)
+	r := New(base, redis.NewClient(&redis.Options{Addr: mr.Addr(), MaxRetries: -1}), time.Minute)
+	r.UseBreaker(b)
+
+	// un miss (redis.Nil) no es una falla de Redis
+	if _, err := r.GetByKey(ctx, "new_checkout"); err != nil {
+		t.Fatal(err)
+	}
+	if b.State() != breaker.Closed {
+		t.Fatalf("state = %v, want closed", b.State())
+	}
+
+	mr.Close()
+	for range 3 {
+		if v, err := r.GetByKey(ctx, "new_checkout"); err != nil || v.ID != f.ID {
+			t.Fatalf("GetByKey with Redis down = %v, %v", v, err)
+		}
+	}
+	if b.State() != breaker.Open {
+		t.Errorf("state = %v, want open", b.State())
+	}
+}
@@ -0,0 +1,157 @@
+package cached
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/Franconl/ffaas/internal/core"
+	"github.com/Franconl/ffaas/internal/repo"
+	"github.com/redis/go-redis/v9"
+)
+
+// La lista completa de flags activas (GET /sdk/flags) se guarda en Redis como
+// un solo blob con su version. La version vive en su propia key y se sube con
+// cada mutacion, en el mismo script que borra el blob; el blob se reconstruye
+// en el proximo List y solo se guarda si la version no cambio mientras se leia
+// la base (WATCH), asi nunca queda un blob viejo con una version nueva.
+const (
+	snapshotKey        = "ff:snapshot"
+	snapshotVersionKey = "ff:snapshot:version"
+	// feedChangePrefix marca los cambios del feed que ya subieron la version
+	feedChangePrefix = "ff:snapshot:change:"
+
+	// feedChangeTTL es cuanto se recuerda un cambio del feed ya aplicado. Cubre
+	// el catch-up al reconectar, que repite los ultimos segundos; un cambio que
+	// llega despues solo cuesta un bump de mas.
+	feedChangeTTL = 10 * time.Minute
+)
+
+// bumpScript sube la version a max(actual+1, ahora en ms) y borra el blob. Usar
+// la hora hace que la version siga creciendo aunque Redis pierda la key. Con
+// KEYS[3] el bump es uno por cambio: si la key ya existe no hace nada y
+// devuelve 0.
+var bumpScript = redis.NewScript(`
+if KEYS[3] and not redis.call('SET', KEYS[3], '1', 'NX', 'PX', ARGV[2]) then
+  return 0
+end
+local v = tonumber(redis.call('GET', KEYS[1]) or '0')
+local t = tonumber(ARGV[1])
+if t <= v then t = v + 1 end
+redis.call('SET', KEYS[1], t)
+redis.call('DEL', KEYS[2])
+return t
+`)
+
+type snapshotBlob struct {
+	Version uint64             `json:"version"`
+	Flags   []core.FeatureFlag `json:"flags"`
+}
+
+// Snapshot devuelve las flags activas con su version: primero el tier local,
+// despues el blob de Redis y si no, la base (una sola lectura aunque haya
+// varios pedidos a la vez). Si Redis no responde devuelve la lista de la base
+// con version 0, que los handlers tratan como "sin version".
+func (r *Repo) Snapshot(ctx context.Context) (repo.Snapshot, error) {
+	if snap, ok := r.localSnapshot(ctx); ok {
+		return snap, nil
+	}
+	gen := r.localListGeneration()
+
+	var blob snapshotBlob
+	ok, err := getJSON(ctx, r.rdb, snapshotKey, &blob)
+	recordCache(ctx, tierRedis, err == nil && ok)
+	if err == nil && ok {
+		snap := repo.Snapshot{Version: blob.Version, Flags: blob.Flags}
+		r.localSetSnapshot(gen, snap)
+		return snap, nil
+	}
+
+	ch := r.snapshotFills.DoChan(snapshotKey, func() (any, error) {
+		return r.buildSnapshot(context.WithoutCancel(ctx))
+	})
+	select {
+	case <-ctx.Done():
+		return repo.Snapshot{}, ctx.Err()
+	case res := <-ch:
+		if res.Err != nil {
+			return repo.Snapshot{}, res.Err
+		}
+		snap := res.Val.(repo.Snapshot)
+		if snap.Version != 0 {
+			r.localSetSnapshot(gen, snap)
+		}
+		return repo.Snapshot{Version: snap.Version, Flags: cloneFlags(snap.Flags)}, nil
+	}
+}
+
+func (r *Repo) buildSnapshot(ctx context.Context) (repo.Snapshot, error) {
+	version, err := r.snapshotVersion(ctx)
+	if err != nil {
+		log.Println("⚠️ Error leyendo la version del snapshot en Redis:", err)
+		list, err := r.base.List(ctx)
+		return repo.Snapshot{Flags: sortFlags(list)}, err
+	}
+
+	list, err := r.base.List(ctx)
+	if err != nil {
+		return repo.Snapshot{}, err
+	}
+	snap := repo.Snapshot{Version: version, Flags: sortFlags(list)}
+
+	b, err := json.Marshal(snapshotBlob{Version: snap.Version, Flags: snap.Flags})
+	if err != nil {
+		return snap, nil
+	}
+	err = r.rdb.Watch(ctx, func(tx *redis.Tx) error {
+		cur, err := tx.Get(ctx, snapshotVersionKey).Uint64()
+		if err != nil {
+			return err
+		}
+		if cur != version {
+			// hubo una mutacion mientras se leia la base: el blob ya es viejo
+			return redis.TxFailedErr
+		}
+		_, err = tx.TxPipelined(ctx, func(p redis.Pipeliner) error {
+			p.Set(ctx, snapshotKey, b, jitter(r.ttl))
+			return nil
+		})
+		return err
+	}, snapshotVersionKey)
+	if err != nil && !errors.Is(err, redis.TxFailedErr) {
+		log.Println("⚠️ Error guardando el snapshot en Redis:", err)
+	}
+	return snap, nil
+}
+
+// snapshotVersion lee la version actual; si no existe (Redis nuevo o vaciado) la crea
+func (r *Repo) snapshotVersion(ctx context.Context) (uint64, error) {
+	v, err := r.rdb.Get(ctx, snapshotVersionKey).Uint64()
+	if errors.Is(err, redis.Nil) {
+		return r.bumpSnapshot(ctx)
+	}
+	return v, err
+}
+
+// bumpSnapshot sube la version y descarta el blob; se llama despues de cada mutacion
+func (r *Repo) bumpSnapshot(ctx context.Context) (uint64, error) {
+	keys := []string{snapshotVersionKey, snapshotKey}
+	return bumpScript.Run(ctx, r.rdb, keys, time.Now().UnixMilli()).Uint64()
+}
+
+// bumpSnapshotOnce es bumpSnapshot para un cambio que reciben todas las
+// instancias (el change feed): solo la primera sube la version. Devuelve 0 si
+// otra ya lo hizo.
+func (r *Repo) bumpSnapshotOnce(ctx context.Context, changeID string) (uint64, error) {
+	keys := []string{snapshotVersionKey, snapshotKey, feedChangePrefix + changeID}
+	return bumpScript.Run(ctx, r.rdb, keys, time.Now().UnixMilli(), feedChangeTTL.Milliseconds()).Uint64()
+}
+
+// sortFlags ordena como el List de los backends (repo.SortFlags), asi el mismo
+// snapshot siempre se serializa igual
+func sortFlags(list []core.FeatureFlag) []core.FeatureFlag {
+	repo.SortFlags(list)
+	return list
+}
@@ -0,0 +1,156 @@
+package cached
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Franconl/ffaas/internal/core"
+	"github.com/Franconl/ffaas/internal/repo"
+	"github.com/Franconl/ffaas/internal/repo/memory"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// listingBase cuenta los List y puede correr una funcion en el medio de la lectura
+type listingBase struct {
+	*memory.Repo
+	lists  atomic.Int32
+	during func()
+}
+
+func (b *listingBase) List(ctx context.Context) ([]core.FeatureFlag, error) {
+	b.lists.Add(1)
+	list, err := b.Repo.List(ctx)
+	if b.during != nil {
+		b.during()
+	}
+	return list, err
+}
+
+func TestSnapshotVersionedAndCached(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	base := &listingBase{Repo: memory.New()}
+	r := New(base, redis.NewClient(&redis.Options{Addr: mr.Addr()}), time.Minute)
+
+	for _, k := range []string{"b_flag", "a_flag"} {
+		if err := r.Create(ctx, &core.FeatureFlag{Key: k}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s1, err := r.Snapshot(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s1.Version == 0 || len(s1.Flags) != 2 || s1.Flags[0].Key != "b_flag" {
+		t.Fatalf("snapshot = %+v", s1)
+	}
+	s2, _ := r.Snapshot(ctx)
+	if s2.Version != s1.Version || base.lists.Load() != 1 {
+		t.Errorf("second snapshot: version %d (want %d), base lists %d (want 1)", s2.Version, s1.Version, base.lists.Load())
+	}
+
+	f := s1.Flags[0]
+	f.Enabled = true
+	if err := r.Update(ctx, &f); err != nil {
+		t.Fatal(err)
+	}
+	s3, _ := r.Snapshot(ctx)
+	if s3.Version <= s1.Version || !s3.Flags[0].Enabled {
+		t.Errorf("snapshot after update = %+v (previous version %d)", s3, s1.Version)
+	}
+
+	// si Redis pierde todo, la version sigue creciendo (es la hora en ms)
+	time.Sleep(2 * time.Millisecond)
+	mr.FlushAll()
+	s4, _ := r.Snapshot(ctx)
+	if s4.Version <= s3.Version {
+		t.Errorf("version after flush = %d, want > %d", s4.Version, s3.Version)
+	}
+}
+
+func TestSnapshotNotStoredIfChangedWhileBuilding(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	base := &listingBase{Repo: memory.New()}
+	r := New(base, redis.NewClient(&redis.Options{Addr: mr.Addr()}), time.Minute)
+
+	// una escritura entre la lectura de la base y el guardado del blob
+	base.during = func() {
+		base.during = nil
+		if err := r.Create(ctx, &core.FeatureFlag{Key: "late_flag"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	stale, err := r.Snapshot(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mr.Exists(snapshotKey) {
+		t.Error("stale snapshot was stored in Redis")
+	}
+
+	fresh, _ := r.Snapshot(ctx)
+	if fresh.Version <= stale.Version || len(fresh.Flags) != 1 {
+		t.Errorf("fresh snapshot = %+v, stale version %d", fresh, stale.Version)
+	}
+}
+
+// blockingBase frena List y GetByKey hasta que se cierre release y avisa cuando entran
+type blockingBase struct {
+	*memory.Repo
+	release chan struct{}
+	entered chan string
+}
+
+func (b *blockingBase) List(ctx context.Context) ([]core.FeatureFlag, error) {
+	b.entered <- "list"
+	<-b.release
+	return b.Repo.List(ctx)
+}
+
+func (b *blockingBase) GetByKey(ctx context.Context, key string) (*core.FeatureFlag, error) {
+	b.entered <- "key:" + key
+	<-b.release
+	return b.Repo.GetByKey(ctx, key)
+}
+
+// una flag que se llama como la key del snapshot no puede sumarse a su lectura
+func TestSnapshotAndGetByKeyDoNotShareFills(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	base := &blockingBase{Repo: memory.New(), release: make(chan struct{}), entered: make(chan string, 2)}
+	r := New(base, redis.NewClient(&redis.Options{Addr: mr.Addr()}), time.Minute)
+
+	snapErr := make(chan error, 1)
+	go func() {
+		_, err := r.Snapshot(ctx)
+		snapErr <- err
+	}()
+	keyErr := make(chan error, 1)
+	go func() {
+		_, err := r.GetByKey(ctx, snapshotKey)
+		keyErr <- err
+	}()
+
+	// las dos lecturas tienen que llegar a la base por separado
+	for range 2 {
+		select {
+		case <-base.entered:
+		case <-time.After(2 * time.Second):
+			t.Fatal("Snapshot and GetByKey(" + snapshotKey + ") were coalesced into one read")
+		}
+	}
+	close(base.release)
+
+	if err := <-snapErr; err != nil {
+		t.Errorf("Snapshot: %v", err)
+	}
+	if err := <-keyErr; !errors.Is(err, repo.ErrNotFound) {
+		t.Errorf("GetByKey: %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,42 @@
+package repo
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Franconl/ffaas/internal/core"
+)
+
+// ChangeOp es la operacion de un Change dentro de un Apply
+type ChangeOp string
+
+const (
+	ChangeCreate  ChangeOp = "create"
+	ChangeUpdate  ChangeOp = "update"
+	ChangeArchive ChangeOp = "archive"
+)
+
+// Change es una modificacion a aplicar en lote. Para create y update se usa la
+// flag completa; para archive alcanza con Flag.ID.
+//
+// IfUpdatedAt es una precondicion opcional de los update: si no es cero, el
+// cambio solo se aplica si la flag guardada sigue teniendo ese updated_at
+// (ErrStale si no). Se chequea en el mismo lock/transaccion que la escritura.
+type Change struct {
+	Op          ChangeOp
+	Flag        core.FeatureFlag
+	IfUpdatedAt time.Time
+}
+
+// ChangeError indica que Change fallo dentro de un Apply (y que no se aplico
+// nada). Index es la posicion del cambio en la lista recibida.
+type ChangeError struct {
+	Index int
+	Op    ChangeOp
+	Key   string
+	Err   error
+}
+
+func (e *ChangeError) Error() string { return fmt.Sprintf("%s %s: %v", e.Op, e.Key, e.Err) }
+
+func (e *ChangeError) Unwrap() error { return e.Err }
@@ -2,12 +2,41 @@ package repo
 
 import (
 	"errors"
+
+	"github.com/Franconl/ffaas/internal/core"
 )
 
 var (
 	ErrNotFound       = errors.New("not found")
 	ErrKeyAlreadyUsed = errors.New("flag key already exists")
-	ErrKeyRequired    = errors.New("key is required")
-	ErrInvalidPercent = errors.New("invalid percentage")
+	ErrKeyRequired    = core.ErrKeyRequired
+	ErrInvalidPercent = core.ErrInvalidPercent
+	ErrInvalidKind    = core.ErrInvalidKind
 	ErrInvalidBody    = errors.New("invalid JSON body")
+	ErrInvalidCursor  = errors.New("invalid cursor")
+	ErrArchived       = errors.New("flag is archived")
+	ErrNotArchived    = errors.New("flag is not archived")
+	ErrRetention      = errors.New("archived flag is still within the retention period")
+	ErrReadOnly       = errors.New("flags are read-only: this instance loads them from files, change the files instead")
+	// ErrStale: el update tenia una precondicion (Change.IfUpdatedAt) y la flag cambio desde entonces
+	ErrStale = errors.New("flag was modified since it was read")
+	// ErrUnavailable: el backend esta caido (circuit breaker abierto); no es de negocio
+	ErrUnavailable = errors.New("flag store unavailable")
 )
+
+// IsDomainError indica si el error es de negocio (input invalido, conflicto,
+// not found) y no una falla del backend
+func IsDomainError(err error) bool {
+	var verr *core.ValidationError
+	if errors.As(err, &verr) {
+		return true
+	}
+	for _, target := range []error{
+		ErrNotFound, ErrKeyAlreadyUsed, ErrInvalidCursor, ErrArchived, ErrNotArchived, ErrRetention, ErrReadOnly, ErrStale,
+	} {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
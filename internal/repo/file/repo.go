@@ -0,0 +1,260 @@
+// Package file es un backend de solo lectura que carga las flags desde un
+// directorio de documentos YAML/JSON (el mismo formato que el export, ver
+// internal/flagfile). Pensado para GitOps: las flags se cambian en el repo y
+// el servidor recarga el directorio solo.
+package file
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/Franconl/ffaas/internal/core"
+	"github.com/Franconl/ffaas/internal/flagfile"
+	"github.com/Franconl/ffaas/internal/repo"
+	"github.com/google/uuid"
+)
+
+// Errores compartidos con el resto de los backends
+var (
+	ErrNotFound = repo.ErrNotFound
+	ErrReadOnly = repo.ErrReadOnly
+)
+
+// namespace de los ids: el id de una flag es uuid v5 de su key, asi es estable
+// entre recargas y entre instancias que leen los mismos archivos
+var namespace = uuid.MustParse("6f1c7f4e-4b0c-4a55-9d3c-0f6c2b6a9e21")
+
+// state es una foto inmutable del directorio; se reemplaza entera al recargar
+type state struct {
+	byID  map[string]core.FeatureFlag
+	byKey map[string]string
+	// fingerprint de los archivos leidos, para detectar cambios sin releerlos
+	fingerprint string
+}
+
+type Repo struct {
+	dir   string
+	state atomic.Pointer[state]
+}
+
+// New carga el directorio. Si algun archivo es invalido devuelve error:
+// al arrancar no se sirve un set de flags a medias.
+func New(dir string) (*Repo, error) {
+	r := &Repo{dir: dir}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reload relee el directorio y, si todo es valido, reemplaza el estado de
+// una vez. Si falla, se sigue sirviendo el estado anterior.
+func (r *Repo) Reload() error {
+	fp, err := fingerprint(r.dir)
+	if err != nil {
+		return err
+	}
+	st, err := load(r.dir)
+	if err != nil {
+		return err
+	}
+	st.fingerprint = fp
+	r.state.Store(st)
+	return nil
+}
+
+// Watch revisa el directorio cada interval y recarga si cambio algun archivo.
+// Corre hasta que se cancela ctx.
+func (r *Repo) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	// ultima version invalida: se loguea una sola vez hasta que cambie de nuevo
+	var failed string
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			fp, err := fingerprint(r.dir)
+			if err != nil {
+				log.Println("⚠️ Error leyendo el directorio de flags:", err)
+				continue
+			}
+			if fp == r.state.Load().fingerprint || fp == failed {
+				continue
+			}
+			if err := r.Reload(); err != nil {
+				failed = fp
+				log.Println("⚠️ Flags no recargadas, se mantiene la version anterior:", err)
+				continue
+			}
+			log.Printf("🔄 Flags recargadas desde %s (%d flags)", r.dir, len(r.state.Load().byID))
+		}
+	}
+}
+
+// flagFiles lista los documentos del directorio (recursivo), en orden estable
+func flagFiles(dir string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			// directorios ocultos (.git) no
+			if path != dir && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".yaml", ".yml", ".json":
+			files = append(files, path)
+		}
+		return nil
+	})
+	sort.Strings(files)
+	return files, err
+}
+
+// fingerprint resume nombre, tamaño y fecha de modificacion de cada archivo
+func fingerprint(dir string) (string, error) {
+	files, err := flagFiles(dir)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	for _, path := range files {
+		info, err := os.Stat(path)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&b, "%s:%d:%d;", path, info.Size(), info.ModTime().UnixNano())
+	}
+	return b.String(), nil
+}
+
+func load(dir string) (*state, error) {
+	files, err := flagFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	st := &state{
+		byID:  make(map[string]core.FeatureFlag),
+		byKey: make(map[string]string),
+	}
+	source := make(map[string]string)
+
+	for _, path := range files {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		format := flagfile.FormatYAML
+		if strings.EqualFold(filepath.Ext(path), ".json") {
+			format = flagfile.FormatJSON
+		}
+		doc, err := flagfile.Decode(data, format)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, f := range doc.ToFlags() {
+			if other, dup := source[f.Key]; dup {
+				return nil, fmt.Errorf("%s: flag %q is already defined in %s", path, f.Key, other)
+			}
+			source[f.Key] = path
+
+			f.ID = uuid.NewSHA1(namespace, []byte(f.Key)).String()
+			f.CreatedAt = info.ModTime().UTC()
+			f.UpdatedAt = f.CreatedAt
+			st.byID[f.ID] = f
+			st.byKey[f.Key] = f.ID
+		}
+	}
+
+	return st, nil
+}
+
+// --- lectura ---
+
+func (r *Repo) GetByID(ctx context.Context, id string) (*core.FeatureFlag, error) {
+	f, ok := r.state.Load().byID[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	cur := clone(f)
+	return &cur, nil
+}
+
+func (r *Repo) GetByKey(ctx context.Context, key string) (*core.FeatureFlag, error) {
+	st := r.state.Load()
+	id, ok := st.byKey[key]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	cur := clone(st.byID[id])
+	return &cur, nil
+}
+
+func (r *Repo) List(ctx context.Context) ([]core.FeatureFlag, error) {
+	st := r.state.Load()
+	list := make([]core.FeatureFlag, 0, len(st.byID))
+	for _, f := range st.byID {
+		list = append(list, clone(f))
+	}
+	repo.SortFlags(list)
+	return list, nil
+}
+
+func (r *Repo) Search(ctx context.Context, q repo.Query) (repo.Page, error) {
+	st := r.state.Load()
+	list := make([]core.FeatureFlag, 0)
+	for _, f := range st.byID {
+		if q.Matches(f) {
+			list = append(list, clone(f))
+		}
+	}
+	return repo.Paginate(list, q)
+}
+
+func clone(f core.FeatureFlag) core.FeatureFlag {
+	f.Tags = slices.Clone(f.Tags)
+	return f
+}
+
+// --- escritura: no se permite ---
+
+func (r *Repo) Create(ctx context.Context, f *core.FeatureFlag) error { return ErrReadOnly }
+
+func (r *Repo) Update(ctx context.Context, f *core.FeatureFlag) error { return ErrReadOnly }
+
+func (r *Repo) DeleteByID(ctx context.Context, id string) error { return ErrReadOnly }
+
+func (r *Repo) Archive(ctx context.Context, id string) error { return ErrReadOnly }
+
+func (r *Repo) Restore(ctx context.Context, id string) error { return ErrReadOnly }
+
+func (r *Repo) Purge(ctx context.Context, id string, archivedBefore time.Time) error {
+	return ErrReadOnly
+}
+
+func (r *Repo) Apply(ctx context.Context, changes []repo.Change) error { return ErrReadOnly }
+
+var _ repo.Flags = (*Repo)(nil)
@@ -0,0 +1,68 @@
+package file
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Franconl/ffaas/internal/core"
+	"github.com/Franconl/ffaas/internal/repo"
+)
+
+func writeFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadAndReload(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	writeFile(t, dir, "checkout.yaml", "version: 1\nflags:\n  - key: new_checkout\n    enabled: true\n    percentage: 50\n")
+	writeFile(t, dir, "ui.json", `{"version": 1, "flags": [{"key": "dark_mode", "percentage": 100}]}`)
+
+	r, err := New(dir)
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+
+	f, err := r.GetByKey(ctx, "new_checkout")
+	if err != nil || !f.Enabled || f.Percentage != 50 {
+		t.Fatalf("unexpected flag %+v (%v)", f, err)
+	}
+	id := f.ID
+
+	// una version invalida no reemplaza la anterior
+	writeFile(t, dir, "ui.json", `{"version": 1, "flags": [{"key": "new_checkout"}]}`)
+	if err := r.Reload(); err == nil {
+		t.Fatal("expected duplicated key error")
+	}
+	if _, err := r.GetByKey(ctx, "dark_mode"); err != nil {
+		t.Errorf("expected previous state to be kept, got %v", err)
+	}
+
+	writeFile(t, dir, "ui.json", `{"version": 1, "flags": []}`)
+	if err := r.Reload(); err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	if _, err := r.GetByKey(ctx, "dark_mode"); !errors.Is(err, repo.ErrNotFound) {
+		t.Errorf("expected dark_mode to be gone, got %v", err)
+	}
+	// el id sale de la key: es estable entre recargas
+	if f, _ := r.GetByKey(ctx, "new_checkout"); f == nil || f.ID != id {
+		t.Errorf("expected stable id %s, got %+v", id, f)
+	}
+}
+
+func TestMutationsAreRejected(t *testing.T) {
+	r, err := New(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Create(context.Background(), &core.FeatureFlag{Key: "x"}); !errors.Is(err, repo.ErrReadOnly) {
+		t.Errorf("expected ErrReadOnly, got %v", err)
+	}
+}
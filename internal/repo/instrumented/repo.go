@@ -0,0 +1,125 @@
+package instrumented
+
+import (
+	"context"
+	"time"
+
+	"github.com/Franconl/ffaas/internal/core"
+	"github.com/Franconl/ffaas/internal/metrics"
+	"github.com/Franconl/ffaas/internal/repo"
+	"github.com/Franconl/ffaas/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Repo decora cualquier repo.Flags con un span por metodo y metricas de latencia
+// y errores, etiquetados con el nombre del backend (memory, postgres, cached, ...)
+type Repo struct {
+	base    repo.Flags
+	backend string
+}
+
+func New(base repo.Flags, backend string) *Repo {
+	return &Repo{base: base, backend: backend}
+}
+
+// start abre el span de la llamada y devuelve la funcion que la cierra,
+// registrando duracion y, si corresponde, el error. Los errores de negocio
+// (not found, key duplicada, validacion) no se cuentan como errores del backend.
+func (r *Repo) start(ctx context.Context, method string) (context.Context, func(error)) {
+	begin := time.Now()
+	ctx, span := tracing.Tracer().Start(ctx, r.backend+"."+method,
+		trace.WithAttributes(
+			attribute.String("repo.backend", r.backend),
+			attribute.String("repo.method", method),
+		),
+	)
+	return ctx, func(err error) {
+		metrics.RepoCallDuration.WithLabelValues(r.backend, method).Observe(time.Since(begin).Seconds())
+		if err != nil && !repo.IsDomainError(err) {
+			metrics.RepoCallErrors.WithLabelValues(r.backend, method).Inc()
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}
+}
+
+func (r *Repo) Create(ctx context.Context, f *core.FeatureFlag) error {
+	ctx, done := r.start(ctx, "Create")
+	err := r.base.Create(ctx, f)
+	done(err)
+	return err
+}
+
+func (r *Repo) Update(ctx context.Context, f *core.FeatureFlag) error {
+	ctx, done := r.start(ctx, "Update")
+	err := r.base.Update(ctx, f)
+	done(err)
+	return err
+}
+
+func (r *Repo) DeleteByID(ctx context.Context, id string) error {
+	ctx, done := r.start(ctx, "DeleteByID")
+	err := r.base.DeleteByID(ctx, id)
+	done(err)
+	return err
+}
+
+func (r *Repo) Archive(ctx context.Context, id string) error {
+	ctx, done := r.start(ctx, "Archive")
+	err := r.base.Archive(ctx, id)
+	done(err)
+	return err
+}
+
+func (r *Repo) Restore(ctx context.Context, id string) error {
+	ctx, done := r.start(ctx, "Restore")
+	err := r.base.Restore(ctx, id)
+	done(err)
+	return err
+}
+
+func (r *Repo) Purge(ctx context.Context, id string, archivedBefore time.Time) error {
+	ctx, done := r.start(ctx, "Purge")
+	err := r.base.Purge(ctx, id, archivedBefore)
+	done(err)
+	return err
+}
+
+func (r *Repo) GetByID(ctx context.Context, id string) (*core.FeatureFlag, error) {
+	ctx, done := r.start(ctx, "GetByID")
+	ff, err := r.base.GetByID(ctx, id)
+	done(err)
+	return ff, err
+}
+
+func (r *Repo) GetByKey(ctx context.Context, key string) (*core.FeatureFlag, error) {
+	ctx, done := r.start(ctx, "GetByKey")
+	ff, err := r.base.GetByKey(ctx, key)
+	done(err)
+	return ff, err
+}
+
+func (r *Repo) List(ctx context.Context) ([]core.FeatureFlag, error) {
+	ctx, done := r.start(ctx, "List")
+	list, err := r.base.List(ctx)
+	done(err)
+	return list, err
+}
+
+func (r *Repo) Search(ctx context.Context, q repo.Query) (repo.Page, error) {
+	ctx, done := r.start(ctx, "Search")
+	page, err := r.base.Search(ctx, q)
+	done(err)
+	return page, err
+}
+
+func (r *Repo) Apply(ctx context.Context, changes []repo.Change) error {
+	ctx, done := r.start(ctx, "Apply")
+	trace.SpanFromContext(ctx).SetAttributes(attribute.Int("repo.changes", len(changes)))
+	err := r.base.Apply(ctx, changes)
+	done(err)
+	return err
+}
@@ -0,0 +1,57 @@
+package instrumented
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Franconl/ffaas/internal/core"
+	"github.com/Franconl/ffaas/internal/metrics"
+	"github.com/Franconl/ffaas/internal/repo/memory"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// brokenRepo falla las lecturas por key como una base caida
+type brokenRepo struct {
+	*memory.Repo
+}
+
+func (brokenRepo) GetByKey(ctx context.Context, key string) (*core.FeatureFlag, error) {
+	return nil, errors.New("connection refused")
+}
+
+func TestDomainErrorsAreNotBackendErrors(t *testing.T) {
+	ctx := context.Background()
+	r := New(memory.New(), "test-domain")
+
+	if err := r.Create(ctx, &core.FeatureFlag{Key: "new_checkout"}); err != nil {
+		t.Fatal(err)
+	}
+	// not found, key duplicada y validacion son errores de negocio
+	if _, err := r.GetByKey(ctx, "missing"); err == nil {
+		t.Fatal("expected not found")
+	}
+	if err := r.Create(ctx, &core.FeatureFlag{Key: "new_checkout"}); err == nil {
+		t.Fatal("expected duplicate key")
+	}
+	if err := r.Create(ctx, &core.FeatureFlag{Key: "bad key!"}); err == nil {
+		t.Fatal("expected validation error")
+	}
+
+	for _, method := range []string{"GetByKey", "Create"} {
+		if n := testutil.ToFloat64(metrics.RepoCallErrors.WithLabelValues("test-domain", method)); n != 0 {
+			t.Errorf("%s: %v backend errors, want 0", method, n)
+		}
+	}
+}
+
+func TestBackendErrorsAreCounted(t *testing.T) {
+	r := New(brokenRepo{memory.New()}, "test-broken")
+
+	if _, err := r.GetByKey(context.Background(), "new_checkout"); err == nil {
+		t.Fatal("expected error")
+	}
+	if n := testutil.ToFloat64(metrics.RepoCallErrors.WithLabelValues("test-broken", "GetByKey")); n != 1 {
+		t.Errorf("%v backend errors, want 1", n)
+	}
+}
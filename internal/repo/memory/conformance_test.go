@@ -0,0 +1,23 @@
+package memory
+
+import (
+	"testing"
+
+	"github.com/Franconl/ffaas/internal/repo"
+	"github.com/Franconl/ffaas/internal/repo/repotest"
+)
+
+func TestConformance(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) repo.Flags { return New() })
+}
+
+func TestConformancePersisted(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) repo.Flags {
+		r, err := Open(t.TempDir())
+		if err != nil {
+			t.Fatalf("open: %v", err)
+		}
+		t.Cleanup(func() { r.Close() })
+		return r
+	})
+}
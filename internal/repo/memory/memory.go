@@ -1,11 +1,16 @@
 package memory
 
 import (
-	"errors"
+	"context"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
 	"sync"
 	"time"
 
 	"github.com/Franconl/ffaas/internal/core"
+	"github.com/Franconl/ffaas/internal/repo"
 	"github.com/google/uuid"
 )
 
@@ -13,28 +18,43 @@ type Repo struct {
 	mu    sync.RWMutex
 	byID  map[string]core.FeatureFlag
 	byKey map[string]string
+	usage map[string]core.FlagUsage
+
+	// persistencia opcional (ver Open en persist.go): nil = solo memoria
+	dir string
+	wal *os.File
 }
 
 func New() *Repo {
 	return &Repo{
 		byID:  make(map[string]core.FeatureFlag),
 		byKey: make(map[string]string),
+		usage: make(map[string]core.FlagUsage),
 	}
 }
 
+// Errores compartidos con el resto de los backends
 var (
-	ErrNotFound       = errors.New("not found")
-	ErrKeyAlreadyUsed = errors.New("flag key already exists")
-	ErrKeyRequired    = errors.New("key is required")
-	ErrInvalidPercent = errors.New("invalid percentage")
+	ErrNotFound       = repo.ErrNotFound
+	ErrKeyAlreadyUsed = repo.ErrKeyAlreadyUsed
+	ErrKeyRequired    = repo.ErrKeyRequired
+	ErrInvalidPercent = repo.ErrInvalidPercent
+	ErrInvalidKind    = repo.ErrInvalidKind
+	ErrArchived       = repo.ErrArchived
+	ErrNotArchived    = repo.ErrNotArchived
+	ErrRetention      = repo.ErrRetention
 )
 
-func (r *Repo) Create(f *core.FeatureFlag) error {
-	r.mu.Lock()
-	defer r.mu.Unlock()
+func (r *Repo) Create(ctx context.Context, f *core.FeatureFlag) error {
+	return r.mutate(func() error { return r.create(f) })
+}
 
-	if f.Key == "" {
-		return ErrNotFound
+// create, update y archive asumen el lock tomado (los comparte Apply).
+// Todas las mutaciones pasan por mutate, que toma el lock y las persiste.
+func (r *Repo) create(f *core.FeatureFlag) error {
+	f.Normalize()
+	if err := f.Validate(); err != nil {
+		return err
 	}
 
 	if _, exist := r.byKey[f.Key]; exist {
@@ -48,25 +68,38 @@ func (r *Repo) Create(f *core.FeatureFlag) error {
 	now := time.Now()
 	f.CreatedAt = now
 	f.UpdatedAt = now
+	f.RolloutChangedAt = now
 
-	r.byID[f.ID] = *f
+	r.byID[f.ID] = clone(*f)
 	r.byKey[f.Key] = f.ID
 
 	return nil
 }
 
-func (r *Repo) Update(f *core.FeatureFlag) error {
-	r.mu.Lock()
-	defer r.mu.Unlock()
+func (r *Repo) Update(ctx context.Context, f *core.FeatureFlag) error {
+	return r.mutate(func() error { return r.update(f, time.Time{}) })
+}
 
+// update pisa la flag; si ifUpdatedAt no es cero, solo si la guardada sigue
+// teniendo ese updated_at
+func (r *Repo) update(f *core.FeatureFlag, ifUpdatedAt time.Time) error {
 	cur, exist := r.byID[f.ID]
 
 	if !exist {
 		return ErrNotFound
 	}
 
-	if f.Key == "" {
-		return ErrKeyRequired
+	if cur.Archived() {
+		return ErrArchived
+	}
+
+	if !ifUpdatedAt.IsZero() && !cur.UpdatedAt.Equal(ifUpdatedAt) {
+		return repo.ErrStale
+	}
+
+	f.Normalize()
+	if err := f.Validate(); err != nil {
+		return err
 	}
 
 	if cur.Key != f.Key {
@@ -80,25 +113,39 @@ func (r *Repo) Update(f *core.FeatureFlag) error {
 		}
 	}
 
+	now := time.Now()
+	if cur.Enabled != f.Enabled || cur.Percentage != f.Percentage {
+		cur.RolloutChangedAt = now
+	}
+
 	cur.Key = f.Key
 	cur.Description = f.Description
 	cur.Enabled = f.Enabled
 	cur.Percentage = f.Percentage
-	cur.UpdatedAt = time.Now()
+	cur.Owner = f.Owner
+	cur.Tags = slices.Clone(f.Tags)
+	cur.Kind = f.Kind
+	cur.Temporary = f.Temporary
+	cur.ExpiresAt = f.ExpiresAt
+	cur.UpdatedAt = now
+	f.UpdatedAt = cur.UpdatedAt
+	f.RolloutChangedAt = cur.RolloutChangedAt
 
 	r.byID[f.ID] = cur
 
 	return nil
 }
 
-func (r *Repo) DeleteByID(id string) error {
-	r.mu.Lock()
-	defer r.mu.Unlock()
+func (r *Repo) DeleteByID(ctx context.Context, id string) error {
+	return r.mutate(func() error { return r.deleteByID(id) })
+}
 
+func (r *Repo) deleteByID(id string) error {
 	if flag, exist := r.byID[id]; exist {
 
 		delete(r.byID, id)
 		delete(r.byKey, flag.Key)
+		delete(r.usage, id)
 
 	} else {
 		return ErrNotFound
@@ -107,7 +154,80 @@ func (r *Repo) DeleteByID(id string) error {
 	return nil
 }
 
-func (r *Repo) GetByID(id string) (*core.FeatureFlag, error) {
+func (r *Repo) Archive(ctx context.Context, id string) error {
+	return r.mutate(func() error { return r.archive(id) })
+}
+
+func (r *Repo) archive(id string) error {
+	cur, exist := r.byID[id]
+
+	if !exist {
+		return ErrNotFound
+	}
+
+	if cur.Archived() {
+		return ErrArchived
+	}
+
+	now := time.Now()
+	cur.ArchivedAt = &now
+	cur.UpdatedAt = now
+
+	r.byID[id] = cur
+
+	return nil
+}
+
+func (r *Repo) Restore(ctx context.Context, id string) error {
+	return r.mutate(func() error { return r.restore(id) })
+}
+
+func (r *Repo) restore(id string) error {
+	cur, exist := r.byID[id]
+
+	if !exist {
+		return ErrNotFound
+	}
+
+	if !cur.Archived() {
+		return ErrNotArchived
+	}
+
+	cur.ArchivedAt = nil
+	cur.UpdatedAt = time.Now()
+
+	r.byID[id] = cur
+
+	return nil
+}
+
+func (r *Repo) Purge(ctx context.Context, id string, archivedBefore time.Time) error {
+	return r.mutate(func() error { return r.purge(id, archivedBefore) })
+}
+
+func (r *Repo) purge(id string, archivedBefore time.Time) error {
+	cur, exist := r.byID[id]
+
+	if !exist {
+		return ErrNotFound
+	}
+
+	if !cur.Archived() {
+		return ErrNotArchived
+	}
+
+	if cur.ArchivedAt.After(archivedBefore) {
+		return ErrRetention
+	}
+
+	delete(r.byID, id)
+	delete(r.byKey, cur.Key)
+	delete(r.usage, id)
+
+	return nil
+}
+
+func (r *Repo) GetByID(ctx context.Context, id string) (*core.FeatureFlag, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
@@ -117,11 +237,11 @@ func (r *Repo) GetByID(id string) (*core.FeatureFlag, error) {
 		return nil, ErrNotFound
 	}
 
-	cur := flag
+	cur := clone(flag)
 	return &cur, nil
 }
 
-func (r *Repo) GetByKey(key string) (*core.FeatureFlag, error) {
+func (r *Repo) GetByKey(ctx context.Context, key string) (*core.FeatureFlag, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
@@ -131,18 +251,117 @@ func (r *Repo) GetByKey(key string) (*core.FeatureFlag, error) {
 		return nil, ErrNotFound
 	}
 
-	cur := r.byID[id]
+	cur := clone(r.byID[id])
 	return &cur, nil
 }
 
-func (r *Repo) List() ([]core.FeatureFlag, error) {
+func (r *Repo) List(ctx context.Context) ([]core.FeatureFlag, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
 	list := make([]core.FeatureFlag, 0, len(r.byID))
 
 	for _, f := range r.byID {
-		list = append(list, f)
+		if !f.Archived() {
+			list = append(list, clone(f))
+		}
+	}
+
+	repo.SortFlags(list)
+	return list, nil
+}
+
+// Search filtra en memoria y ordena/pagina con el mismo criterio que postgres
+func (r *Repo) Search(ctx context.Context, q repo.Query) (repo.Page, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	list := make([]core.FeatureFlag, 0)
+
+	for _, f := range r.byID {
+		if q.Matches(f) {
+			list = append(list, clone(f))
+		}
+	}
+
+	return repo.Paginate(list, q)
+}
+
+// Apply aplica los cambios sobre el estado actual y, si alguno falla,
+// vuelve a los mapas anteriores: nadie ve un estado intermedio porque se
+// mantiene el lock durante todo el lote. Cada Flag queda con lo guardado.
+func (r *Repo) Apply(ctx context.Context, changes []repo.Change) error {
+	return r.mutate(func() error { return r.apply(changes) })
+}
+
+func (r *Repo) apply(changes []repo.Change) error {
+	byID := maps.Clone(r.byID)
+	byKey := maps.Clone(r.byKey)
+
+	for i := range changes {
+		c := &changes[i]
+		var err error
+		switch c.Op {
+		case repo.ChangeCreate:
+			err = r.create(&c.Flag)
+		case repo.ChangeUpdate:
+			err = r.update(&c.Flag, c.IfUpdatedAt)
+		case repo.ChangeArchive:
+			err = r.archive(c.Flag.ID)
+		default:
+			err = fmt.Errorf("unknown change op %q", c.Op)
+		}
+		if err != nil {
+			r.byID, r.byKey = byID, byKey
+			return &repo.ChangeError{Index: i, Op: c.Op, Key: c.Flag.Key, Err: err}
+		}
+		c.Flag = clone(r.byID[c.Flag.ID])
+	}
+
+	return nil
+}
+
+// clone evita compartir los slices de la flag guardada con quien la recibe
+func clone(f core.FeatureFlag) core.FeatureFlag {
+	f.Tags = slices.Clone(f.Tags)
+	return f
+}
+
+// --- Usage ---
+
+func (r *Repo) AddUsage(ctx context.Context, usage []core.FlagUsage) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	merged := make([]core.FlagUsage, 0, len(usage))
+	for _, u := range usage {
+		cur := r.usage[u.FlagID]
+		cur.FlagID = u.FlagID
+		cur.Merge(u)
+		merged = append(merged, cur)
+	}
+
+	// en el log va el valor acumulado, no el delta: reaplicarlo es idempotente
+	if r.wal != nil && len(merged) > 0 {
+		if err := r.append(walRecord{Usage: merged}); err != nil {
+			return fmt.Errorf("memory wal: %w", err)
+		}
+	}
+	for _, u := range merged {
+		r.usage[u.FlagID] = u
+	}
+
+	return nil
+}
+
+func (r *Repo) ListUsage(ctx context.Context) ([]core.FlagUsage, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	list := make([]core.FlagUsage, 0, len(r.usage))
+
+	for _, u := range r.usage {
+		list = append(list, u)
 	}
 
 	return list, nil
@@ -0,0 +1,331 @@
+package memory
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"maps"
+	"os"
+	"path/filepath"
+	"reflect"
+	"time"
+
+	"github.com/Franconl/ffaas/internal/core"
+)
+
+// Persistencia opcional del repo en memoria: un snapshot periodico del estado
+// completo mas un write-ahead log (JSONL) con cada mutacion desde el ultimo
+// snapshot. Al arrancar se carga el snapshot y se reaplica el log.
+//
+// Cada registro del log guarda el estado final de lo que cambio (flags
+// completas o ids borrados), no la operacion: reaplicarlo dos veces da lo
+// mismo, asi que no importa si el proceso muere entre el snapshot y el
+// truncado del log.
+
+const (
+	snapshotFile    = "flags.snapshot.json"
+	walFile         = "flags.wal"
+	snapshotVersion = 1
+)
+
+type snapshot struct {
+	Version int                `json:"version"`
+	TakenAt time.Time          `json:"taken_at"`
+	Flags   []core.FeatureFlag `json:"flags"`
+	Usage   []core.FlagUsage   `json:"usage"`
+}
+
+// walRecord es una linea del log. Una mutacion (incluido un Apply con varios
+// cambios) es un solo registro: o se reaplica entera o no se reaplica.
+type walRecord struct {
+	Puts    []core.FeatureFlag `json:"puts,omitempty"`
+	Deletes []string           `json:"deletes,omitempty"`
+	Usage   []core.FlagUsage   `json:"usage,omitempty"`
+}
+
+// Open crea un repo en memoria persistido en dir: restaura el ultimo snapshot,
+// reaplica el log y deja el log abierto para las proximas mutaciones.
+func Open(dir string) (*Repo, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	r := New()
+	r.dir = dir
+
+	if err := r.loadSnapshot(); err != nil {
+		return nil, err
+	}
+
+	wal, err := os.OpenFile(filepath.Join(dir, walFile), os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.replay(wal); err != nil {
+		wal.Close()
+		return nil, err
+	}
+	r.wal = wal
+
+	return r, nil
+}
+
+func (r *Repo) loadSnapshot() error {
+	data, err := os.ReadFile(filepath.Join(r.dir, snapshotFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var snap snapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return fmt.Errorf("memory snapshot: %w", err)
+	}
+	if snap.Version != snapshotVersion {
+		return fmt.Errorf("memory snapshot: unsupported version %d", snap.Version)
+	}
+
+	for _, f := range snap.Flags {
+		r.byID[f.ID] = f
+		r.byKey[f.Key] = f.ID
+	}
+	for _, u := range snap.Usage {
+		r.usage[u.FlagID] = u
+	}
+	return nil
+}
+
+// replay reaplica el log. Una ultima linea incompleta (el proceso murio a mitad
+// de la escritura) se descarta y se trunca; una linea rota en el medio es error.
+func (r *Repo) replay(wal *os.File) error {
+	reader := bufio.NewReader(wal)
+	var offset int64
+
+	for {
+		line, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			if len(bytes.TrimSpace(line)) > 0 {
+				log.Printf("⚠️ WAL: se descarta un registro incompleto al final de %s", wal.Name())
+			}
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		var rec walRecord
+		if err := json.Unmarshal(line, &rec); err != nil {
+			return fmt.Errorf("memory wal: corrupt record at offset %d: %w", offset, err)
+		}
+		r.applyRecord(rec)
+		offset += int64(len(line))
+	}
+
+	if err := wal.Truncate(offset); err != nil {
+		return err
+	}
+	_, err := wal.Seek(offset, io.SeekStart)
+	return err
+}
+
+func (r *Repo) applyRecord(rec walRecord) {
+	for _, f := range rec.Puts {
+		if old, ok := r.byID[f.ID]; ok && old.Key != f.Key {
+			delete(r.byKey, old.Key)
+		}
+		r.byID[f.ID] = f
+		r.byKey[f.Key] = f.ID
+	}
+	for _, id := range rec.Deletes {
+		if old, ok := r.byID[id]; ok {
+			delete(r.byKey, old.Key)
+			delete(r.byID, id)
+		}
+		delete(r.usage, id)
+	}
+	for _, u := range rec.Usage {
+		r.usage[u.FlagID] = u
+	}
+}
+
+// append escribe un registro en el log y hace fsync antes de devolver. Si la
+// escritura falla a medias, se trunca lo escrito para no dejar una linea rota
+// en el medio del log.
+func (r *Repo) append(rec walRecord) error {
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	pos, err := r.wal.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	_, err = r.wal.Write(append(line, '\n'))
+	if err == nil {
+		err = r.wal.Sync()
+	}
+	if err != nil {
+		_ = r.wal.Truncate(pos)
+		_, _ = r.wal.Seek(pos, io.SeekStart)
+	}
+	return err
+}
+
+// mutate corre fn con el lock tomado. Con persistencia, compara el estado antes
+// y despues, escribe el cambio en el log y, si la escritura falla, vuelve al
+// estado anterior: lo que esta en memoria siempre esta en disco.
+// Comparar cuesta O(n) flags por escritura, razonable para un nodo con pocas
+// miles de flags y mutaciones de administracion.
+func (r *Repo) mutate(fn func() error) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.wal == nil {
+		return fn()
+	}
+
+	byID := maps.Clone(r.byID)
+	byKey := maps.Clone(r.byKey)
+	usage := maps.Clone(r.usage)
+
+	if err := fn(); err != nil {
+		return err
+	}
+
+	var rec walRecord
+	for id, f := range r.byID {
+		if old, ok := byID[id]; !ok || !reflect.DeepEqual(old, f) {
+			rec.Puts = append(rec.Puts, f)
+		}
+	}
+	for id := range byID {
+		if _, ok := r.byID[id]; !ok {
+			rec.Deletes = append(rec.Deletes, id)
+		}
+	}
+	if len(rec.Puts) == 0 && len(rec.Deletes) == 0 {
+		return nil
+	}
+
+	if err := r.append(rec); err != nil {
+		r.byID, r.byKey, r.usage = byID, byKey, usage
+		return fmt.Errorf("memory wal: %w", err)
+	}
+	return nil
+}
+
+// Snapshot escribe el estado completo (archivo temporal + fsync + rename +
+// fsync del directorio) y despues vacia el log.
+func (r *Repo) Snapshot() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.wal == nil {
+		return nil
+	}
+
+	snap := snapshot{
+		Version: snapshotVersion,
+		TakenAt: time.Now().UTC(),
+		Flags:   make([]core.FeatureFlag, 0, len(r.byID)),
+		Usage:   make([]core.FlagUsage, 0, len(r.usage)),
+	}
+	for _, f := range r.byID {
+		snap.Flags = append(snap.Flags, f)
+	}
+	for _, u := range r.usage {
+		snap.Usage = append(snap.Usage, u)
+	}
+
+	data, err := json.Marshal(snap)
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(filepath.Join(r.dir, snapshotFile), data); err != nil {
+		return err
+	}
+
+	if err := r.wal.Truncate(0); err != nil {
+		return err
+	}
+	if _, err := r.wal.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	return r.wal.Sync()
+}
+
+// RunSnapshots toma un snapshot cada interval y uno final al cancelarse ctx
+func (r *Repo) RunSnapshots(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			if err := r.Snapshot(); err != nil {
+				log.Println("⚠️ Error en el snapshot final:", err)
+			}
+			return
+		case <-ticker.C:
+			if err := r.Snapshot(); err != nil {
+				log.Println("⚠️ Error tomando snapshot:", err)
+			}
+		}
+	}
+}
+
+// Close cierra el log. No toma snapshot: para eso esta RunSnapshots.
+func (r *Repo) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.wal == nil {
+		return nil
+	}
+	err := r.wal.Close()
+	r.wal = nil
+	return err
+}
+
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	// CreateTemp crea con 0600; mismos permisos que el WAL
+	if err := tmp.Chmod(0o644); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+
+	// el rename es durable recien cuando se sincroniza el directorio
+	dir, err := os.Open(filepath.Dir(path))
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+	return dir.Sync()
+}
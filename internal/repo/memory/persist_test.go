@@ -0,0 +1,113 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Franconl/ffaas/internal/core"
+	"github.com/Franconl/ffaas/internal/repo"
+)
+
+func reopen(t *testing.T, r *Repo, dir string) *Repo {
+	t.Helper()
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+	r, err := Open(dir)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	return r
+}
+
+func TestPersistenceRestoresState(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	r, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a := core.FeatureFlag{Key: "a", Percentage: 10}
+	b := core.FeatureFlag{Key: "b"}
+	if err := r.Create(ctx, &a); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Create(ctx, &b); err != nil {
+		t.Fatal(err)
+	}
+	a.Key = "a_renamed"
+	if err := r.Update(ctx, &a); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.AddUsage(ctx, []core.FlagUsage{{FlagID: a.ID, Evaluations: 3, LastEvaluatedAt: time.Now()}}); err != nil {
+		t.Fatal(err)
+	}
+
+	// solo log, sin snapshot
+	r = reopen(t, r, dir)
+	if f, err := r.GetByKey(ctx, "a_renamed"); err != nil || f.Percentage != 10 {
+		t.Fatalf("expected renamed flag after replay, got %+v (%v)", f, err)
+	}
+	if _, err := r.GetByKey(ctx, "a"); !errors.Is(err, repo.ErrNotFound) {
+		t.Errorf("expected old key to be gone, got %v", err)
+	}
+
+	if err := r.Snapshot(); err != nil {
+		t.Fatal(err)
+	}
+	if info, _ := os.Stat(filepath.Join(dir, walFile)); info.Size() != 0 {
+		t.Errorf("expected empty wal after snapshot, got %d bytes", info.Size())
+	}
+	if err := r.DeleteByID(ctx, b.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	// snapshot + log
+	r = reopen(t, r, dir)
+	defer r.Close()
+	if _, err := r.GetByID(ctx, b.ID); !errors.Is(err, repo.ErrNotFound) {
+		t.Errorf("expected deleted flag to stay deleted, got %v", err)
+	}
+	usage, _ := r.ListUsage(ctx)
+	if len(usage) != 1 || usage[0].Evaluations != 3 {
+		t.Errorf("expected usage to survive restart, got %+v", usage)
+	}
+}
+
+func TestReplayDropsTornRecord(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	r, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Create(ctx, &core.FeatureFlag{Key: "a"}); err != nil {
+		t.Fatal(err)
+	}
+	r.Close()
+
+	// el proceso murio a mitad de una escritura
+	wal, _ := os.OpenFile(filepath.Join(dir, walFile), os.O_APPEND|os.O_WRONLY, 0o644)
+	wal.WriteString(`{"puts":[{"id":"x","ke`)
+	wal.Close()
+
+	r, err = Open(dir)
+	if err != nil {
+		t.Fatalf("expected torn tail to be ignored, got %v", err)
+	}
+	if err := r.Create(ctx, &core.FeatureFlag{Key: "b"}); err != nil {
+		t.Fatal(err)
+	}
+	r = reopen(t, r, dir)
+	defer r.Close()
+	if list, _ := r.List(ctx); len(list) != 2 {
+		t.Errorf("expected 2 flags, got %+v", list)
+	}
+}
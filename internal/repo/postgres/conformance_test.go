@@ -0,0 +1,56 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+
+	"github.com/Franconl/ffaas/internal/repo"
+	"github.com/Franconl/ffaas/internal/repo/repotest"
+)
+
+// testDB abre la base de FFAAS_TEST_POSTGRES_DSN y la migra. Los tests vacian
+// las tablas de flags: usar una base descartable, nunca una con datos reales.
+func testDB(t *testing.T) *sql.DB {
+	t.Helper()
+	dsn := os.Getenv("FFAAS_TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("FFAAS_TEST_POSTGRES_DSN no definido")
+	}
+	db, err := sql.Open("pgx", dsn)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if _, err := Migrate(context.Background(), db); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	return db
+}
+
+func truncate(t *testing.T, db *sql.DB) {
+	t.Helper()
+	const q = `TRUNCATE feature_flags, flag_usage, flag_tombstones, flag_outbox`
+	if _, err := db.ExecContext(context.Background(), q); err != nil {
+		t.Fatalf("truncate: %v", err)
+	}
+}
+
+func TestConformance(t *testing.T) {
+	db := testDB(t)
+	repotest.Run(t, func(t *testing.T) repo.Flags {
+		truncate(t, db)
+		return New(db)
+	})
+}
+
+func TestConformanceWithOutbox(t *testing.T) {
+	db := testDB(t)
+	repotest.Run(t, func(t *testing.T) repo.Flags {
+		truncate(t, db)
+		r := New(db)
+		r.EnableOutbox()
+		return r
+	})
+}
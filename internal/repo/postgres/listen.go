@@ -0,0 +1,183 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+)
+
+// ChangesChannel es el canal de NOTIFY del trigger de feature_flags (migracion 0002)
+const ChangesChannel = "ff_changes"
+
+const (
+	// catchUpOverlap: updated_at es la hora de inicio de la transaccion, asi que
+	// una transaccion larga puede commitear con un updated_at anterior al ultimo
+	// cambio visto. Ponerse al dia desde un poco antes la cubre; repetir un
+	// cambio no molesta (los consumidores invalidan).
+	catchUpOverlap = 30 * time.Second
+
+	// tombstoneRetention es cuanto se guardan los deletes/renames para el catch-up
+	tombstoneRetention = 7 * 24 * time.Hour
+)
+
+// FlagChange es un cambio en feature_flags. Op es insert, update o delete;
+// en un update OldKey es la key anterior (igual a Key si no cambio).
+type FlagChange struct {
+	Op     string    `json:"op"`
+	ID     string    `json:"id"`
+	Key    string    `json:"key"`
+	OldKey string    `json:"old_key,omitempty"`
+	At     time.Time `json:"at"`
+}
+
+// ChangeID identifica el cambio: es el mismo en todas las instancias que lo
+// reciben y entre la notificacion y el catch-up (At es el updated_at de la fila
+// o la hora del delete)
+func (c FlagChange) ChangeID() string {
+	return c.ID + "@" + c.At.UTC().Format(time.RFC3339Nano)
+}
+
+// Keys devuelve las keys afectadas (la nueva y, si cambio, la vieja)
+func (c FlagChange) Keys() []string {
+	if c.OldKey != "" && c.OldKey != c.Key {
+		return []string{c.Key, c.OldKey}
+	}
+	return []string{c.Key}
+}
+
+// Listener escucha el change feed de Postgres (LISTEN ff_changes) y pasa cada
+// cambio a los handlers registrados. Si la conexion se cae reconecta solo y,
+// antes de seguir escuchando, se pone al dia con lo que cambio mientras tanto
+// (por updated_at y por la tabla de tombstones).
+type Listener struct {
+	db       *sql.DB
+	handlers []func(FlagChange)
+
+	// since es hasta donde se vieron cambios (hora de la base)
+	since time.Time
+}
+
+func NewListener(db *sql.DB) *Listener {
+	return &Listener{db: db}
+}
+
+// OnChange registra un handler. Registrar antes de Run: no es thread-safe.
+func (l *Listener) OnChange(fn func(FlagChange)) {
+	l.handlers = append(l.handlers, fn)
+}
+
+func (l *Listener) emit(c FlagChange) {
+	if c.At.After(l.since) {
+		l.since = c.At
+	}
+	for _, fn := range l.handlers {
+		fn(c)
+	}
+}
+
+// Run escucha hasta que se cancela ctx
+func (l *Listener) Run(ctx context.Context) {
+	backoff := 100 * time.Millisecond
+
+	for {
+		err := l.listen(ctx, func() { backoff = 100 * time.Millisecond })
+		if ctx.Err() != nil {
+			return
+		}
+		log.Println("⚠️ Change feed de Postgres caido, reintentando:", err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, 5*time.Second)
+	}
+}
+
+// listen toma una conexion del pool, hace LISTEN, se pone al dia y queda
+// esperando notificaciones. connected se llama cuando ya esta escuchando.
+func (l *Listener) listen(ctx context.Context, connected func()) error {
+	conn, err := l.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	return conn.Raw(func(driverConn any) error {
+		pc := driverConn.(*stdlib.Conn).Conn()
+		// la conexion vuelve al pool: que no siga escuchando
+		defer func() {
+			if !pc.IsClosed() {
+				_, _ = pc.Exec(context.Background(), "UNLISTEN *")
+			}
+		}()
+
+		// primero LISTEN y despues el catch-up: lo que cambie en el medio llega
+		// por los dos lados, pero no se pierde
+		if _, err := pc.Exec(ctx, "LISTEN "+ChangesChannel); err != nil {
+			return err
+		}
+		if err := l.catchUp(ctx, pc); err != nil {
+			return err
+		}
+		log.Println("📡 Escuchando cambios de flags en Postgres")
+		connected()
+
+		for {
+			n, err := pc.WaitForNotification(ctx)
+			if err != nil {
+				return err
+			}
+			var c FlagChange
+			if err := json.Unmarshal([]byte(n.Payload), &c); err != nil {
+				log.Println("⚠️ Notificacion de cambio invalida:", err)
+				continue
+			}
+			l.emit(c)
+		}
+	})
+}
+
+// catchUp emite los cambios desde since. La primera vez no hay nada que
+// recuperar: solo se toma la hora de la base como punto de partida.
+func (l *Listener) catchUp(ctx context.Context, conn *pgx.Conn) error {
+	if l.since.IsZero() {
+		return conn.QueryRow(ctx, `SELECT NOW()`).Scan(&l.since)
+	}
+
+	const q = `
+		SELECT 'update', id::text, key, updated_at FROM feature_flags WHERE updated_at > $1
+		UNION ALL
+		SELECT 'delete', id::text, key, removed_at FROM flag_tombstones WHERE removed_at > $1
+		ORDER BY 4, 1`
+	rows, err := conn.Query(ctx, q, l.since.Add(-catchUpOverlap))
+	if err != nil {
+		return err
+	}
+	changes, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (FlagChange, error) {
+		var c FlagChange
+		err := row.Scan(&c.Op, &c.ID, &c.Key, &c.At)
+		return c, err
+	})
+	if err != nil {
+		return err
+	}
+	if len(changes) > 0 {
+		log.Printf("📡 Change feed: %d cambios recuperados despues de reconectar", len(changes))
+	}
+	for _, c := range changes {
+		l.emit(c)
+	}
+
+	_, err = conn.Exec(ctx, `DELETE FROM flag_tombstones WHERE removed_at < NOW() - $1 * INTERVAL '1 second'`,
+		int64(tombstoneRetention.Seconds()))
+	if err != nil && ctx.Err() == nil {
+		log.Println("⚠️ Error limpiando tombstones:", err)
+	}
+	return nil
+}
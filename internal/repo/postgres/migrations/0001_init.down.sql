@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS flag_usage;
+DROP TABLE IF EXISTS feature_flags;
@@ -0,0 +1,36 @@
+-- Tabla base de flags. IF NOT EXISTS: instalaciones que crearon la tabla a mano
+-- antes de que existieran las migraciones la adoptan sin perder datos.
+CREATE TABLE IF NOT EXISTS feature_flags (
+    id          UUID        PRIMARY KEY,
+    key         TEXT        NOT NULL UNIQUE,
+    description TEXT        NOT NULL DEFAULT '',
+    enabled     BOOLEAN     NOT NULL DEFAULT FALSE,
+    percentage  INTEGER     NOT NULL DEFAULT 0 CHECK (percentage BETWEEN 0 AND 100),
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- metadata (owner, tags, kind, temporary, expires_at)
+ALTER TABLE feature_flags ADD COLUMN IF NOT EXISTS owner      TEXT        NOT NULL DEFAULT '';
+ALTER TABLE feature_flags ADD COLUMN IF NOT EXISTS tags       TEXT[]      NOT NULL DEFAULT '{}';
+ALTER TABLE feature_flags ADD COLUMN IF NOT EXISTS kind       TEXT        NOT NULL DEFAULT 'release';
+ALTER TABLE feature_flags ADD COLUMN IF NOT EXISTS temporary  BOOLEAN     NOT NULL DEFAULT FALSE;
+ALTER TABLE feature_flags ADD COLUMN IF NOT EXISTS expires_at TIMESTAMPTZ;
+
+-- archivado (soft delete)
+ALTER TABLE feature_flags ADD COLUMN IF NOT EXISTS archived_at TIMESTAMPTZ;
+
+-- el orden por key es byte a byte, igual que en memory y sqlite (con la collation
+-- del cluster "Zeta" podria quedar antes que "alpha" y el paginado no coincidiria)
+ALTER TABLE feature_flags ALTER COLUMN key TYPE TEXT COLLATE "C";
+
+-- orden y paginado por (columna, key)
+CREATE INDEX IF NOT EXISTS feature_flags_created_at_idx ON feature_flags (created_at, key);
+CREATE INDEX IF NOT EXISTS feature_flags_updated_at_idx ON feature_flags (updated_at, key);
+CREATE INDEX IF NOT EXISTS feature_flags_tags_idx ON feature_flags USING GIN (tags);
+
+CREATE TABLE IF NOT EXISTS flag_usage (
+    flag_id           UUID        PRIMARY KEY REFERENCES feature_flags (id) ON DELETE CASCADE,
+    evaluations       BIGINT      NOT NULL DEFAULT 0,
+    last_evaluated_at TIMESTAMPTZ
+);
@@ -0,0 +1,3 @@
+DROP TRIGGER IF EXISTS feature_flags_notify ON feature_flags;
+DROP FUNCTION IF EXISTS ffaas_notify_flag_change();
+DROP TABLE IF EXISTS flag_tombstones;
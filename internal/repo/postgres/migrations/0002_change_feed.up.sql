@@ -0,0 +1,38 @@
+-- Change feed: cada cambio en feature_flags se avisa por NOTIFY en el canal
+-- ff_changes, haga quien haga la escritura (esta app, otra instancia, psql).
+-- El payload lleva solo id y keys: la flag se vuelve a leer si hace falta.
+
+-- Keys que dejaron de existir (delete o rename). updated_at alcanza para
+-- ponerse al dia con los cambios perdidos durante una desconexion, pero un
+-- delete no deja fila; esta tabla si.
+CREATE TABLE IF NOT EXISTS flag_tombstones (
+    id         UUID        NOT NULL,
+    key        TEXT        NOT NULL,
+    removed_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS flag_tombstones_removed_at_idx ON flag_tombstones (removed_at);
+
+CREATE OR REPLACE FUNCTION ffaas_notify_flag_change() RETURNS trigger AS $$
+DECLARE
+    payload JSON;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        INSERT INTO flag_tombstones (id, key) VALUES (OLD.id, OLD.key);
+        payload := json_build_object('op', 'delete', 'id', OLD.id, 'key', OLD.key, 'at', NOW());
+    ELSIF TG_OP = 'UPDATE' THEN
+        IF OLD.key <> NEW.key THEN
+            INSERT INTO flag_tombstones (id, key) VALUES (OLD.id, OLD.key);
+        END IF;
+        payload := json_build_object('op', 'update', 'id', NEW.id, 'key', NEW.key, 'old_key', OLD.key, 'at', NEW.updated_at);
+    ELSE
+        payload := json_build_object('op', 'insert', 'id', NEW.id, 'key', NEW.key, 'at', NEW.updated_at);
+    END IF;
+    PERFORM pg_notify('ff_changes', payload::text);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS feature_flags_notify ON feature_flags;
+CREATE TRIGGER feature_flags_notify
+    AFTER INSERT OR UPDATE OR DELETE ON feature_flags
+    FOR EACH ROW EXECUTE FUNCTION ffaas_notify_flag_change();
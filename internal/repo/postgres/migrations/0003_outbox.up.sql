@@ -0,0 +1,18 @@
+-- Outbox de eventos de cambio: postgres.Repo escribe el evento en la misma
+-- transaccion que el cambio y el relay lo publica despues (at-least-once).
+CREATE TABLE IF NOT EXISTS flag_outbox (
+    id           BIGSERIAL   PRIMARY KEY,
+    event_type   TEXT        NOT NULL,
+    flag_id      UUID        NOT NULL,
+    flag_key     TEXT        NOT NULL,
+    -- estado de la flag despues del cambio (NULL en flag.deleted)
+    flag         JSONB,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    published_at TIMESTAMPTZ,
+    attempts     INTEGER     NOT NULL DEFAULT 0,
+    last_error   TEXT
+);
+
+-- pendientes en orden y publicados viejos para la limpieza
+CREATE INDEX IF NOT EXISTS flag_outbox_pending_idx ON flag_outbox (id) WHERE published_at IS NULL;
+CREATE INDEX IF NOT EXISTS flag_outbox_published_at_idx ON flag_outbox (published_at) WHERE published_at IS NOT NULL;
@@ -0,0 +1,8 @@
+-- Ultima vez que cambio enabled o percentage. El reporte de flags "stuck" usa
+-- esta fecha y no updated_at, que se mueve con cualquier edicion (descripcion,
+-- tags...). Las flags existentes arrancan con su updated_at: es lo mas cercano
+-- que hay y nunca es anterior al cambio real.
+ALTER TABLE feature_flags ADD COLUMN IF NOT EXISTS rollout_changed_at TIMESTAMPTZ;
+UPDATE feature_flags SET rollout_changed_at = updated_at WHERE rollout_changed_at IS NULL;
+ALTER TABLE feature_flags ALTER COLUMN rollout_changed_at SET DEFAULT NOW();
+ALTER TABLE feature_flags ALTER COLUMN rollout_changed_at SET NOT NULL;
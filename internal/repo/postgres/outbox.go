@@ -0,0 +1,220 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/Franconl/ffaas/internal/core"
+	"github.com/Franconl/ffaas/internal/metrics"
+	"github.com/Franconl/ffaas/internal/outbox"
+)
+
+// outboxLockID es el advisory lock del relay: un solo relay publica a la vez
+// (entre todas las replicas), asi los eventos salen en el orden del outbox
+const outboxLockID int64 = 0x66666161735f6f62
+
+// EnableOutbox hace que cada escritura deje su evento en flag_outbox dentro de
+// la misma transaccion (migracion 0003). Sin un Relay corriendo los eventos se
+// acumulan: habilitarlo solo junto con uno. Llamar antes de empezar a servir.
+func (r *Repo) EnableOutbox() {
+	r.outbox = true
+}
+
+// write corre fn en una transaccion si hay outbox (el cambio y su evento se
+// confirman juntos); sin outbox alcanza con la conexion del pool
+func (r *Repo) write(ctx context.Context, fn func(q querier) error) error {
+	if !r.outbox {
+		return fn(r.db)
+	}
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// record agrega el evento al outbox con el estado de la flag leido en la misma
+// transaccion. En flag.deleted la flag ya no existe: se usa la key recibida.
+func (r *Repo) record(ctx context.Context, db querier, eventType, id, key string) error {
+	if !r.outbox {
+		return nil
+	}
+
+	var flag []byte
+	if eventType != outbox.FlagDeleted {
+		f, err := getByID(ctx, db, id)
+		if err != nil {
+			return err
+		}
+		if flag, err = json.Marshal(f); err != nil {
+			return err
+		}
+		key = f.Key
+	}
+
+	const q = `
+		INSERT INTO flag_outbox (event_type, flag_id, flag_key, flag)
+		VALUES ($1, $2, $3, $4)`
+	_, err := db.ExecContext(ctx, q, eventType, id, key, flag)
+	return err
+}
+
+// Relay publica los eventos pendientes de flag_outbox en un Sink. Un evento se
+// marca como publicado recien despues de que el sink confirma el lote: si el
+// proceso se cae en el medio, el lote se vuelve a mandar (at-least-once).
+type Relay struct {
+	db   *sql.DB
+	sink outbox.Sink
+
+	// BatchSize es cuantos eventos se mandan por lote
+	BatchSize int
+	// Retention es cuanto se guardan los eventos ya publicados
+	Retention time.Duration
+}
+
+func NewRelay(db *sql.DB, sink outbox.Sink) *Relay {
+	return &Relay{db: db, sink: sink, BatchSize: 100, Retention: 24 * time.Hour}
+}
+
+// Run publica cada interval (sin esperar mientras haya lotes llenos) hasta que
+// se cancela ctx. Si el sink falla, reintenta con backoff hasta un minuto.
+func (rl *Relay) Run(ctx context.Context, interval time.Duration) {
+	wait := interval
+	var lastCleanup time.Time
+
+	for {
+		n, err := rl.publishBatch(ctx)
+		switch {
+		case err != nil:
+			if ctx.Err() != nil {
+				return
+			}
+			log.Println("⚠️ Error publicando eventos del outbox, reintentando:", err)
+			wait = min(wait*2, time.Minute)
+		case n == rl.BatchSize:
+			// hay mas pendientes
+			wait = interval
+			continue
+		default:
+			wait = interval
+			if time.Since(lastCleanup) > 10*time.Minute {
+				rl.cleanup(ctx)
+				lastCleanup = time.Now()
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+	}
+}
+
+// publishBatch manda el proximo lote pendiente y devuelve cuantos eventos
+// publico. Si otro relay tiene el lock, no hace nada.
+func (rl *Relay) publishBatch(ctx context.Context) (int, error) {
+	tx, err := rl.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var locked bool
+	if err := tx.QueryRowContext(ctx, `SELECT pg_try_advisory_xact_lock($1)`, outboxLockID).Scan(&locked); err != nil {
+		return 0, err
+	}
+	if !locked {
+		return 0, nil
+	}
+
+	events, err := pendingEvents(ctx, tx, rl.BatchSize)
+	if err != nil || len(events) == 0 {
+		return 0, err
+	}
+	ids := make([]int64, len(events))
+	for i, e := range events {
+		ids[i] = e.ID
+	}
+
+	if err := rl.sink.Publish(ctx, events); err != nil {
+		metrics.OutboxEvents.WithLabelValues("failed").Add(float64(len(events)))
+		tx.Rollback()
+		rl.recordFailure(ctx, ids, err)
+		return 0, err
+	}
+
+	const q = `UPDATE flag_outbox SET published_at = NOW(), attempts = attempts + 1 WHERE id = ANY($1)`
+	if _, err := tx.ExecContext(ctx, q, ids); err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	metrics.OutboxEvents.WithLabelValues("published").Add(float64(len(events)))
+	return len(events), nil
+}
+
+func pendingEvents(ctx context.Context, tx *sql.Tx, limit int) ([]outbox.Event, error) {
+	const q = `
+		SELECT id, event_type, flag_id, flag_key, flag, created_at
+		  FROM flag_outbox
+		 WHERE published_at IS NULL
+		 ORDER BY id
+		 LIMIT $1`
+	rows, err := tx.QueryContext(ctx, q, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []outbox.Event
+	for rows.Next() {
+		var e outbox.Event
+		var flag []byte
+		if err := rows.Scan(&e.ID, &e.Type, &e.FlagID, &e.FlagKey, &flag, &e.OccurredAt); err != nil {
+			return nil, err
+		}
+		if flag != nil {
+			e.Flag = &core.FeatureFlag{}
+			if err := json.Unmarshal(flag, e.Flag); err != nil {
+				return nil, err
+			}
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+// recordFailure deja el intento y el error en las filas, para diagnosticar
+// desde la base que esta trabado
+func (rl *Relay) recordFailure(ctx context.Context, ids []int64, cause error) {
+	const q = `UPDATE flag_outbox SET attempts = attempts + 1, last_error = $2 WHERE id = ANY($1)`
+	if _, err := rl.db.ExecContext(ctx, q, ids, cause.Error()); err != nil && !errors.Is(err, context.Canceled) {
+		log.Println("⚠️ Error registrando la falla del outbox:", err)
+	}
+}
+
+// cleanup borra los eventos publicados hace mas de Retention
+func (rl *Relay) cleanup(ctx context.Context) {
+	const q = `DELETE FROM flag_outbox WHERE published_at < NOW() - $1 * INTERVAL '1 second'`
+	res, err := rl.db.ExecContext(ctx, q, int64(rl.Retention.Seconds()))
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Println("⚠️ Error limpiando el outbox:", err)
+		}
+		return
+	}
+	if n, _ := res.RowsAffected(); n > 0 {
+		log.Printf("🧹 Outbox: %d eventos publicados borrados", n)
+	}
+}
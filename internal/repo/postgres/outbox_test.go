@@ -0,0 +1,192 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Franconl/ffaas/internal/core"
+	"github.com/Franconl/ffaas/internal/outbox"
+	"github.com/google/uuid"
+)
+
+// recordingSink guarda lo publicado; si err no es nil falla sin guardar nada
+type recordingSink struct {
+	err    error
+	calls  int
+	events []outbox.Event
+}
+
+func (s *recordingSink) Publish(ctx context.Context, events []outbox.Event) error {
+	s.calls++
+	if s.err != nil {
+		return s.err
+	}
+	s.events = append(s.events, events...)
+	return nil
+}
+
+type outboxRow struct {
+	published bool
+	attempts  int
+	lastError sql.NullString
+}
+
+func outboxRows(t *testing.T, db *sql.DB) []outboxRow {
+	t.Helper()
+	rows, err := db.Query(`SELECT published_at IS NOT NULL, attempts, last_error FROM flag_outbox ORDER BY id`)
+	if err != nil {
+		t.Fatalf("query outbox: %v", err)
+	}
+	defer rows.Close()
+	var out []outboxRow
+	for rows.Next() {
+		var r outboxRow
+		if err := rows.Scan(&r.published, &r.attempts, &r.lastError); err != nil {
+			t.Fatalf("scan outbox: %v", err)
+		}
+		out = append(out, r)
+	}
+	return out
+}
+
+// outboxRepo arma un repo con outbox y deja dos eventos pendientes (created y updated)
+func outboxRepo(t *testing.T, db *sql.DB) {
+	t.Helper()
+	truncate(t, db)
+	r := New(db)
+	r.EnableOutbox()
+
+	ctx := context.Background()
+	f := core.FeatureFlag{Key: "new_checkout"}
+	if err := r.Create(ctx, &f); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	f.Enabled = true
+	if err := r.Update(ctx, &f); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+}
+
+func TestRelayMarksPublishedOnlyAfterSink(t *testing.T) {
+	db := testDB(t)
+	outboxRepo(t, db)
+	ctx := context.Background()
+
+	sink := &recordingSink{err: errors.New("webhook down")}
+	rl := NewRelay(db, sink)
+
+	// el sink falla: los eventos quedan pendientes con el intento y el error
+	if n, err := rl.publishBatch(ctx); err == nil || n != 0 {
+		t.Fatalf("publishBatch with failing sink = %d, %v", n, err)
+	}
+	rows := outboxRows(t, db)
+	if len(rows) != 2 {
+		t.Fatalf("outbox rows = %d, want 2", len(rows))
+	}
+	for _, r := range rows {
+		if r.published || r.attempts != 1 || r.lastError.String != "webhook down" {
+			t.Errorf("row after failure = %+v", r)
+		}
+	}
+
+	// el sink vuelve: se mandan los mismos eventos, en orden, y recien ahi se marcan
+	sink.err = nil
+	n, err := rl.publishBatch(ctx)
+	if err != nil || n != 2 {
+		t.Fatalf("publishBatch = %d, %v", n, err)
+	}
+	if len(sink.events) != 2 || sink.events[0].Type != outbox.FlagCreated || sink.events[1].Type != outbox.FlagUpdated {
+		t.Fatalf("published events = %+v", sink.events)
+	}
+	if f := sink.events[1].Flag; f == nil || !f.Enabled || sink.events[1].FlagKey != "new_checkout" {
+		t.Errorf("updated event carries %+v", f)
+	}
+	for _, r := range outboxRows(t, db) {
+		if !r.published || r.attempts != 2 {
+			t.Errorf("row after publish = %+v", r)
+		}
+	}
+
+	// no queda nada pendiente
+	calls := sink.calls
+	if n, err := rl.publishBatch(ctx); err != nil || n != 0 || sink.calls != calls {
+		t.Errorf("second publishBatch = %d, %v (sink calls %d -> %d)", n, err, calls, sink.calls)
+	}
+}
+
+func TestRelaySkipsWhileLocked(t *testing.T) {
+	db := testDB(t)
+	outboxRepo(t, db)
+	ctx := context.Background()
+
+	// otro relay tiene el lock
+	other, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer other.Rollback()
+	var locked bool
+	if err := other.QueryRowContext(ctx, `SELECT pg_try_advisory_xact_lock($1)`, outboxLockID).Scan(&locked); err != nil || !locked {
+		t.Fatalf("take lock: %v %v", locked, err)
+	}
+
+	sink := &recordingSink{}
+	rl := NewRelay(db, sink)
+	if n, err := rl.publishBatch(ctx); err != nil || n != 0 || sink.calls != 0 {
+		t.Fatalf("publishBatch while locked = %d, %v (sink calls %d)", n, err, sink.calls)
+	}
+	for _, r := range outboxRows(t, db) {
+		if r.published || r.attempts != 0 {
+			t.Errorf("row touched while locked: %+v", r)
+		}
+	}
+
+	other.Rollback()
+	if n, err := rl.publishBatch(ctx); err != nil || n != 2 {
+		t.Errorf("publishBatch after unlock = %d, %v", n, err)
+	}
+}
+
+func TestRelayCleanupRespectsRetention(t *testing.T) {
+	db := testDB(t)
+	truncate(t, db)
+	ctx := context.Background()
+
+	const insert = `
+		INSERT INTO flag_outbox (event_type, flag_id, flag_key, published_at)
+		VALUES ($1, $2, $3, NOW() - $4 * INTERVAL '1 second')`
+	for _, e := range []struct {
+		key string
+		age time.Duration
+	}{{"old", 2 * time.Hour}, {"recent", 10 * time.Minute}} {
+		if _, err := db.ExecContext(ctx, insert, outbox.FlagUpdated, uuid.NewString(), e.key, int64(e.age.Seconds())); err != nil {
+			t.Fatalf("insert: %v", err)
+		}
+	}
+	const pending = `INSERT INTO flag_outbox (event_type, flag_id, flag_key) VALUES ($1, $2, 'pending')`
+	if _, err := db.ExecContext(ctx, pending, outbox.FlagCreated, uuid.NewString()); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+
+	rl := NewRelay(db, &recordingSink{})
+	rl.Retention = time.Hour
+	rl.cleanup(ctx)
+
+	rows, err := db.QueryContext(ctx, `SELECT flag_key FROM flag_outbox ORDER BY id`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var keys []string
+	for rows.Next() {
+		var k string
+		rows.Scan(&k)
+		keys = append(keys, k)
+	}
+	if len(keys) != 2 || keys[0] != "recent" || keys[1] != "pending" {
+		t.Errorf("outbox after cleanup = %v, want [recent pending]", keys)
+	}
+}
@@ -3,39 +3,140 @@ package postgres
 import (
 	"context"
 	"database/sql"
+	"embed"
 	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
 	"time"
 
 	"github.com/Franconl/ffaas/internal/core"
+	"github.com/Franconl/ffaas/internal/migrate"
+	"github.com/Franconl/ffaas/internal/outbox"
+	"github.com/Franconl/ffaas/internal/repo"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 var (
-	ErrNotFound       = errors.New("not found")
-	ErrKeyAlreadyUsed = errors.New("flag key already exists")
-	ErrKeyRequired    = errors.New("key is required")
-	ErrInvalidPercent = errors.New("invalid percentage")
+	ErrNotFound       = repo.ErrNotFound
+	ErrKeyAlreadyUsed = repo.ErrKeyAlreadyUsed
+	ErrKeyRequired    = repo.ErrKeyRequired
+	ErrInvalidPercent = repo.ErrInvalidPercent
+	ErrInvalidKind    = repo.ErrInvalidKind
+	ErrArchived       = repo.ErrArchived
+	ErrNotArchived    = repo.ErrNotArchived
+	ErrRetention      = repo.ErrRetention
 )
 
 type Repo struct {
 	db *sql.DB
+	// outbox: cada escritura deja su evento en flag_outbox (ver outbox.go)
+	outbox bool
 }
 
 func New(db *sql.DB) *Repo { return &Repo{db: db} }
 
+// --- migraciones ---
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// migrationLockID identifica el advisory lock de las migraciones (cualquier
+// int64 fijo sirve, mientras nadie mas lo use en la misma base)
+const migrationLockID int64 = 0x66666161735f6d67
+
+// Migrator devuelve el migrador con las migraciones embebidas. Toma un advisory
+// lock de sesion: si varias replicas arrancan a la vez, una migra y el resto
+// espera y despues no encuentra nada pendiente.
+func Migrator(db *sql.DB) (migrate.Migrator, error) {
+	sub, err := fs.Sub(migrationFiles, "migrations")
+	if err != nil {
+		return migrate.Migrator{}, err
+	}
+	migrations, err := migrate.Load(sub)
+	if err != nil {
+		return migrate.Migrator{}, err
+	}
+	return migrate.Migrator{DB: db, Migrations: migrations, Lock: advisoryLock}, nil
+}
+
+// Migrate aplica las migraciones embebidas que falten
+func Migrate(ctx context.Context, db *sql.DB) ([]int, error) {
+	m, err := Migrator(db)
+	if err != nil {
+		return nil, err
+	}
+	return m.Up(ctx)
+}
+
+func advisoryLock(ctx context.Context, conn *sql.Conn) (func() error, error) {
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, migrationLockID); err != nil {
+		return nil, err
+	}
+	return func() error {
+		// con un contexto propio: el de la migracion puede estar cancelado
+		_, err := conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, migrationLockID)
+		return err
+	}, nil
+}
+
 // --- helpers ---
 
+// validate aplica los defaults y la validacion compartida del modelo
 func validate(f *core.FeatureFlag) error {
-	if f.Key == "" {
-		return ErrKeyRequired
+	f.Normalize()
+	return f.Validate()
+}
+
+// tagsArg evita mandar NULL a la columna tags (NOT NULL DEFAULT '{}')
+func tagsArg(tags []string) []string {
+	if tags == nil {
+		return []string{}
 	}
-	if f.Percentage < 0 || f.Percentage > 100 {
-		return ErrInvalidPercent
+	return tags
+}
+
+// columnas y scan compartidos por todos los SELECT de flags
+const selectFlag = `
+		SELECT id, key, description, enabled, percentage,
+		       owner, tags, kind, temporary, expires_at, archived_at, created_at, updated_at,
+		       rollout_changed_at
+		  FROM feature_flags`
+
+// querier lo cumplen *sql.DB y *sql.Tx, asi las escrituras se comparten con Apply
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+// scanFlag lee una fila de selectFlag. tags es text[], que database/sql no sabe
+// escanear, por eso se usa el type map de pgx (no es thread-safe: uno por query).
+func scanFlag(row scanner, m *pgtype.Map) (core.FeatureFlag, error) {
+	var ff core.FeatureFlag
+	var kind string
+	err := row.Scan(
+		&ff.ID, &ff.Key, &ff.Description, &ff.Enabled, &ff.Percentage,
+		&ff.Owner, m.SQLScanner(&ff.Tags), &kind, &ff.Temporary, &ff.ExpiresAt, &ff.ArchivedAt,
+		&ff.CreatedAt, &ff.UpdatedAt, &ff.RolloutChangedAt,
+	)
+	ff.Kind = core.FlagKind(kind)
+	if len(ff.Tags) == 0 {
+		ff.Tags = nil
 	}
-	return nil
+	return ff, err
 }
 
+// validID: la columna id es UUID, asi que un id mal formado no puede existir.
+// Sin este chequeo postgres lo rechaza con un error de sintaxis en vez de
+// devolver ErrNotFound como el resto de los backends.
+func validID(id string) bool { return uuid.Validate(id) == nil }
+
 func isUniqueViolation(err error) bool {
 	var pgErr *pgconn.PgError
 	if errors.As(err, &pgErr) {
@@ -47,20 +148,32 @@ func isUniqueViolation(err error) bool {
 // --- CRUD ---
 
 func (r *Repo) Create(ctx context.Context, f *core.FeatureFlag) error {
+	return r.write(ctx, func(q querier) error {
+		if err := create(ctx, q, f); err != nil {
+			return err
+		}
+		return r.record(ctx, q, outbox.FlagCreated, f.ID, f.Key)
+	})
+}
+
+func create(ctx context.Context, db querier, f *core.FeatureFlag) error {
 	if err := validate(f); err != nil {
 		return err
 	}
 	if f.ID == "" {
 		f.ID = uuid.NewString()
 	}
-	now := time.Now().UTC()
+	// timestamptz guarda microsegundos: lo que se devuelve es lo que queda guardado
+	now := time.Now().UTC().Truncate(time.Microsecond)
 
 	const q = `
 		INSERT INTO feature_flags
-			(id, key, description, enabled, percentage, created_at, updated_at)
-		VALUES ($1,$2,$3,$4,$5,$6,$7)`
+			(id, key, description, enabled, percentage,
+			 owner, tags, kind, temporary, expires_at, created_at, updated_at, rollout_changed_at)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$11,$11)`
 
-	_, err := r.db.ExecContext(ctx, q, f.ID, f.Key, f.Description, f.Enabled, f.Percentage, now, now)
+	_, err := db.ExecContext(ctx, q, f.ID, f.Key, f.Description, f.Enabled, f.Percentage,
+		f.Owner, tagsArg(f.Tags), string(f.Kind), f.Temporary, f.ExpiresAt, now)
 	if err != nil {
 		if isUniqueViolation(err) {
 			return ErrKeyAlreadyUsed
@@ -70,63 +183,194 @@ func (r *Repo) Create(ctx context.Context, f *core.FeatureFlag) error {
 
 	f.CreatedAt = now
 	f.UpdatedAt = now
+	f.RolloutChangedAt = now
 	return nil
 }
 
 func (r *Repo) Update(ctx context.Context, f *core.FeatureFlag) error {
+	return r.write(ctx, func(q querier) error {
+		if err := update(ctx, q, f, time.Time{}); err != nil {
+			return err
+		}
+		return r.record(ctx, q, outbox.FlagUpdated, f.ID, f.Key)
+	})
+}
+
+// update pisa la flag; si ifUpdatedAt no es cero, solo si la guardada sigue
+// teniendo ese updated_at (la condicion va en el UPDATE, que toma el lock de la fila)
+func update(ctx context.Context, db querier, f *core.FeatureFlag, ifUpdatedAt time.Time) error {
 	if err := validate(f); err != nil {
 		return err
 	}
-	// Asegurar existencia y obtener key anterior para feedback/consistencia si querés
-	const sel = `SELECT key FROM feature_flags WHERE id = $1`
-	var oldKey string
-	if err := r.db.QueryRowContext(ctx, sel, f.ID).Scan(&oldKey); err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return ErrNotFound
-		}
+	// Asegurar existencia (y que no este archivada) antes de actualizar
+	archivedAt, err := archivedAt(ctx, db, f.ID)
+	if err != nil {
 		return err
 	}
+	if archivedAt != nil {
+		return ErrArchived
+	}
 
+	// en el SET, enabled y percentage son los valores anteriores
 	const q = `
 		UPDATE feature_flags
 		   SET key = $1,
 		       description = $2,
 		       enabled = $3,
 		       percentage = $4,
+		       rollout_changed_at = CASE WHEN enabled <> $3 OR percentage <> $4
+		                                 THEN NOW() ELSE rollout_changed_at END,
+		       owner = $5,
+		       tags = $6,
+		       kind = $7,
+		       temporary = $8,
+		       expires_at = $9,
 		       updated_at = NOW()
-		 WHERE id = $5`
-	_, err := r.db.ExecContext(ctx, q, f.Key, f.Description, f.Enabled, f.Percentage, f.ID)
-	if err != nil {
-		if isUniqueViolation(err) {
-			return ErrKeyAlreadyUsed
-		}
-		return err
+		 WHERE id = $10
+		   AND ($11::timestamptz IS NULL OR updated_at = $11)
+		RETURNING updated_at, rollout_changed_at`
+	var cond any
+	if !ifUpdatedAt.IsZero() {
+		cond = ifUpdatedAt
 	}
-	return nil
+	err = db.QueryRowContext(ctx, q, f.Key, f.Description, f.Enabled, f.Percentage,
+		f.Owner, tagsArg(f.Tags), string(f.Kind), f.Temporary, f.ExpiresAt, f.ID, cond).Scan(&f.UpdatedAt, &f.RolloutChangedAt)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return repo.ErrStale
+	case isUniqueViolation(err):
+		return ErrKeyAlreadyUsed
+	}
+	return err
 }
 
 func (r *Repo) DeleteByID(ctx context.Context, id string) error {
-	const q = `DELETE FROM feature_flags WHERE id = $1`
-	res, err := r.db.ExecContext(ctx, q, id)
+	if !validID(id) {
+		return ErrNotFound
+	}
+	return r.write(ctx, func(db querier) error {
+		const q = `DELETE FROM feature_flags WHERE id = $1 RETURNING key`
+		var key string
+		if err := db.QueryRowContext(ctx, q, id).Scan(&key); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrNotFound
+			}
+			return err
+		}
+		return r.record(ctx, db, outbox.FlagDeleted, id, key)
+	})
+}
+
+// archivedAt devuelve el archived_at de la flag (nil si esta activa) o ErrNotFound
+func archivedAt(ctx context.Context, db querier, id string) (*time.Time, error) {
+	if !validID(id) {
+		return nil, ErrNotFound
+	}
+	const q = `SELECT archived_at FROM feature_flags WHERE id = $1`
+	var archivedAt *time.Time
+	if err := db.QueryRowContext(ctx, q, id).Scan(&archivedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return archivedAt, nil
+}
+
+func (r *Repo) Archive(ctx context.Context, id string) error {
+	return r.write(ctx, func(q querier) error {
+		if err := archive(ctx, q, id); err != nil {
+			return err
+		}
+		return r.record(ctx, q, outbox.FlagArchived, id, "")
+	})
+}
+
+func archive(ctx context.Context, db querier, id string) error {
+	if !validID(id) {
+		return ErrNotFound
+	}
+	const q = `
+		UPDATE feature_flags
+		   SET archived_at = NOW(), updated_at = NOW()
+		 WHERE id = $1 AND archived_at IS NULL`
+	res, err := db.ExecContext(ctx, q, id)
 	if err != nil {
 		return err
 	}
-	aff, _ := res.RowsAffected()
-	if aff == 0 {
-		return ErrNotFound
+	if aff, _ := res.RowsAffected(); aff == 0 {
+		// no existe o ya estaba archivada
+		if _, err := archivedAt(ctx, db, id); err != nil {
+			return err
+		}
+		return ErrArchived
 	}
 	return nil
 }
 
+func (r *Repo) Restore(ctx context.Context, id string) error {
+	if !validID(id) {
+		return ErrNotFound
+	}
+	return r.write(ctx, func(db querier) error {
+		const q = `
+			UPDATE feature_flags
+			   SET archived_at = NULL, updated_at = NOW()
+			 WHERE id = $1 AND archived_at IS NOT NULL`
+		res, err := db.ExecContext(ctx, q, id)
+		if err != nil {
+			return err
+		}
+		if aff, _ := res.RowsAffected(); aff == 0 {
+			if _, err := archivedAt(ctx, db, id); err != nil {
+				return err
+			}
+			return ErrNotArchived
+		}
+		return r.record(ctx, db, outbox.FlagRestored, id, "")
+	})
+}
+
+// Purge borra la flag en un solo DELETE condicionado, asi no hay carrera con un Restore
+func (r *Repo) Purge(ctx context.Context, id string, archivedBefore time.Time) error {
+	if !validID(id) {
+		return ErrNotFound
+	}
+	return r.write(ctx, func(db querier) error {
+		const q = `
+			DELETE FROM feature_flags
+			 WHERE id = $1 AND archived_at IS NOT NULL AND archived_at <= $2
+			RETURNING key`
+		var key string
+		err := db.QueryRowContext(ctx, q, id, archivedBefore).Scan(&key)
+		if errors.Is(err, sql.ErrNoRows) {
+			at, err := archivedAt(ctx, db, id)
+			if err != nil {
+				return err
+			}
+			if at == nil {
+				return ErrNotArchived
+			}
+			return ErrRetention
+		}
+		if err != nil {
+			return err
+		}
+		return r.record(ctx, db, outbox.FlagDeleted, id, key)
+	})
+}
+
 func (r *Repo) GetByID(ctx context.Context, id string) (*core.FeatureFlag, error) {
-	const q = `
-		SELECT id, key, description, enabled, percentage, created_at, updated_at
-		  FROM feature_flags
+	return getByID(ctx, r.db, id)
+}
+
+func getByID(ctx context.Context, db querier, id string) (*core.FeatureFlag, error) {
+	if !validID(id) {
+		return nil, ErrNotFound
+	}
+	const q = selectFlag + `
 		 WHERE id = $1`
-	var ff core.FeatureFlag
-	err := r.db.QueryRowContext(ctx, q, id).Scan(
-		&ff.ID, &ff.Key, &ff.Description, &ff.Enabled, &ff.Percentage, &ff.CreatedAt, &ff.UpdatedAt,
-	)
+	ff, err := scanFlag(db.QueryRowContext(ctx, q, id), pgtype.NewMap())
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, ErrNotFound
@@ -138,14 +382,9 @@ func (r *Repo) GetByID(ctx context.Context, id string) (*core.FeatureFlag, error
 }
 
 func (r *Repo) GetByKey(ctx context.Context, key string) (*core.FeatureFlag, error) {
-	const q = `
-		SELECT id, key, description, enabled, percentage, created_at, updated_at
-		  FROM feature_flags
+	const q = selectFlag + `
 		 WHERE key = $1`
-	var ff core.FeatureFlag
-	err := r.db.QueryRowContext(ctx, q, key).Scan(
-		&ff.ID, &ff.Key, &ff.Description, &ff.Enabled, &ff.Percentage, &ff.CreatedAt, &ff.UpdatedAt,
-	)
+	ff, err := scanFlag(r.db.QueryRowContext(ctx, q, key), pgtype.NewMap())
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, ErrNotFound
@@ -156,9 +395,8 @@ func (r *Repo) GetByKey(ctx context.Context, key string) (*core.FeatureFlag, err
 }
 
 func (r *Repo) List(ctx context.Context) ([]core.FeatureFlag, error) {
-	const q = `
-		SELECT id, key, description, enabled, percentage, created_at, updated_at
-		  FROM feature_flags
+	const q = selectFlag + `
+		 WHERE archived_at IS NULL
 		 ORDER BY created_at ASC, key ASC`
 	rows, err := r.db.QueryContext(ctx, q)
 	if err != nil {
@@ -166,15 +404,210 @@ func (r *Repo) List(ctx context.Context) ([]core.FeatureFlag, error) {
 	}
 	defer rows.Close()
 
+	return scanFlags(rows)
+}
+
+// Search arma el WHERE segun los filtros presentes en la query y pagina por
+// keyset: (columna de orden, key) mayor/menor que el cursor, trayendo Limit+1
+// filas para saber si hay otra pagina.
+func (r *Repo) Search(ctx context.Context, q repo.Query) (repo.Page, error) {
+	c, err := q.DecodeCursor()
+	if err != nil {
+		return repo.Page{}, err
+	}
+
+	var where []string
+	var args []any
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if q.Owner != "" {
+		where = append(where, "owner = "+arg(q.Owner))
+	}
+	if q.Tag != "" {
+		where = append(where, arg(q.Tag)+" = ANY(tags)")
+	}
+	if q.Kind != "" {
+		where = append(where, "kind = "+arg(string(q.Kind)))
+	}
+	if q.Temporary != nil {
+		where = append(where, "temporary = "+arg(*q.Temporary))
+	}
+	if q.Enabled != nil {
+		where = append(where, "enabled = "+arg(*q.Enabled))
+	}
+	if q.KeyPrefix != "" {
+		where = append(where, "key LIKE "+arg(escapeLike(q.KeyPrefix)+"%"))
+	}
+	if q.Search != "" {
+		where = append(where, "key ILIKE "+arg("%"+escapeLike(q.Search)+"%"))
+	}
+	if !q.UpdatedSince.IsZero() {
+		where = append(where, "updated_at >= "+arg(q.UpdatedSince))
+	}
+	if q.Archived != nil {
+		if *q.Archived {
+			where = append(where, "archived_at IS NOT NULL")
+		} else {
+			where = append(where, "archived_at IS NULL")
+		}
+	}
+
+	// q.Sort() ya viene validado: nunca se interpola input del usuario
+	col := string(q.Sort())
+	dir, cmp := "ASC", ">"
+	if q.Desc {
+		dir, cmp = "DESC", "<"
+	}
+	if c != nil {
+		if q.Sort() == repo.SortKey {
+			where = append(where, "key "+cmp+" "+arg(c.Key))
+		} else {
+			where = append(where, fmt.Sprintf("(%s, key) %s (%s, %s)", col, cmp, arg(c.Time), arg(c.Key)))
+		}
+	}
+
+	query := selectFlag
+	if len(where) > 0 {
+		query += "\n\t\t WHERE " + strings.Join(where, " AND ")
+	}
+	if q.Sort() == repo.SortKey {
+		query += fmt.Sprintf("\n\t\t ORDER BY key %s", dir)
+	} else {
+		query += fmt.Sprintf("\n\t\t ORDER BY %s %s, key %s", col, dir, dir)
+	}
+	if q.Limit > 0 {
+		query += "\n\t\t LIMIT " + arg(q.Limit+1)
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return repo.Page{}, err
+	}
+	defer rows.Close()
+
+	list, err := scanFlags(rows)
+	if err != nil {
+		return repo.Page{}, err
+	}
+
+	page := repo.Page{Items: list}
+	if q.Limit > 0 && len(list) > q.Limit {
+		page.Items = list[:q.Limit]
+		page.NextCursor = q.CursorAfter(page.Items[q.Limit-1])
+	}
+	return page, nil
+}
+
+// Apply corre todos los cambios en una transaccion: si uno falla se hace
+// rollback y la tabla queda como estaba. Cada Flag se relee dentro de la
+// transaccion, asi el caller recibe lo que quedo guardado.
+func (r *Repo) Apply(ctx context.Context, changes []repo.Change) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for i := range changes {
+		c := &changes[i]
+		switch c.Op {
+		case repo.ChangeCreate:
+			err = create(ctx, tx, &c.Flag)
+			if err == nil {
+				err = r.record(ctx, tx, outbox.FlagCreated, c.Flag.ID, c.Flag.Key)
+			}
+		case repo.ChangeUpdate:
+			err = update(ctx, tx, &c.Flag, c.IfUpdatedAt)
+			if err == nil {
+				err = r.record(ctx, tx, outbox.FlagUpdated, c.Flag.ID, c.Flag.Key)
+			}
+		case repo.ChangeArchive:
+			err = archive(ctx, tx, c.Flag.ID)
+			if err == nil {
+				err = r.record(ctx, tx, outbox.FlagArchived, c.Flag.ID, c.Flag.Key)
+			}
+		default:
+			err = fmt.Errorf("unknown change op %q", c.Op)
+		}
+		if err == nil {
+			var stored *core.FeatureFlag
+			if stored, err = getByID(ctx, tx, c.Flag.ID); err == nil {
+				c.Flag = *stored
+			}
+		}
+		if err != nil {
+			return &repo.ChangeError{Index: i, Op: c.Op, Key: c.Flag.Key, Err: err}
+		}
+	}
+
+	return tx.Commit()
+}
+
+// escapeLike escapa los comodines de LIKE para buscar el texto literal
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+func scanFlags(rows *sql.Rows) ([]core.FeatureFlag, error) {
+	m := pgtype.NewMap()
 	var out []core.FeatureFlag
 	for rows.Next() {
-		var ff core.FeatureFlag
-		if err := rows.Scan(
-			&ff.ID, &ff.Key, &ff.Description, &ff.Enabled, &ff.Percentage, &ff.CreatedAt, &ff.UpdatedAt,
-		); err != nil {
+		ff, err := scanFlag(rows, m)
+		if err != nil {
 			return nil, err
 		}
 		out = append(out, ff)
 	}
 	return out, rows.Err()
 }
+
+// --- Usage ---
+
+// AddUsage suma los contadores en flag_usage dentro de una transaccion
+func (r *Repo) AddUsage(ctx context.Context, usage []core.FlagUsage) error {
+	if len(usage) == 0 {
+		return nil
+	}
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// flags borradas entre el Record y el flush se ignoran: si no, la FK
+	// rechazaria todo el lote y el tracker lo reintentaria para siempre
+	const q = `
+		INSERT INTO flag_usage (flag_id, evaluations, last_evaluated_at)
+		SELECT id, $2, $3 FROM feature_flags WHERE id = $1
+		ON CONFLICT (flag_id) DO UPDATE
+		   SET evaluations = flag_usage.evaluations + EXCLUDED.evaluations,
+		       last_evaluated_at = GREATEST(flag_usage.last_evaluated_at, EXCLUDED.last_evaluated_at)`
+	for _, u := range usage {
+		if _, err := tx.ExecContext(ctx, q, u.FlagID, u.Evaluations, u.LastEvaluatedAt); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (r *Repo) ListUsage(ctx context.Context) ([]core.FlagUsage, error) {
+	const q = `SELECT flag_id, evaluations, last_evaluated_at FROM flag_usage`
+	rows, err := r.db.QueryContext(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []core.FlagUsage
+	for rows.Next() {
+		var u core.FlagUsage
+		if err := rows.Scan(&u.FlagID, &u.Evaluations, &u.LastEvaluatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, u)
+	}
+	return out, rows.Err()
+}
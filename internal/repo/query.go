@@ -0,0 +1,197 @@
+package repo
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Franconl/ffaas/internal/core"
+)
+
+// SortField es el campo por el que se ordena el listado admin.
+// El desempate siempre es por key (unica), asi el orden es total y estable.
+type SortField string
+
+const (
+	SortCreatedAt SortField = "created_at"
+	SortUpdatedAt SortField = "updated_at"
+	SortKey       SortField = "key"
+)
+
+// Valid indica si el campo de orden es soportado
+func (s SortField) Valid() bool {
+	switch s {
+	case SortCreatedAt, SortUpdatedAt, SortKey:
+		return true
+	}
+	return false
+}
+
+// Query filtra, ordena y pagina el listado admin de flags.
+// Los campos vacios (o nil) no filtran; Limit 0 devuelve todo.
+type Query struct {
+	Owner        string
+	Tag          string
+	Kind         core.FlagKind
+	Temporary    *bool
+	Enabled      *bool
+	KeyPrefix    string    // prefijo exacto de la key
+	Search       string    // substring de la key, sin distinguir mayusculas
+	UpdatedSince time.Time // updated_at >= UpdatedSince
+	Archived     *bool     // true: solo archivadas, false: solo activas
+
+	SortBy SortField // por defecto created_at
+	Desc   bool
+	Limit  int
+	Cursor string // next_cursor de la pagina anterior
+}
+
+// Page es una pagina del listado. NextCursor vacio indica que no hay mas.
+type Page struct {
+	Items      []core.FeatureFlag
+	NextCursor string
+}
+
+// Sort devuelve el campo de orden efectivo
+func (q Query) Sort() SortField {
+	if q.SortBy == "" {
+		return SortCreatedAt
+	}
+	return q.SortBy
+}
+
+// Matches indica si la flag cumple todos los filtros de la query (no mira el cursor)
+func (q Query) Matches(f core.FeatureFlag) bool {
+	if q.Owner != "" && f.Owner != q.Owner {
+		return false
+	}
+	if q.Tag != "" && !f.HasTag(q.Tag) {
+		return false
+	}
+	if q.Kind != "" && f.Kind != q.Kind {
+		return false
+	}
+	if q.Temporary != nil && f.Temporary != *q.Temporary {
+		return false
+	}
+	if q.Enabled != nil && f.Enabled != *q.Enabled {
+		return false
+	}
+	if q.KeyPrefix != "" && !strings.HasPrefix(f.Key, q.KeyPrefix) {
+		return false
+	}
+	if q.Search != "" && !strings.Contains(strings.ToLower(f.Key), strings.ToLower(q.Search)) {
+		return false
+	}
+	if !q.UpdatedSince.IsZero() && f.UpdatedAt.Before(q.UpdatedSince) {
+		return false
+	}
+	if q.Archived != nil && f.Archived() != *q.Archived {
+		return false
+	}
+	return true
+}
+
+// --- cursor ---
+
+// Cursor es la posicion (valor de orden + key) del ultimo item de una pagina.
+// Viaja opaco al cliente como base64 de JSON.
+type Cursor struct {
+	Sort SortField `json:"s"`
+	Desc bool      `json:"d,omitempty"`
+	Time time.Time `json:"t,omitempty"`
+	Key  string    `json:"k"`
+}
+
+// CursorAfter arma el cursor que apunta despues de f segun el orden de la query
+func (q Query) CursorAfter(f core.FeatureFlag) string {
+	c := Cursor{Sort: q.Sort(), Desc: q.Desc, Key: f.Key}
+	switch c.Sort {
+	case SortCreatedAt:
+		c.Time = f.CreatedAt
+	case SortUpdatedAt:
+		c.Time = f.UpdatedAt
+	}
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// DecodeCursor devuelve el cursor de la query (nil si no hay).
+// Un cursor generado con otro orden es invalido.
+func (q Query) DecodeCursor() (*Cursor, error) {
+	if q.Cursor == "" {
+		return nil, nil
+	}
+	b, err := base64.RawURLEncoding.DecodeString(q.Cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c Cursor
+	if err := json.Unmarshal(b, &c); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	if c.Sort != q.Sort() || c.Desc != q.Desc {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
+
+// less compara dos flags segun el orden de la query (ascendente)
+func (q Query) less(a, b core.FeatureFlag) bool {
+	switch q.Sort() {
+	case SortCreatedAt:
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.Before(b.CreatedAt)
+		}
+	case SortUpdatedAt:
+		if !a.UpdatedAt.Equal(b.UpdatedAt) {
+			return a.UpdatedAt.Before(b.UpdatedAt)
+		}
+	}
+	return a.Key < b.Key
+}
+
+// after indica si f va despues del cursor en el orden de la query
+func (q Query) after(f core.FeatureFlag, c *Cursor) bool {
+	pos := core.FeatureFlag{Key: c.Key, CreatedAt: c.Time, UpdatedAt: c.Time}
+	if q.Desc {
+		return q.less(f, pos)
+	}
+	return q.less(pos, f)
+}
+
+// Paginate ordena y pagina en memoria una lista ya filtrada.
+// Lo usan los backends que no pueden hacerlo en la query (memory, ...).
+func Paginate(list []core.FeatureFlag, q Query) (Page, error) {
+	c, err := q.DecodeCursor()
+	if err != nil {
+		return Page{}, err
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		if q.Desc {
+			return q.less(list[j], list[i])
+		}
+		return q.less(list[i], list[j])
+	})
+
+	if c != nil {
+		start := sort.Search(len(list), func(i int) bool { return q.after(list[i], c) })
+		list = list[start:]
+	}
+
+	page := Page{Items: list}
+	if q.Limit > 0 && len(list) > q.Limit {
+		page.Items = list[:q.Limit]
+		page.NextCursor = q.CursorAfter(page.Items[q.Limit-1])
+	}
+	return page, nil
+}
+
+// SortFlags ordena como List: por created_at y, a igual fecha, por key
+func SortFlags(list []core.FeatureFlag) {
+	var q Query
+	sort.Slice(list, func(i, j int) bool { return q.less(list[i], list[j]) })
+}
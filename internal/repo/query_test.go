@@ -0,0 +1,92 @@
+package repo
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Franconl/ffaas/internal/core"
+)
+
+func TestPaginateWalksAllPages(t *testing.T) {
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	var flags []core.FeatureFlag
+	for i := 0; i < 7; i++ {
+		// dos flags por timestamp, para probar el desempate por key
+		ts := base.Add(time.Duration(i/2) * time.Minute)
+		flags = append(flags, core.FeatureFlag{Key: fmt.Sprintf("flag_%d", 6-i), CreatedAt: ts, UpdatedAt: ts})
+	}
+
+	for _, desc := range []bool{false, true} {
+		q := Query{Limit: 3, Desc: desc}
+		var seen []string
+		for pages := 0; ; pages++ {
+			if pages > 5 {
+				t.Fatalf("desc=%v: too many pages", desc)
+			}
+			page, err := Paginate(append([]core.FeatureFlag(nil), flags...), q)
+			if err != nil {
+				t.Fatalf("desc=%v: %v", desc, err)
+			}
+			for _, f := range page.Items {
+				seen = append(seen, f.Key)
+			}
+			if page.NextCursor == "" {
+				break
+			}
+			q.Cursor = page.NextCursor
+		}
+
+		if len(seen) != len(flags) {
+			t.Fatalf("desc=%v: expected %d flags, got %v", desc, len(flags), seen)
+		}
+		// created_at ascendente, y dentro del mismo minuto por key
+		want := []string{"flag_5", "flag_6", "flag_3", "flag_4", "flag_1", "flag_2", "flag_0"}
+		for i := range want {
+			got := seen[i]
+			if desc {
+				got = seen[len(seen)-1-i]
+			}
+			if got != want[i] {
+				t.Errorf("desc=%v: position %d expected %s, got %s", desc, i, want[i], got)
+			}
+		}
+	}
+}
+
+func TestPaginateRejectsCursorFromOtherSort(t *testing.T) {
+	f := core.FeatureFlag{Key: "a", CreatedAt: time.Now()}
+	cursor := Query{SortBy: SortKey}.CursorAfter(f)
+
+	if _, err := Paginate(nil, Query{Cursor: cursor}); err != ErrInvalidCursor {
+		t.Errorf("expected ErrInvalidCursor, got %v", err)
+	}
+	if _, err := Paginate(nil, Query{Cursor: "%%%"}); err != ErrInvalidCursor {
+		t.Errorf("expected ErrInvalidCursor, got %v", err)
+	}
+}
+
+func TestQueryMatches(t *testing.T) {
+	enabled := true
+	f := core.FeatureFlag{Key: "checkout_v2", Enabled: true, Owner: "payments", Tags: []string{"web"}}
+
+	cases := []struct {
+		name string
+		q    Query
+		want bool
+	}{
+		{"empty", Query{}, true},
+		{"prefix", Query{KeyPrefix: "checkout"}, true},
+		{"prefix miss", Query{KeyPrefix: "v2"}, false},
+		{"search case insensitive", Query{Search: "OUT_V"}, true},
+		{"enabled", Query{Enabled: &enabled}, true},
+		{"tag miss", Query{Tag: "mobile"}, false},
+		{"owner", Query{Owner: "payments", Tag: "web"}, true},
+		{"updated since", Query{UpdatedSince: time.Now()}, false},
+	}
+	for _, c := range cases {
+		if got := c.q.Matches(f); got != c.want {
+			t.Errorf("%s: expected %v, got %v", c.name, c.want, got)
+		}
+	}
+}
@@ -1,12 +1,54 @@
 package repo
 
-import "github.com/Franconl/ffaas/internal/core"
+import (
+	"context"
+	"time"
 
+	"github.com/Franconl/ffaas/internal/core"
+)
+
+// Flags es el contrato de los backends de flags.
+//
+// DeleteByID borra sin condiciones; Archive es el soft delete que usa la API,
+// y Purge borra definitivamente solo si la flag esta archivada desde antes de
+// archivedBefore (ErrNotArchived / ErrRetention si no).
+//
+// Apply aplica una lista de cambios de forma atomica: o se aplican todos o
+// ninguno. Si uno falla devuelve un *ChangeError con el cambio que fallo. Si
+// no, cada changes[i].Flag queda con el estado guardado (ids y timestamps
+// generados por el backend incluidos).
 type Flags interface {
-	Create(f *core.FeatureFlag) error
-	Update(f *core.FeatureFlag) error
-	DeleteByID(id string) error
-	GetByID(id string) (*core.FeatureFlag, error)
-	GetByKey(key string) (*core.FeatureFlag, error)
-	List() ([]core.FeatureFlag, error)
+	Create(ctx context.Context, f *core.FeatureFlag) error
+	Update(ctx context.Context, f *core.FeatureFlag) error
+	DeleteByID(ctx context.Context, id string) error
+	Archive(ctx context.Context, id string) error
+	Restore(ctx context.Context, id string) error
+	Purge(ctx context.Context, id string, archivedBefore time.Time) error
+	GetByID(ctx context.Context, id string) (*core.FeatureFlag, error)
+	GetByKey(ctx context.Context, key string) (*core.FeatureFlag, error)
+	// List devuelve las flags activas (no archivadas) ordenadas por created_at
+	// y key (ver SortFlags): es lo que ven los SDKs
+	List(ctx context.Context) ([]core.FeatureFlag, error)
+	Search(ctx context.Context, q Query) (Page, error)
+	Apply(ctx context.Context, changes []Change) error
+}
+
+// Usage persiste las estadisticas de evaluacion de las flags.
+// AddUsage suma los contadores recibidos a los que ya estan guardados.
+type Usage interface {
+	AddUsage(ctx context.Context, usage []core.FlagUsage) error
+	ListUsage(ctx context.Context) ([]core.FlagUsage, error)
+}
+
+// Snapshot es la lista de flags activas junto con su version. La version crece
+// con cada mutacion: dos snapshots con la misma version tienen las mismas flags.
+type Snapshot struct {
+	Version uint64
+	Flags   []core.FeatureFlag
+}
+
+// Snapshotter lo implementan los backends que versionan la lista completa
+// (cached.Repo); los SDKs la usan para pedidos condicionales de /sdk/flags
+type Snapshotter interface {
+	Snapshot(ctx context.Context) (Snapshot, error)
 }
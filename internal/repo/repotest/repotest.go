@@ -0,0 +1,556 @@
+// Package repotest es la suite de conformidad de repo.Flags: el mismo conjunto
+// de tests que tiene que pasar cualquier backend (memory, sqlite, postgres, y
+// los decoradores como cached) para que la API se comporte igual con todos.
+//
+// Uso, desde el _test.go del backend:
+//
+//	func TestConformance(t *testing.T) {
+//		repotest.Run(t, func(t *testing.T) repo.Flags { return memory.New() })
+//	}
+//
+// newRepo se llama una vez por subtest y tiene que devolver un repo vacio.
+package repotest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Franconl/ffaas/internal/core"
+	"github.com/Franconl/ffaas/internal/repo"
+	"github.com/google/uuid"
+)
+
+// Run corre toda la suite contra los repos que devuelve newRepo
+func Run(t *testing.T, newRepo func(t *testing.T) repo.Flags) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, r repo.Flags)
+	}{
+		{"CreateAndGet", testCreateAndGet},
+		{"Validation", testValidation},
+		{"Uniqueness", testUniqueness},
+		{"KeyRename", testKeyRename},
+		{"NotFound", testNotFound},
+		{"ArchiveLifecycle", testArchiveLifecycle},
+		{"Ordering", testOrdering},
+		{"Apply", testApply},
+		{"ApplyPrecondition", testApplyPrecondition},
+		{"RolloutChangedAt", testRolloutChangedAt},
+		{"ConcurrentWrites", testConcurrentWrites},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) { tt.fn(t, newRepo(t)) })
+	}
+}
+
+func mustCreate(t *testing.T, r repo.Flags, f core.FeatureFlag) core.FeatureFlag {
+	t.Helper()
+	if err := r.Create(context.Background(), &f); err != nil {
+		t.Fatalf("create %s: %v", f.Key, err)
+	}
+	return f
+}
+
+func keys(list []core.FeatureFlag) []string {
+	out := make([]string, 0, len(list))
+	for _, f := range list {
+		out = append(out, f.Key)
+	}
+	return out
+}
+
+func testCreateAndGet(t *testing.T, r repo.Flags) {
+	ctx := context.Background()
+	expires := time.Now().Add(24 * time.Hour).UTC().Truncate(time.Second)
+
+	f := mustCreate(t, r, core.FeatureFlag{
+		Key: "new_checkout", Description: "nuevo checkout", Enabled: true, Percentage: 30,
+		Owner: "payments", Tags: []string{"web", "payments"}, Temporary: true, ExpiresAt: &expires,
+	})
+	if f.ID == "" || f.CreatedAt.IsZero() || f.UpdatedAt.IsZero() {
+		t.Fatalf("create no completo id/timestamps: %+v", f)
+	}
+
+	byID, err := r.GetByID(ctx, f.ID)
+	if err != nil {
+		t.Fatalf("get by id: %v", err)
+	}
+	byKey, err := r.GetByKey(ctx, f.Key)
+	if err != nil {
+		t.Fatalf("get by key: %v", err)
+	}
+	for _, got := range []*core.FeatureFlag{byID, byKey} {
+		if got.ID != f.ID || got.Key != f.Key || got.Description != f.Description || !got.Enabled ||
+			got.Percentage != 30 || got.Owner != "payments" || got.Kind != core.KindRelease || !got.Temporary {
+			t.Errorf("flag leida = %+v", got)
+		}
+		if fmt.Sprint(got.Tags) != "[web payments]" {
+			t.Errorf("tags = %v", got.Tags)
+		}
+		if got.ExpiresAt == nil || !got.ExpiresAt.Equal(expires) {
+			t.Errorf("expires_at = %v, se esperaba %v", got.ExpiresAt, expires)
+		}
+		if got.Archived() {
+			t.Errorf("flag nueva archivada: %+v", got)
+		}
+	}
+
+	// lo devuelto es una copia: modificarlo no cambia lo guardado
+	byID.Tags[0] = "mutada"
+	again, _ := r.GetByID(ctx, f.ID)
+	if again.Tags[0] != "web" {
+		t.Errorf("el repo comparte los tags con quien los lee: %v", again.Tags)
+	}
+}
+
+func testValidation(t *testing.T, r repo.Flags) {
+	ctx := context.Background()
+
+	cases := []struct {
+		name string
+		flag core.FeatureFlag
+		want error
+	}{
+		{"key vacia", core.FeatureFlag{}, repo.ErrKeyRequired},
+		{"key invalida", core.FeatureFlag{Key: "con espacios"}, core.ErrInvalidKey},
+		{"porcentaje negativo", core.FeatureFlag{Key: "neg", Percentage: -1}, repo.ErrInvalidPercent},
+		{"porcentaje mayor a 100", core.FeatureFlag{Key: "big", Percentage: 101}, repo.ErrInvalidPercent},
+		{"kind invalido", core.FeatureFlag{Key: "kind", Kind: "bogus"}, repo.ErrInvalidKind},
+		{"tag duplicado", core.FeatureFlag{Key: "tags", Tags: []string{"a", "a"}}, core.ErrInvalidTag},
+	}
+	for _, c := range cases {
+		err := r.Create(ctx, &c.flag)
+		var verr *core.ValidationError
+		if !errors.As(err, &verr) || !errors.Is(err, c.want) {
+			t.Errorf("create con %s: se esperaba ValidationError con %v, vino %v", c.name, c.want, err)
+		}
+	}
+	if page, err := r.Search(ctx, repo.Query{}); err != nil || len(page.Items) != 0 {
+		t.Errorf("las flags invalidas no se tienen que guardar: %v %v", keys(page.Items), err)
+	}
+
+	f := mustCreate(t, r, core.FeatureFlag{Key: "valid", Percentage: 10})
+	bad := f
+	bad.Percentage = 150
+	if err := r.Update(ctx, &bad); !errors.Is(err, repo.ErrInvalidPercent) {
+		t.Errorf("update con porcentaje invalido: se esperaba ErrInvalidPercent, vino %v", err)
+	}
+	bad = f
+	bad.Key = ""
+	if err := r.Update(ctx, &bad); !errors.Is(err, repo.ErrKeyRequired) {
+		t.Errorf("update sin key: se esperaba ErrKeyRequired, vino %v", err)
+	}
+	if got, _ := r.GetByID(ctx, f.ID); got == nil || got.Percentage != 10 || got.Key != "valid" {
+		t.Errorf("un update invalido modifico la flag: %+v", got)
+	}
+}
+
+func testUniqueness(t *testing.T, r repo.Flags) {
+	ctx := context.Background()
+	a := mustCreate(t, r, core.FeatureFlag{Key: "flag_a"})
+	mustCreate(t, r, core.FeatureFlag{Key: "flag_b"})
+
+	if err := r.Create(ctx, &core.FeatureFlag{Key: "flag_a"}); !errors.Is(err, repo.ErrKeyAlreadyUsed) {
+		t.Errorf("create con key repetida: se esperaba ErrKeyAlreadyUsed, vino %v", err)
+	}
+
+	a.Key = "flag_b"
+	if err := r.Update(ctx, &a); !errors.Is(err, repo.ErrKeyAlreadyUsed) {
+		t.Errorf("renombrar a una key usada: se esperaba ErrKeyAlreadyUsed, vino %v", err)
+	}
+	if got, err := r.GetByKey(ctx, "flag_a"); err != nil || got.ID != a.ID {
+		t.Errorf("flag_a despues del rename fallido = %+v, %v", got, err)
+	}
+
+	// una flag archivada sigue ocupando su key
+	if err := r.Archive(ctx, a.ID); err != nil {
+		t.Fatalf("archive: %v", err)
+	}
+	if err := r.Create(ctx, &core.FeatureFlag{Key: "flag_a"}); !errors.Is(err, repo.ErrKeyAlreadyUsed) {
+		t.Errorf("create con la key de una archivada: se esperaba ErrKeyAlreadyUsed, vino %v", err)
+	}
+}
+
+func testKeyRename(t *testing.T, r repo.Flags) {
+	ctx := context.Background()
+	f := mustCreate(t, r, core.FeatureFlag{Key: "old_key", Percentage: 5})
+
+	// leer antes del rename (los decoradores con caché tienen que invalidar)
+	if _, err := r.GetByKey(ctx, "old_key"); err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if _, err := r.GetByKey(ctx, "new_key"); !errors.Is(err, repo.ErrNotFound) {
+		t.Fatalf("new_key antes del rename: se esperaba ErrNotFound, vino %v", err)
+	}
+
+	f.Key = "new_key"
+	if err := r.Update(ctx, &f); err != nil {
+		t.Fatalf("rename: %v", err)
+	}
+	if _, err := r.GetByKey(ctx, "old_key"); !errors.Is(err, repo.ErrNotFound) {
+		t.Errorf("la key vieja tiene que dejar de resolver, vino %v", err)
+	}
+	got, err := r.GetByKey(ctx, "new_key")
+	if err != nil || got.ID != f.ID || got.Percentage != 5 {
+		t.Errorf("new_key = %+v, %v", got, err)
+	}
+	if got, err := r.GetByID(ctx, f.ID); err != nil || got.Key != "new_key" {
+		t.Errorf("get by id despues del rename = %+v, %v", got, err)
+	}
+
+	// la key vieja queda libre para otra flag
+	other := mustCreate(t, r, core.FeatureFlag{Key: "old_key"})
+	if got, err := r.GetByKey(ctx, "old_key"); err != nil || got.ID != other.ID {
+		t.Errorf("old_key reutilizada = %+v, %v", got, err)
+	}
+}
+
+func testNotFound(t *testing.T, r repo.Flags) {
+	ctx := context.Background()
+	mustCreate(t, r, core.FeatureFlag{Key: "exists"})
+
+	for _, id := range []string{uuid.NewString(), "not-a-uuid"} {
+		ops := map[string]error{
+			"GetByID":    func() error { _, err := r.GetByID(ctx, id); return err }(),
+			"Update":     r.Update(ctx, &core.FeatureFlag{ID: id, Key: "missing"}),
+			"DeleteByID": r.DeleteByID(ctx, id),
+			"Archive":    r.Archive(ctx, id),
+			"Restore":    r.Restore(ctx, id),
+			"Purge":      r.Purge(ctx, id, time.Now()),
+		}
+		for op, err := range ops {
+			if !errors.Is(err, repo.ErrNotFound) {
+				t.Errorf("%s(%q): se esperaba ErrNotFound, vino %v", op, id, err)
+			}
+		}
+	}
+	// tambien comprueba que los updates fallidos no crearon nada
+	if _, err := r.GetByKey(ctx, "missing"); !errors.Is(err, repo.ErrNotFound) {
+		t.Errorf("GetByKey: se esperaba ErrNotFound, vino %v", err)
+	}
+}
+
+func testArchiveLifecycle(t *testing.T, r repo.Flags) {
+	ctx := context.Background()
+	f := mustCreate(t, r, core.FeatureFlag{Key: "lifecycle"})
+	mustCreate(t, r, core.FeatureFlag{Key: "other"})
+
+	if err := r.Purge(ctx, f.ID, time.Now().Add(time.Hour)); !errors.Is(err, repo.ErrNotArchived) {
+		t.Errorf("purge de una activa: se esperaba ErrNotArchived, vino %v", err)
+	}
+	if err := r.Restore(ctx, f.ID); !errors.Is(err, repo.ErrNotArchived) {
+		t.Errorf("restore de una activa: se esperaba ErrNotArchived, vino %v", err)
+	}
+
+	if err := r.Archive(ctx, f.ID); err != nil {
+		t.Fatalf("archive: %v", err)
+	}
+	if err := r.Archive(ctx, f.ID); !errors.Is(err, repo.ErrArchived) {
+		t.Errorf("archivar dos veces: se esperaba ErrArchived, vino %v", err)
+	}
+	if err := r.Update(ctx, &f); !errors.Is(err, repo.ErrArchived) {
+		t.Errorf("update de una archivada: se esperaba ErrArchived, vino %v", err)
+	}
+	if got, err := r.GetByID(ctx, f.ID); err != nil || !got.Archived() {
+		t.Errorf("la archivada se sigue leyendo por id: %+v, %v", got, err)
+	}
+	list, err := r.List(ctx)
+	if err != nil || fmt.Sprint(keys(list)) != "[other]" {
+		t.Errorf("List tiene que omitir las archivadas: %v, %v", keys(list), err)
+	}
+	archived := true
+	if page, err := r.Search(ctx, repo.Query{Archived: &archived}); err != nil || fmt.Sprint(keys(page.Items)) != "[lifecycle]" {
+		t.Errorf("Search de archivadas = %v, %v", keys(page.Items), err)
+	}
+
+	if err := r.Restore(ctx, f.ID); err != nil {
+		t.Fatalf("restore: %v", err)
+	}
+	if got, err := r.GetByKey(ctx, "lifecycle"); err != nil || got.Archived() {
+		t.Errorf("restaurada = %+v, %v", got, err)
+	}
+
+	if err := r.Archive(ctx, f.ID); err != nil {
+		t.Fatalf("archive: %v", err)
+	}
+	if err := r.Purge(ctx, f.ID, time.Now().Add(-time.Hour)); !errors.Is(err, repo.ErrRetention) {
+		t.Errorf("purge dentro de la retencion: se esperaba ErrRetention, vino %v", err)
+	}
+	if err := r.Purge(ctx, f.ID, time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("purge: %v", err)
+	}
+	if _, err := r.GetByID(ctx, f.ID); !errors.Is(err, repo.ErrNotFound) {
+		t.Errorf("despues del purge: se esperaba ErrNotFound, vino %v", err)
+	}
+	if _, err := r.GetByKey(ctx, "lifecycle"); !errors.Is(err, repo.ErrNotFound) {
+		t.Errorf("despues del purge la key tiene que dejar de resolver, vino %v", err)
+	}
+}
+
+func testOrdering(t *testing.T, r repo.Flags) {
+	ctx := context.Background()
+	// las fechas se guardan con precision de microsegundos (postgres):
+	// dormir un poco asegura created_at distintos
+	for _, k := range []string{"charlie", "alpha", "bravo", "delta"} {
+		mustCreate(t, r, core.FeatureFlag{Key: k})
+		time.Sleep(2 * time.Millisecond)
+	}
+
+	list, err := r.List(ctx)
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if got := fmt.Sprint(keys(list)); got != "[charlie alpha bravo delta]" {
+		t.Errorf("List tiene que ordenar por created_at, vino %s", got)
+	}
+
+	cases := []struct {
+		q    repo.Query
+		want string
+	}{
+		{repo.Query{}, "[charlie alpha bravo delta]"},
+		{repo.Query{Desc: true}, "[delta bravo alpha charlie]"},
+		{repo.Query{SortBy: repo.SortKey}, "[alpha bravo charlie delta]"},
+		{repo.Query{SortBy: repo.SortKey, Desc: true}, "[delta charlie bravo alpha]"},
+	}
+	for _, c := range cases {
+		// paginando de a 3 para cubrir tambien el cursor
+		c.q.Limit = 3
+		var all []core.FeatureFlag
+		for i := 0; ; i++ {
+			page, err := r.Search(ctx, c.q)
+			if err != nil {
+				t.Fatalf("search %+v: %v", c.q, err)
+			}
+			all = append(all, page.Items...)
+			if page.NextCursor == "" || i > 2 {
+				break
+			}
+			c.q.Cursor = page.NextCursor
+		}
+		if got := fmt.Sprint(keys(all)); got != c.want {
+			t.Errorf("search sort=%q desc=%v = %s, se esperaba %s", c.q.SortBy, c.q.Desc, got, c.want)
+		}
+	}
+}
+
+func testApply(t *testing.T, r repo.Flags) {
+	ctx := context.Background()
+	a := mustCreate(t, r, core.FeatureFlag{Key: "apply_a"})
+	b := mustCreate(t, r, core.FeatureFlag{Key: "apply_b"})
+
+	a.Enabled = true
+	err := r.Apply(ctx, []repo.Change{
+		{Op: repo.ChangeUpdate, Flag: a},
+		{Op: repo.ChangeCreate, Flag: core.FeatureFlag{Key: "apply_c"}},
+		{Op: repo.ChangeCreate, Flag: core.FeatureFlag{Key: "apply_b"}},
+	})
+	var cerr *repo.ChangeError
+	if !errors.As(err, &cerr) || cerr.Index != 2 || !errors.Is(err, repo.ErrKeyAlreadyUsed) {
+		t.Fatalf("se esperaba ChangeError en el indice 2 con ErrKeyAlreadyUsed, vino %v", err)
+	}
+	if got, _ := r.GetByID(ctx, a.ID); got == nil || got.Enabled {
+		t.Errorf("el lote fallido aplico el update: %+v", got)
+	}
+	if _, err := r.GetByKey(ctx, "apply_c"); !errors.Is(err, repo.ErrNotFound) {
+		t.Errorf("el lote fallido creo apply_c: %v", err)
+	}
+
+	changes := []repo.Change{
+		{Op: repo.ChangeUpdate, Flag: a},
+		{Op: repo.ChangeCreate, Flag: core.FeatureFlag{Key: "apply_c"}},
+		{Op: repo.ChangeArchive, Flag: b},
+	}
+	if err := r.Apply(ctx, changes); err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+	// cada cambio queda con lo que se guardo (id, timestamps, archived_at)
+	for i, c := range changes {
+		stored, err := r.GetByID(ctx, c.Flag.ID)
+		if err != nil {
+			t.Fatalf("cambio %d: GetByID(%q): %v", i, c.Flag.ID, err)
+		}
+		if stored.Key != c.Flag.Key || !stored.UpdatedAt.Equal(c.Flag.UpdatedAt) || stored.Archived() != c.Flag.Archived() {
+			t.Errorf("cambio %d: el caller tiene %+v, quedo guardado %+v", i, c.Flag, *stored)
+		}
+	}
+	if !changes[2].Flag.Archived() {
+		t.Error("el archive no devolvio archived_at")
+	}
+	list, _ := r.List(ctx)
+	if got := fmt.Sprint(keys(list)); got != "[apply_a apply_c]" {
+		t.Errorf("despues del apply List = %s", got)
+	}
+	if got, _ := r.GetByKey(ctx, "apply_a"); got == nil || !got.Enabled {
+		t.Errorf("apply_a = %+v", got)
+	}
+}
+
+func testApplyPrecondition(t *testing.T, r repo.Flags) {
+	ctx := context.Background()
+	created := mustCreate(t, r, core.FeatureFlag{Key: "guarded"})
+	read, err := r.GetByID(ctx, created.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// otro escritor cambia la flag despues de la lectura
+	time.Sleep(2 * time.Millisecond)
+	other := *read
+	other.Percentage = 20
+	if err := r.Update(ctx, &other); err != nil {
+		t.Fatal(err)
+	}
+
+	stale := *read
+	stale.Enabled = true
+	err = r.Apply(ctx, []repo.Change{{Op: repo.ChangeUpdate, Flag: stale, IfUpdatedAt: read.UpdatedAt}})
+	if !errors.Is(err, repo.ErrStale) {
+		t.Fatalf("update con updated_at viejo: se esperaba ErrStale, vino %v", err)
+	}
+	if got, _ := r.GetByID(ctx, read.ID); got == nil || got.Enabled || got.Percentage != 20 {
+		t.Errorf("el update viejo piso la flag: %+v", got)
+	}
+
+	fresh, _ := r.GetByID(ctx, read.ID)
+	fresh.Enabled = true
+	if err := r.Apply(ctx, []repo.Change{{Op: repo.ChangeUpdate, Flag: *fresh, IfUpdatedAt: fresh.UpdatedAt}}); err != nil {
+		t.Fatalf("update con updated_at vigente: %v", err)
+	}
+	if got, _ := r.GetByID(ctx, read.ID); got == nil || !got.Enabled || got.Percentage != 20 {
+		t.Errorf("despues del update condicional = %+v", got)
+	}
+}
+
+// rollout_changed_at solo se mueve cuando cambia lo que reciben los usuarios
+func testRolloutChangedAt(t *testing.T, r repo.Flags) {
+	ctx := context.Background()
+	f := mustCreate(t, r, core.FeatureFlag{Key: "rollout", Percentage: 10})
+	if f.RolloutChangedAt.IsZero() || !f.RolloutChangedAt.Equal(f.CreatedAt) {
+		t.Fatalf("create: rollout_changed_at = %v, created_at = %v", f.RolloutChangedAt, f.CreatedAt)
+	}
+	created := f.RolloutChangedAt
+
+	time.Sleep(2 * time.Millisecond)
+	f.Description = "solo texto"
+	f.Tags = []string{"web"}
+	if err := r.Update(ctx, &f); err != nil {
+		t.Fatal(err)
+	}
+	got, err := r.GetByID(ctx, f.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.RolloutChangedAt.Equal(created) || !f.RolloutChangedAt.Equal(created) {
+		t.Errorf("editar la descripcion movio rollout_changed_at: %v -> %v", created, got.RolloutChangedAt)
+	}
+	if !got.UpdatedAt.After(created) {
+		t.Errorf("updated_at no se movio: %v", got.UpdatedAt)
+	}
+
+	time.Sleep(2 * time.Millisecond)
+	f.Percentage = 100
+	if err := r.Update(ctx, &f); err != nil {
+		t.Fatal(err)
+	}
+	got, err = r.GetByID(ctx, f.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.RolloutChangedAt.After(created) || !got.RolloutChangedAt.Equal(f.RolloutChangedAt) {
+		t.Errorf("cambiar el porcentaje: rollout_changed_at = %v (devuelto %v), antes %v",
+			got.RolloutChangedAt, f.RolloutChangedAt, created)
+	}
+}
+
+func testConcurrentWrites(t *testing.T, r repo.Flags) {
+	ctx := context.Background()
+	const n = 10
+
+	// creates concurrentes de la misma key: gana exactamente uno
+	var wg sync.WaitGroup
+	errs := make([]error, n)
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = r.Create(ctx, &core.FeatureFlag{Key: "contended", Percentage: i})
+		}()
+	}
+	wg.Wait()
+	wins := 0
+	for _, err := range errs {
+		switch {
+		case err == nil:
+			wins++
+		case !errors.Is(err, repo.ErrKeyAlreadyUsed):
+			t.Errorf("create concurrente: se esperaba ErrKeyAlreadyUsed, vino %v", err)
+		}
+	}
+	if wins != 1 {
+		t.Errorf("creates concurrentes de la misma key: %d exitosos, se esperaba 1", wins)
+	}
+
+	// renames concurrentes a la misma key: tambien gana uno solo
+	ids := make([]string, n)
+	for i := range n {
+		ids[i] = mustCreate(t, r, core.FeatureFlag{Key: fmt.Sprintf("rename_%d", i)}).ID
+	}
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = r.Update(ctx, &core.FeatureFlag{ID: ids[i], Key: "renamed"})
+		}()
+	}
+	wg.Wait()
+	wins = 0
+	for _, err := range errs {
+		switch {
+		case err == nil:
+			wins++
+		case !errors.Is(err, repo.ErrKeyAlreadyUsed):
+			t.Errorf("rename concurrente: se esperaba ErrKeyAlreadyUsed, vino %v", err)
+		}
+	}
+	if wins != 1 {
+		t.Errorf("renames concurrentes a la misma key: %d exitosos, se esperaba 1", wins)
+	}
+
+	// updates concurrentes a flags distintas: se aplican todos
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			f, err := r.GetByID(ctx, ids[i])
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			f.Percentage = 50
+			errs[i] = r.Update(ctx, f)
+		}()
+	}
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil {
+			t.Errorf("update concurrente de %s: %v", ids[i], err)
+		}
+	}
+	page, err := r.Search(ctx, repo.Query{})
+	if err != nil {
+		t.Fatalf("search: %v", err)
+	}
+	if len(page.Items) != n+1 {
+		t.Errorf("se esperaban %d flags, hay %d", n+1, len(page.Items))
+	}
+	for _, f := range page.Items {
+		if f.Key != "contended" && f.Percentage != 50 {
+			t.Errorf("se perdio un update concurrente: %+v", f)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package resilient
+
+import (
+	"context"
+	"time"
+
+	"github.com/Franconl/ffaas/internal/breaker"
+	"github.com/Franconl/ffaas/internal/core"
+	"github.com/Franconl/ffaas/internal/repo"
+)
+
+// IsFailure dice si un error del repo cuenta como falla del backend para el
+// circuit breaker: los de negocio no cuentan (las cancelaciones del cliente
+// las resuelve el breaker, que no las cuenta para ningun lado)
+func IsFailure(err error) bool {
+	return err != nil && !repo.IsDomainError(err)
+}
+
+// Guarded pasa cada llamada al repo por un circuit breaker. Con el breaker
+// abierto devuelve repo.ErrUnavailable al instante en vez de esperar el
+// timeout de una base caida.
+type Guarded struct {
+	base repo.Flags
+	b    *breaker.Breaker
+}
+
+// Guard envuelve base con el breaker b (creado con IsFailure como criterio)
+func Guard(base repo.Flags, b *breaker.Breaker) *Guarded {
+	return &Guarded{base: base, b: b}
+}
+
+func (g *Guarded) do(fn func() error) error {
+	t, err := g.b.Allow()
+	if err != nil {
+		return repo.ErrUnavailable
+	}
+	err = fn()
+	g.b.Record(t, err)
+	return err
+}
+
+func (g *Guarded) Create(ctx context.Context, f *core.FeatureFlag) error {
+	return g.do(func() error { return g.base.Create(ctx, f) })
+}
+
+func (g *Guarded) Update(ctx context.Context, f *core.FeatureFlag) error {
+	return g.do(func() error { return g.base.Update(ctx, f) })
+}
+
+func (g *Guarded) DeleteByID(ctx context.Context, id string) error {
+	return g.do(func() error { return g.base.DeleteByID(ctx, id) })
+}
+
+func (g *Guarded) Archive(ctx context.Context, id string) error {
+	return g.do(func() error { return g.base.Archive(ctx, id) })
+}
+
+func (g *Guarded) Restore(ctx context.Context, id string) error {
+	return g.do(func() error { return g.base.Restore(ctx, id) })
+}
+
+func (g *Guarded) Purge(ctx context.Context, id string, archivedBefore time.Time) error {
+	return g.do(func() error { return g.base.Purge(ctx, id, archivedBefore) })
+}
+
+func (g *Guarded) GetByID(ctx context.Context, id string) (ff *core.FeatureFlag, err error) {
+	err = g.do(func() error {
+		ff, err = g.base.GetByID(ctx, id)
+		return err
+	})
+	return ff, err
+}
+
+func (g *Guarded) GetByKey(ctx context.Context, key string) (ff *core.FeatureFlag, err error) {
+	err = g.do(func() error {
+		ff, err = g.base.GetByKey(ctx, key)
+		return err
+	})
+	return ff, err
+}
+
+func (g *Guarded) List(ctx context.Context) (list []core.FeatureFlag, err error) {
+	err = g.do(func() error {
+		list, err = g.base.List(ctx)
+		return err
+	})
+	return list, err
+}
+
+func (g *Guarded) Search(ctx context.Context, q repo.Query) (page repo.Page, err error) {
+	err = g.do(func() error {
+		page, err = g.base.Search(ctx, q)
+		return err
+	})
+	return page, err
+}
+
+func (g *Guarded) Apply(ctx context.Context, changes []repo.Change) error {
+	return g.do(func() error { return g.base.Apply(ctx, changes) })
+}
@@ -0,0 +1,147 @@
+// Package resilient mantiene las lecturas de los SDKs funcionando cuando el
+// backend se cae: Guard corta las llamadas con un circuit breaker y Repo
+// contesta GetByKey/List desde la ultima copia buena de las flags.
+package resilient
+
+import (
+	"context"
+	"log"
+	"slices"
+	"sync/atomic"
+	"time"
+
+	"github.com/Franconl/ffaas/internal/core"
+	"github.com/Franconl/ffaas/internal/metrics"
+	"github.com/Franconl/ffaas/internal/repo"
+)
+
+// Repo decora el store de flags con una copia en proceso de la ultima lista
+// buena. Se actualiza con cada List/Snapshot que sale bien y con Run; si el
+// backend falla (no por un error de negocio), las lecturas de los SDKs salen
+// de la copia y se marcan con repo.MarkStale. Las escrituras y la API admin
+// pasan directo: no tiene sentido aceptar cambios que no se pueden guardar.
+type Repo struct {
+	repo.Flags
+	snapshots repo.Snapshotter
+
+	good atomic.Pointer[goodCopy]
+}
+
+type goodCopy struct {
+	snap  repo.Snapshot
+	byKey map[string]core.FeatureFlag
+	// asOf es la ultima vez que se confirmo contra el backend
+	asOf time.Time
+}
+
+// New envuelve base. snapshots es opcional: si esta, List usa la lista
+// versionada (y la version se conserva en la copia).
+func New(base repo.Flags, snapshots repo.Snapshotter) *Repo {
+	return &Repo{Flags: base, snapshots: snapshots}
+}
+
+// unavailable dice si err es una falla del backend (y no del request)
+func unavailable(ctx context.Context, err error) bool {
+	return err != nil && ctx.Err() == nil && !repo.IsDomainError(err)
+}
+
+func (r *Repo) GetByKey(ctx context.Context, key string) (*core.FeatureFlag, error) {
+	f, err := r.Flags.GetByKey(ctx, key)
+	if !unavailable(ctx, err) {
+		return f, err
+	}
+	good := r.good.Load()
+	if good == nil {
+		return nil, err
+	}
+	cur, ok := good.byKey[key]
+	if !ok {
+		return nil, err
+	}
+	metrics.StaleResponses.WithLabelValues("GetByKey").Inc()
+	repo.MarkStale(ctx, good.asOf)
+	cur.Tags = slices.Clone(cur.Tags)
+	return &cur, nil
+}
+
+func (r *Repo) List(ctx context.Context) ([]core.FeatureFlag, error) {
+	snap, err := r.Snapshot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return snap.Flags, nil
+}
+
+// Snapshot implementa repo.Snapshotter con el mismo fallback que List
+func (r *Repo) Snapshot(ctx context.Context) (repo.Snapshot, error) {
+	snap, err := r.fetch(ctx)
+	if err == nil {
+		r.remember(snap)
+		return snap, nil
+	}
+	if !unavailable(ctx, err) {
+		return repo.Snapshot{}, err
+	}
+	good := r.good.Load()
+	if good == nil {
+		return repo.Snapshot{}, err
+	}
+	metrics.StaleResponses.WithLabelValues("List").Inc()
+	repo.MarkStale(ctx, good.asOf)
+	return repo.Snapshot{Version: good.snap.Version, Flags: cloneFlags(good.snap.Flags)}, nil
+}
+
+func (r *Repo) fetch(ctx context.Context) (repo.Snapshot, error) {
+	if r.snapshots != nil {
+		return r.snapshots.Snapshot(ctx)
+	}
+	list, err := r.Flags.List(ctx)
+	return repo.Snapshot{Flags: list}, err
+}
+
+// remember guarda snap como la ultima copia buena. Si la version no cambio
+// solo se actualiza asOf, sin copiar las flags.
+func (r *Repo) remember(snap repo.Snapshot) {
+	now := time.Now()
+	if cur := r.good.Load(); cur != nil && snap.Version != 0 && cur.snap.Version == snap.Version {
+		r.good.Store(&goodCopy{snap: cur.snap, byKey: cur.byKey, asOf: now})
+		return
+	}
+
+	flags := cloneFlags(snap.Flags)
+	byKey := make(map[string]core.FeatureFlag, len(flags))
+	for _, f := range flags {
+		byKey[f.Key] = f
+	}
+	r.good.Store(&goodCopy{snap: repo.Snapshot{Version: snap.Version, Flags: flags}, byKey: byKey, asOf: now})
+}
+
+// Run refresca la copia cada interval hasta que se cancela ctx, asi esta al
+// dia aunque ningun SDK pida la lista completa
+func (r *Repo) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if snap, err := r.fetch(ctx); err == nil {
+			r.remember(snap)
+		} else if ctx.Err() == nil {
+			log.Println("⚠️ No se pudo refrescar la copia de flags:", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func cloneFlags(list []core.FeatureFlag) []core.FeatureFlag {
+	out := make([]core.FeatureFlag, len(list))
+	for i, f := range list {
+		f.Tags = slices.Clone(f.Tags)
+		out[i] = f
+	}
+	return out
+}
@@ -0,0 +1,100 @@
+package resilient
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Franconl/ffaas/internal/breaker"
+	"github.com/Franconl/ffaas/internal/core"
+	"github.com/Franconl/ffaas/internal/repo"
+	"github.com/Franconl/ffaas/internal/repo/memory"
+)
+
+var errDown = errors.New("dial tcp: connection refused")
+
+// flakyRepo falla todas las lecturas mientras down es true
+type flakyRepo struct {
+	*memory.Repo
+	down bool
+}
+
+func (f *flakyRepo) GetByKey(ctx context.Context, key string) (*core.FeatureFlag, error) {
+	if f.down {
+		return nil, errDown
+	}
+	return f.Repo.GetByKey(ctx, key)
+}
+
+func (f *flakyRepo) List(ctx context.Context) ([]core.FeatureFlag, error) {
+	if f.down {
+		return nil, errDown
+	}
+	return f.Repo.List(ctx)
+}
+
+func TestServesLastGoodCopyWhenDown(t *testing.T) {
+	ctx := context.Background()
+	base := &flakyRepo{Repo: memory.New()}
+	flag := core.FeatureFlag{Key: "new_checkout", Enabled: true, Percentage: 100}
+	if err := base.Create(ctx, &flag); err != nil {
+		t.Fatal(err)
+	}
+	r := New(base, nil)
+
+	// sin copia todavia, la falla llega tal cual
+	base.down = true
+	if _, err := r.GetByKey(ctx, "new_checkout"); !errors.Is(err, errDown) {
+		t.Fatalf("err = %v, want errDown", err)
+	}
+
+	base.down = false
+	if _, err := r.List(ctx); err != nil {
+		t.Fatal(err)
+	}
+	base.down = true
+
+	sctx, stale := repo.TrackStale(ctx)
+	f, err := r.GetByKey(sctx, "new_checkout")
+	if err != nil || !f.Enabled {
+		t.Fatalf("GetByKey while down = %+v, %v", f, err)
+	}
+	if _, ok := stale(); !ok {
+		t.Error("response was not marked stale")
+	}
+	if list, err := r.List(ctx); err != nil || len(list) != 1 {
+		t.Errorf("List while down = %v, %v", list, err)
+	}
+	if _, err := r.GetByKey(ctx, "unknown"); !errors.Is(err, errDown) {
+		t.Errorf("unknown key while down: err = %v, want errDown", err)
+	}
+
+	// un not found real no se tapa con la copia
+	base.down = false
+	if err := base.DeleteByID(ctx, flag.ID); err != nil {
+		t.Fatal(err)
+	}
+	sctx, stale = repo.TrackStale(ctx)
+	if _, err := r.GetByKey(sctx, "new_checkout"); !errors.Is(err, repo.ErrNotFound) {
+		t.Errorf("err = %v, want ErrNotFound", err)
+	}
+	if _, ok := stale(); ok {
+		t.Error("not found was marked stale")
+	}
+}
+
+func TestGuardFailsFastWhenOpen(t *testing.T) {
+	ctx := context.Background()
+	base := &flakyRepo{Repo: memory.New(), down: true}
+	g := Guard(base, breaker.New("test-guard", 2, time.Minute, IsFailure))
+
+	for range 2 {
+		if _, err := g.GetByKey(ctx, "new_checkout"); !errors.Is(err, errDown) {
+			t.Fatalf("err = %v, want errDown", err)
+		}
+	}
+	if _, err := g.GetByKey(ctx, "new_checkout"); !errors.Is(err, repo.ErrUnavailable) {
+		t.Errorf("err = %v, want ErrUnavailable", err)
+	}
+}
@@ -0,0 +1,12 @@
+package sqlite
+
+import (
+	"testing"
+
+	"github.com/Franconl/ffaas/internal/repo"
+	"github.com/Franconl/ffaas/internal/repo/repotest"
+)
+
+func TestConformance(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) repo.Flags { return newTestRepo(t) })
+}
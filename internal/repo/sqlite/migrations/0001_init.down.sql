@@ -0,0 +1,2 @@
+DROP TABLE flag_usage;
+DROP TABLE feature_flags;
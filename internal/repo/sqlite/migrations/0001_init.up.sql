@@ -0,0 +1,27 @@
+-- Los timestamps se guardan como texto UTC de ancho fijo (ver timeFormat en repo.go),
+-- asi el orden lexicografico es el cronologico y el paginado por cursor funciona igual
+-- que en postgres. tags es un array JSON.
+CREATE TABLE feature_flags (
+    id          TEXT    PRIMARY KEY,
+    key         TEXT    NOT NULL UNIQUE,
+    description TEXT    NOT NULL DEFAULT '',
+    enabled     INTEGER NOT NULL DEFAULT 0,
+    percentage  INTEGER NOT NULL DEFAULT 0 CHECK (percentage BETWEEN 0 AND 100),
+    owner       TEXT    NOT NULL DEFAULT '',
+    tags        TEXT    NOT NULL DEFAULT '[]',
+    kind        TEXT    NOT NULL DEFAULT 'release',
+    temporary   INTEGER NOT NULL DEFAULT 0,
+    expires_at  TEXT,
+    archived_at TEXT,
+    created_at  TEXT    NOT NULL,
+    updated_at  TEXT    NOT NULL
+);
+
+CREATE INDEX feature_flags_created_at_idx ON feature_flags (created_at, key);
+CREATE INDEX feature_flags_updated_at_idx ON feature_flags (updated_at, key);
+
+CREATE TABLE flag_usage (
+    flag_id           TEXT    PRIMARY KEY REFERENCES feature_flags (id) ON DELETE CASCADE,
+    evaluations       INTEGER NOT NULL DEFAULT 0,
+    last_evaluated_at TEXT
+);
@@ -0,0 +1,5 @@
+-- Ultima vez que cambio enabled o percentage (ver la migracion de postgres). Las
+-- flags existentes arrancan con su updated_at. Queda nullable porque sqlite no
+-- agrega columnas NOT NULL sin default; los SELECT usan COALESCE con updated_at.
+ALTER TABLE feature_flags ADD COLUMN rollout_changed_at TEXT;
+UPDATE feature_flags SET rollout_changed_at = updated_at;
@@ -0,0 +1,613 @@
+// Package sqlite implementa repo.Flags sobre SQLite (driver modernc, sin cgo).
+// Pensado para deploys chicos y desarrollo local con persistencia: mismas
+// reglas que postgres (validacion, keys unicas, orden y paginado).
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+	"time"
+
+	"github.com/Franconl/ffaas/internal/core"
+	"github.com/Franconl/ffaas/internal/migrate"
+	"github.com/Franconl/ffaas/internal/repo"
+	"github.com/google/uuid"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+var (
+	ErrNotFound       = repo.ErrNotFound
+	ErrKeyAlreadyUsed = repo.ErrKeyAlreadyUsed
+	ErrKeyRequired    = repo.ErrKeyRequired
+	ErrInvalidPercent = repo.ErrInvalidPercent
+	ErrInvalidKind    = repo.ErrInvalidKind
+	ErrArchived       = repo.ErrArchived
+	ErrNotArchived    = repo.ErrNotArchived
+	ErrRetention      = repo.ErrRetention
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+type Repo struct {
+	db *sql.DB
+}
+
+func New(db *sql.DB) *Repo { return &Repo{db: db} }
+
+// Open abre (o crea) la base en path con foreign keys, WAL y busy timeout.
+// _txlock=immediate toma el lock de escritura al empezar la transaccion y
+// evita los SQLITE_BUSY por upgrade de lock entre transacciones concurrentes.
+func Open(path string) (*sql.DB, error) {
+	dsn := "file:" + path +
+		"?_pragma=foreign_keys(1)&_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)&_txlock=immediate"
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// Migrator devuelve el migrador con las migraciones embebidas. SQLite no
+// necesita lock: la transaccion de cada migracion ya bloquea la base.
+func Migrator(db *sql.DB) (migrate.Migrator, error) {
+	sub, err := fs.Sub(migrationFiles, "migrations")
+	if err != nil {
+		return migrate.Migrator{}, err
+	}
+	migrations, err := migrate.Load(sub)
+	if err != nil {
+		return migrate.Migrator{}, err
+	}
+	return migrate.Migrator{DB: db, Migrations: migrations}, nil
+}
+
+// Migrate aplica las migraciones embebidas que falten
+func Migrate(ctx context.Context, db *sql.DB) ([]int, error) {
+	m, err := Migrator(db)
+	if err != nil {
+		return nil, err
+	}
+	return m.Up(ctx)
+}
+
+// --- helpers ---
+
+// timeFormat tiene ancho fijo (siempre 9 decimales, siempre UTC) para que
+// comparar y ordenar los textos sea lo mismo que comparar las fechas
+const timeFormat = "2006-01-02T15:04:05.000000000Z"
+
+func timeArg(t time.Time) string { return t.UTC().Format(timeFormat) }
+
+func timePtrArg(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return timeArg(*t)
+}
+
+func parseTime(s string) (time.Time, error) { return time.Parse(timeFormat, s) }
+
+func parseTimePtr(s sql.NullString) (*time.Time, error) {
+	if !s.Valid {
+		return nil, nil
+	}
+	t, err := parseTime(s.String)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func tagsArg(tags []string) string {
+	if tags == nil {
+		tags = []string{}
+	}
+	b, _ := json.Marshal(tags)
+	return string(b)
+}
+
+// validate aplica los defaults y la validacion compartida del modelo
+func validate(f *core.FeatureFlag) error {
+	f.Normalize()
+	return f.Validate()
+}
+
+func isUniqueViolation(err error) bool {
+	var sqlErr *sqlite.Error
+	if errors.As(err, &sqlErr) {
+		return sqlErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE ||
+			sqlErr.Code() == sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY
+	}
+	return false
+}
+
+// columnas y scan compartidos por todos los SELECT de flags
+const selectFlag = `
+		SELECT id, key, description, enabled, percentage,
+		       owner, tags, kind, temporary, expires_at, archived_at, created_at, updated_at,
+		       COALESCE(rollout_changed_at, updated_at)
+		  FROM feature_flags`
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+// querier lo cumplen *sql.DB y *sql.Tx, asi las escrituras se comparten con Apply
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+func scanFlag(row scanner) (core.FeatureFlag, error) {
+	var ff core.FeatureFlag
+	var kind, tags, createdAt, updatedAt, rolloutChangedAt string
+	var expiresAt, archivedAt sql.NullString
+
+	err := row.Scan(
+		&ff.ID, &ff.Key, &ff.Description, &ff.Enabled, &ff.Percentage,
+		&ff.Owner, &tags, &kind, &ff.Temporary, &expiresAt, &archivedAt,
+		&createdAt, &updatedAt, &rolloutChangedAt,
+	)
+	if err != nil {
+		return ff, err
+	}
+
+	ff.Kind = core.FlagKind(kind)
+	if err := json.Unmarshal([]byte(tags), &ff.Tags); err != nil {
+		return ff, fmt.Errorf("flag %s: invalid tags: %w", ff.ID, err)
+	}
+	if len(ff.Tags) == 0 {
+		ff.Tags = nil
+	}
+	if ff.ExpiresAt, err = parseTimePtr(expiresAt); err != nil {
+		return ff, err
+	}
+	if ff.ArchivedAt, err = parseTimePtr(archivedAt); err != nil {
+		return ff, err
+	}
+	if ff.CreatedAt, err = parseTime(createdAt); err != nil {
+		return ff, err
+	}
+	if ff.UpdatedAt, err = parseTime(updatedAt); err != nil {
+		return ff, err
+	}
+	ff.RolloutChangedAt, err = parseTime(rolloutChangedAt)
+	return ff, err
+}
+
+func scanFlags(rows *sql.Rows) ([]core.FeatureFlag, error) {
+	var out []core.FeatureFlag
+	for rows.Next() {
+		ff, err := scanFlag(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, ff)
+	}
+	return out, rows.Err()
+}
+
+// --- CRUD ---
+
+func (r *Repo) Create(ctx context.Context, f *core.FeatureFlag) error {
+	return create(ctx, r.db, f)
+}
+
+func create(ctx context.Context, db querier, f *core.FeatureFlag) error {
+	if err := validate(f); err != nil {
+		return err
+	}
+	if f.ID == "" {
+		f.ID = uuid.NewString()
+	}
+	now := time.Now().UTC()
+
+	const q = `
+		INSERT INTO feature_flags
+			(id, key, description, enabled, percentage,
+			 owner, tags, kind, temporary, expires_at, created_at, updated_at, rollout_changed_at)
+		VALUES (?,?,?,?,?,?,?,?,?,?,?,?,?)`
+
+	_, err := db.ExecContext(ctx, q, f.ID, f.Key, f.Description, f.Enabled, f.Percentage,
+		f.Owner, tagsArg(f.Tags), string(f.Kind), f.Temporary, timePtrArg(f.ExpiresAt),
+		timeArg(now), timeArg(now), timeArg(now))
+	if err != nil {
+		if isUniqueViolation(err) {
+			return ErrKeyAlreadyUsed
+		}
+		return err
+	}
+
+	f.CreatedAt = now
+	f.UpdatedAt = now
+	f.RolloutChangedAt = now
+	return nil
+}
+
+func (r *Repo) Update(ctx context.Context, f *core.FeatureFlag) error {
+	return update(ctx, r.db, f, time.Time{})
+}
+
+// update pisa la flag; si ifUpdatedAt no es cero, solo si la guardada sigue
+// teniendo ese updated_at
+func update(ctx context.Context, db querier, f *core.FeatureFlag, ifUpdatedAt time.Time) error {
+	if err := validate(f); err != nil {
+		return err
+	}
+	// Asegurar existencia (y que no este archivada) antes de actualizar
+	archivedAt, err := archivedAt(ctx, db, f.ID)
+	if err != nil {
+		return err
+	}
+	if archivedAt != nil {
+		return ErrArchived
+	}
+
+	// en el SET, enabled y percentage son los valores anteriores
+	const q = `
+		UPDATE feature_flags
+		   SET key = ?,
+		       description = ?,
+		       enabled = ?,
+		       percentage = ?,
+		       rollout_changed_at = CASE WHEN enabled <> ? OR percentage <> ?
+		                                 THEN ? ELSE COALESCE(rollout_changed_at, updated_at) END,
+		       owner = ?,
+		       tags = ?,
+		       kind = ?,
+		       temporary = ?,
+		       expires_at = ?,
+		       updated_at = ?
+		 WHERE id = ?
+		   AND (? IS NULL OR updated_at = ?)
+		RETURNING rollout_changed_at`
+	var cond any
+	if !ifUpdatedAt.IsZero() {
+		cond = timeArg(ifUpdatedAt)
+	}
+	now := time.Now().UTC()
+	var rolloutChangedAt string
+	err = db.QueryRowContext(ctx, q, f.Key, f.Description, f.Enabled, f.Percentage,
+		f.Enabled, f.Percentage, timeArg(now),
+		f.Owner, tagsArg(f.Tags), string(f.Kind), f.Temporary, timePtrArg(f.ExpiresAt),
+		timeArg(now), f.ID, cond, cond).Scan(&rolloutChangedAt)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return repo.ErrStale
+	case isUniqueViolation(err):
+		return ErrKeyAlreadyUsed
+	case err != nil:
+		return err
+	}
+	f.UpdatedAt = now
+	f.RolloutChangedAt, err = parseTime(rolloutChangedAt)
+	return err
+}
+
+func (r *Repo) DeleteByID(ctx context.Context, id string) error {
+	const q = `DELETE FROM feature_flags WHERE id = ?`
+	res, err := r.db.ExecContext(ctx, q, id)
+	if err != nil {
+		return err
+	}
+	aff, _ := res.RowsAffected()
+	if aff == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// archivedAt devuelve el archived_at de la flag (nil si esta activa) o ErrNotFound
+func archivedAt(ctx context.Context, db querier, id string) (*time.Time, error) {
+	const q = `SELECT archived_at FROM feature_flags WHERE id = ?`
+	var raw sql.NullString
+	if err := db.QueryRowContext(ctx, q, id).Scan(&raw); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return parseTimePtr(raw)
+}
+
+func (r *Repo) Archive(ctx context.Context, id string) error {
+	return archive(ctx, r.db, id)
+}
+
+func archive(ctx context.Context, db querier, id string) error {
+	const q = `
+		UPDATE feature_flags
+		   SET archived_at = ?1, updated_at = ?1
+		 WHERE id = ?2 AND archived_at IS NULL`
+	res, err := db.ExecContext(ctx, q, timeArg(time.Now()), id)
+	if err != nil {
+		return err
+	}
+	if aff, _ := res.RowsAffected(); aff == 0 {
+		// no existe o ya estaba archivada
+		if _, err := archivedAt(ctx, db, id); err != nil {
+			return err
+		}
+		return ErrArchived
+	}
+	return nil
+}
+
+func (r *Repo) Restore(ctx context.Context, id string) error {
+	const q = `
+		UPDATE feature_flags
+		   SET archived_at = NULL, updated_at = ?
+		 WHERE id = ? AND archived_at IS NOT NULL`
+	res, err := r.db.ExecContext(ctx, q, timeArg(time.Now()), id)
+	if err != nil {
+		return err
+	}
+	if aff, _ := res.RowsAffected(); aff == 0 {
+		if _, err := archivedAt(ctx, r.db, id); err != nil {
+			return err
+		}
+		return ErrNotArchived
+	}
+	return nil
+}
+
+// Purge borra la flag en un solo DELETE condicionado, asi no hay carrera con un Restore
+func (r *Repo) Purge(ctx context.Context, id string, archivedBefore time.Time) error {
+	const q = `
+		DELETE FROM feature_flags
+		 WHERE id = ? AND archived_at IS NOT NULL AND archived_at <= ?`
+	res, err := r.db.ExecContext(ctx, q, id, timeArg(archivedBefore))
+	if err != nil {
+		return err
+	}
+	if aff, _ := res.RowsAffected(); aff == 0 {
+		at, err := archivedAt(ctx, r.db, id)
+		if err != nil {
+			return err
+		}
+		if at == nil {
+			return ErrNotArchived
+		}
+		return ErrRetention
+	}
+	return nil
+}
+
+func (r *Repo) GetByID(ctx context.Context, id string) (*core.FeatureFlag, error) {
+	return getByID(ctx, r.db, id)
+}
+
+func getByID(ctx context.Context, db querier, id string) (*core.FeatureFlag, error) {
+	const q = selectFlag + `
+		 WHERE id = ?`
+	ff, err := scanFlag(db.QueryRowContext(ctx, q, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &ff, nil
+}
+
+func (r *Repo) GetByKey(ctx context.Context, key string) (*core.FeatureFlag, error) {
+	const q = selectFlag + `
+		 WHERE key = ?`
+	ff, err := scanFlag(r.db.QueryRowContext(ctx, q, key))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &ff, nil
+}
+
+func (r *Repo) List(ctx context.Context) ([]core.FeatureFlag, error) {
+	const q = selectFlag + `
+		 WHERE archived_at IS NULL
+		 ORDER BY created_at ASC, key ASC`
+	rows, err := r.db.QueryContext(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanFlags(rows)
+}
+
+// Search arma el WHERE con los mismos filtros y el mismo paginado por keyset
+// que postgres. Diferencias de dialecto: tags se busca con json_each, y prefix
+// y q no usan LIKE (en SQLite no distingue mayusculas) sino substr/instr.
+func (r *Repo) Search(ctx context.Context, q repo.Query) (repo.Page, error) {
+	c, err := q.DecodeCursor()
+	if err != nil {
+		return repo.Page{}, err
+	}
+
+	var where []string
+	var args []any
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("?%d", len(args))
+	}
+
+	if q.Owner != "" {
+		where = append(where, "owner = "+arg(q.Owner))
+	}
+	if q.Tag != "" {
+		where = append(where, "EXISTS (SELECT 1 FROM json_each(tags) WHERE json_each.value = "+arg(q.Tag)+")")
+	}
+	if q.Kind != "" {
+		where = append(where, "kind = "+arg(string(q.Kind)))
+	}
+	if q.Temporary != nil {
+		where = append(where, "temporary = "+arg(*q.Temporary))
+	}
+	if q.Enabled != nil {
+		where = append(where, "enabled = "+arg(*q.Enabled))
+	}
+	if q.KeyPrefix != "" {
+		where = append(where, fmt.Sprintf("substr(key, 1, %s) = %s", arg(len(q.KeyPrefix)), arg(q.KeyPrefix)))
+	}
+	if q.Search != "" {
+		where = append(where, "instr(lower(key), lower("+arg(q.Search)+")) > 0")
+	}
+	if !q.UpdatedSince.IsZero() {
+		where = append(where, "updated_at >= "+arg(timeArg(q.UpdatedSince)))
+	}
+	if q.Archived != nil {
+		if *q.Archived {
+			where = append(where, "archived_at IS NOT NULL")
+		} else {
+			where = append(where, "archived_at IS NULL")
+		}
+	}
+
+	// q.Sort() ya viene validado: nunca se interpola input del usuario
+	col := string(q.Sort())
+	dir, cmp := "ASC", ">"
+	if q.Desc {
+		dir, cmp = "DESC", "<"
+	}
+	if c != nil {
+		if q.Sort() == repo.SortKey {
+			where = append(where, "key "+cmp+" "+arg(c.Key))
+		} else {
+			where = append(where, fmt.Sprintf("(%s, key) %s (%s, %s)", col, cmp, arg(timeArg(c.Time)), arg(c.Key)))
+		}
+	}
+
+	query := selectFlag
+	if len(where) > 0 {
+		query += "\n\t\t WHERE " + strings.Join(where, " AND ")
+	}
+	if q.Sort() == repo.SortKey {
+		query += fmt.Sprintf("\n\t\t ORDER BY key %s", dir)
+	} else {
+		query += fmt.Sprintf("\n\t\t ORDER BY %s %s, key %s", col, dir, dir)
+	}
+	if q.Limit > 0 {
+		query += "\n\t\t LIMIT " + arg(q.Limit+1)
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return repo.Page{}, err
+	}
+	defer rows.Close()
+
+	list, err := scanFlags(rows)
+	if err != nil {
+		return repo.Page{}, err
+	}
+
+	page := repo.Page{Items: list}
+	if q.Limit > 0 && len(list) > q.Limit {
+		page.Items = list[:q.Limit]
+		page.NextCursor = q.CursorAfter(page.Items[q.Limit-1])
+	}
+	return page, nil
+}
+
+// Apply corre todos los cambios en una transaccion: si uno falla se hace
+// rollback y la tabla queda como estaba. Cada Flag se relee dentro de la
+// transaccion, asi el caller recibe lo que quedo guardado.
+func (r *Repo) Apply(ctx context.Context, changes []repo.Change) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for i := range changes {
+		c := &changes[i]
+		switch c.Op {
+		case repo.ChangeCreate:
+			err = create(ctx, tx, &c.Flag)
+		case repo.ChangeUpdate:
+			err = update(ctx, tx, &c.Flag, c.IfUpdatedAt)
+		case repo.ChangeArchive:
+			err = archive(ctx, tx, c.Flag.ID)
+		default:
+			err = fmt.Errorf("unknown change op %q", c.Op)
+		}
+		if err == nil {
+			var stored *core.FeatureFlag
+			if stored, err = getByID(ctx, tx, c.Flag.ID); err == nil {
+				c.Flag = *stored
+			}
+		}
+		if err != nil {
+			return &repo.ChangeError{Index: i, Op: c.Op, Key: c.Flag.Key, Err: err}
+		}
+	}
+
+	return tx.Commit()
+}
+
+// --- Usage ---
+
+// AddUsage suma los contadores en flag_usage dentro de una transaccion
+func (r *Repo) AddUsage(ctx context.Context, usage []core.FlagUsage) error {
+	if len(usage) == 0 {
+		return nil
+	}
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// flags borradas entre el Record y el flush se ignoran (en postgres lo
+	// resuelve la FK en la transaccion; aca se filtra con el SELECT)
+	const q = `
+		INSERT INTO flag_usage (flag_id, evaluations, last_evaluated_at)
+		SELECT id, ?2, ?3 FROM feature_flags WHERE id = ?1
+		ON CONFLICT (flag_id) DO UPDATE
+		   SET evaluations = flag_usage.evaluations + excluded.evaluations,
+		       last_evaluated_at = max(coalesce(flag_usage.last_evaluated_at, ''), excluded.last_evaluated_at)`
+	for _, u := range usage {
+		if _, err := tx.ExecContext(ctx, q, u.FlagID, u.Evaluations, timeArg(u.LastEvaluatedAt)); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (r *Repo) ListUsage(ctx context.Context) ([]core.FlagUsage, error) {
+	const q = `SELECT flag_id, evaluations, last_evaluated_at FROM flag_usage`
+	rows, err := r.db.QueryContext(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []core.FlagUsage
+	for rows.Next() {
+		var u core.FlagUsage
+		var last sql.NullString
+		if err := rows.Scan(&u.FlagID, &u.Evaluations, &last); err != nil {
+			return nil, err
+		}
+		if last.Valid {
+			if u.LastEvaluatedAt, err = parseTime(last.String); err != nil {
+				return nil, err
+			}
+		}
+		out = append(out, u)
+	}
+	return out, rows.Err()
+}
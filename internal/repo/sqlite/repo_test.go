@@ -0,0 +1,143 @@
+package sqlite
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Franconl/ffaas/internal/core"
+	"github.com/Franconl/ffaas/internal/repo"
+)
+
+func newTestRepo(t *testing.T) *Repo {
+	t.Helper()
+	db, err := Open(filepath.Join(t.TempDir(), "flags.db"))
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if _, err := Migrate(context.Background(), db); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	return New(db)
+}
+
+func TestCRUD(t *testing.T) {
+	ctx := context.Background()
+	r := newTestRepo(t)
+
+	f := core.FeatureFlag{Key: "new_checkout", Percentage: 20, Tags: []string{"payments", "web"}}
+	if err := r.Create(ctx, &f); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if err := r.Create(ctx, &core.FeatureFlag{Key: "new_checkout"}); !errors.Is(err, repo.ErrKeyAlreadyUsed) {
+		t.Errorf("expected ErrKeyAlreadyUsed, got %v", err)
+	}
+
+	got, err := r.GetByKey(ctx, "new_checkout")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if got.ID != f.ID || got.Kind != core.KindRelease || len(got.Tags) != 2 || !got.CreatedAt.Equal(f.CreatedAt) {
+		t.Errorf("unexpected flag %+v", got)
+	}
+
+	got.Enabled = true
+	got.Key = "checkout_v2"
+	if err := r.Update(ctx, got); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if _, err := r.GetByKey(ctx, "new_checkout"); !errors.Is(err, repo.ErrNotFound) {
+		t.Errorf("expected old key to be gone, got %v", err)
+	}
+
+	if err := r.Archive(ctx, f.ID); err != nil {
+		t.Fatalf("archive: %v", err)
+	}
+	if err := r.Update(ctx, got); !errors.Is(err, repo.ErrArchived) {
+		t.Errorf("expected ErrArchived, got %v", err)
+	}
+	if err := r.Purge(ctx, f.ID, time.Now().Add(-time.Hour)); !errors.Is(err, repo.ErrRetention) {
+		t.Errorf("expected ErrRetention, got %v", err)
+	}
+	if err := r.Purge(ctx, f.ID, time.Now()); err != nil {
+		t.Errorf("purge: %v", err)
+	}
+	if _, err := r.GetByID(ctx, f.ID); !errors.Is(err, repo.ErrNotFound) {
+		t.Errorf("expected ErrNotFound after purge, got %v", err)
+	}
+}
+
+func TestSearchMatchesMemorySemantics(t *testing.T) {
+	ctx := context.Background()
+	r := newTestRepo(t)
+
+	for _, key := range []string{"web.login", "web.Checkout", "api.checkout", "web.search"} {
+		f := core.FeatureFlag{Key: key, Tags: []string{"team"}}
+		if err := r.Create(ctx, &f); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	page, err := r.Search(ctx, repo.Query{KeyPrefix: "web.", Search: "CHECK"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Items) != 1 || page.Items[0].Key != "web.Checkout" {
+		t.Errorf("unexpected items %+v", page.Items)
+	}
+
+	var keys []string
+	q := repo.Query{Tag: "team", SortBy: repo.SortKey, Desc: true, Limit: 3}
+	for {
+		page, err := r.Search(ctx, q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, f := range page.Items {
+			keys = append(keys, f.Key)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		q.Cursor = page.NextCursor
+	}
+	want := []string{"web.search", "web.login", "web.Checkout", "api.checkout"}
+	if len(keys) != len(want) {
+		t.Fatalf("expected %v, got %v", want, keys)
+	}
+	for i := range want {
+		if keys[i] != want[i] {
+			t.Errorf("expected %v, got %v", want, keys)
+			break
+		}
+	}
+}
+
+func TestUsage(t *testing.T) {
+	ctx := context.Background()
+	r := newTestRepo(t)
+
+	f := core.FeatureFlag{Key: "a"}
+	if err := r.Create(ctx, &f); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now().UTC()
+	batch := []core.FlagUsage{{FlagID: f.ID, Evaluations: 2, LastEvaluatedAt: now}, {FlagID: "gone", Evaluations: 1, LastEvaluatedAt: now}}
+	if err := r.AddUsage(ctx, batch); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.AddUsage(ctx, batch[:1]); err != nil {
+		t.Fatal(err)
+	}
+
+	usage, err := r.ListUsage(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(usage) != 1 || usage[0].Evaluations != 4 || !usage[0].LastEvaluatedAt.Equal(now) {
+		t.Errorf("unexpected usage %+v", usage)
+	}
+}
@@ -0,0 +1,47 @@
+package repo
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Cuando el backend no responde, resilient.Repo contesta las lecturas con la
+// ultima copia buena. Como la interfaz Flags no tiene donde decirlo, lo marca
+// en el contexto: el handler llama a TrackStale antes de leer y despues
+// consulta si la respuesta salio de esa copia y de cuando es.
+
+type staleKey struct{}
+
+type staleMark struct {
+	mu    sync.Mutex
+	asOf  time.Time
+	stale bool
+}
+
+// TrackStale devuelve un contexto donde los repos pueden marcar la respuesta
+// como vieja, y una funcion que devuelve de cuando es la copia usada
+func TrackStale(ctx context.Context) (context.Context, func() (asOf time.Time, stale bool)) {
+	m := &staleMark{}
+	return context.WithValue(ctx, staleKey{}, m), func() (time.Time, bool) {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		return m.asOf, m.stale
+	}
+}
+
+// MarkStale indica que la respuesta salio de una copia tomada en asOf. Sin
+// TrackStale en el contexto no hace nada.
+func MarkStale(ctx context.Context, asOf time.Time) {
+	m, ok := ctx.Value(staleKey{}).(*staleMark)
+	if !ok {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	// si hubo varias lecturas viejas, vale la mas vieja
+	if !m.stale || asOf.Before(m.asOf) {
+		m.asOf = asOf
+	}
+	m.stale = true
+}
@@ -0,0 +1,93 @@
+// Package stream reparte los cambios de flags a los SDKs conectados por
+// streaming (GET /sdk/stream).
+package stream
+
+import (
+	"sync"
+)
+
+// subscriberBuffer es cuantos eventos se encolan por suscriptor antes de
+// considerarlo lento y cortarlo
+const subscriberBuffer = 64
+
+// Event es un cambio de flag tal como lo ve un SDK. Op es insert, update o
+// delete; en un delete la key deja de existir. Los SDKs vuelven a pedir la
+// flag (o la lista) en vez de confiar en el evento.
+type Event struct {
+	Op     string `json:"op"`
+	ID     string `json:"id"`
+	Key    string `json:"key"`
+	OldKey string `json:"old_key,omitempty"`
+}
+
+// Hub reparte cada evento a todos los suscriptores. Publish nunca bloquea: a
+// un suscriptor que no lee se le cierra el canal (el SDK reconecta y vuelve
+// a pedir la lista completa).
+type Hub struct {
+	mu     sync.Mutex
+	subs   map[chan Event]struct{}
+	closed bool
+}
+
+func NewHub() *Hub {
+	return &Hub{subs: make(map[chan Event]struct{})}
+}
+
+// Subscribe devuelve el canal de eventos y la funcion para desuscribirse.
+// El canal se cierra si el suscriptor se atrasa o si se cierra el hub.
+func (h *Hub) Subscribe() (<-chan Event, func()) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	ch := make(chan Event, subscriberBuffer)
+	if h.closed {
+		close(ch)
+		return ch, func() {}
+	}
+	h.subs[ch] = struct{}{}
+	return ch, func() { h.remove(ch) }
+}
+
+func (h *Hub) Publish(e Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for ch := range h.subs {
+		select {
+		case ch <- e:
+		default:
+			delete(h.subs, ch)
+			close(ch)
+		}
+	}
+}
+
+// Close cierra todos los streams (al apagar el server, para que Shutdown no
+// espere a conexiones que no terminan nunca)
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.closed = true
+	for ch := range h.subs {
+		delete(h.subs, ch)
+		close(ch)
+	}
+}
+
+// Subscribers devuelve cuantos streams hay abiertos
+func (h *Hub) Subscribers() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.subs)
+}
+
+func (h *Hub) remove(ch chan Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.subs[ch]; ok {
+		delete(h.subs, ch)
+		close(ch)
+	}
+}
@@ -0,0 +1,35 @@
+package stream
+
+import "testing"
+
+func TestHubDropsSlowSubscribers(t *testing.T) {
+	h := NewHub()
+	fast, unsubscribe := h.Subscribe()
+	defer unsubscribe()
+	slow, _ := h.Subscribe()
+
+	for i := range subscriberBuffer + 1 {
+		h.Publish(Event{Op: "update", Key: "new_checkout"})
+		if i < subscriberBuffer {
+			<-fast
+		}
+	}
+	<-fast
+
+	// el lento recibe lo que entro en el buffer y despues el canal cerrado
+	n := 0
+	for range slow {
+		n++
+	}
+	if n != subscriberBuffer {
+		t.Errorf("slow subscriber got %d events, want %d", n, subscriberBuffer)
+	}
+	if h.Subscribers() != 1 {
+		t.Errorf("subscribers = %d, want 1", h.Subscribers())
+	}
+
+	h.Close()
+	if _, ok := <-fast; ok {
+		t.Error("expected channel closed after Close")
+	}
+}
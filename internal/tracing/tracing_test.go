@@ -0,0 +1,101 @@
+package tracing
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// recordSpans instala un TracerProvider que guarda los spans en memoria
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	sr := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr))
+
+	prevTP, prevProp := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		tp.Shutdown(t.Context())
+		otel.SetTracerProvider(prevTP)
+		otel.SetTextMapPropagator(prevProp)
+	})
+	return sr
+}
+
+func TestMiddlewareContinuesTraceparent(t *testing.T) {
+	sr := recordSpans(t)
+
+	var handlerSpan trace.SpanContext
+	r := chi.NewRouter()
+	r.Use(Middleware)
+	r.Get("/flags/{id}", func(w http.ResponseWriter, r *http.Request) {
+		handlerSpan = trace.SpanContextFromContext(r.Context())
+	})
+
+	const traceID, parentID = "4bf92f3577b34da6a3ce929d0e0e4736", "00f067aa0ba902b7"
+	req := httptest.NewRequest(http.MethodGet, "/flags/a1", nil)
+	req.Header.Set("traceparent", "00-"+traceID+"-"+parentID+"-01")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := sr.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("%d spans, want 1", len(spans))
+	}
+	span := spans[0]
+	if got := span.SpanContext().TraceID().String(); got != traceID {
+		t.Errorf("trace id = %s, want the caller's %s", got, traceID)
+	}
+	if got := span.Parent().SpanID().String(); got != parentID || !span.Parent().IsRemote() {
+		t.Errorf("parent = %s (remote %v), want remote %s", got, span.Parent().IsRemote(), parentID)
+	}
+	if handlerSpan.SpanID() != span.SpanContext().SpanID() {
+		t.Error("the handler context does not carry the request span")
+	}
+}
+
+func TestMiddlewareNamesSpanAfterRoute(t *testing.T) {
+	sr := recordSpans(t)
+
+	r := chi.NewRouter()
+	r.Use(Middleware)
+	r.Get("/flags/{id}", func(w http.ResponseWriter, r *http.Request) {})
+	r.Get("/boom", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	for _, path := range []string{"/flags/a1", "/boom"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	spans := sr.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("%d spans, want 2", len(spans))
+	}
+	// sin traceparent arranca un trace nuevo
+	if spans[0].Parent().IsValid() {
+		t.Errorf("span without traceparent has parent %v", spans[0].Parent())
+	}
+	if got := spans[0].Name(); got != "GET /flags/{id}" {
+		t.Errorf("span name = %q, want %q", got, "GET /flags/{id}")
+	}
+	var route string
+	for _, a := range spans[0].Attributes() {
+		if a.Key == "http.route" {
+			route = a.Value.AsString()
+		}
+	}
+	if route != "/flags/{id}" {
+		t.Errorf("http.route = %q", route)
+	}
+	if got := spans[1].Status().Code.String(); got != "Error" {
+		t.Errorf("5xx span status = %s, want Error", got)
+	}
+}
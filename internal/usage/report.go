@@ -0,0 +1,81 @@
+package usage
+
+import (
+	"sort"
+	"time"
+
+	"github.com/Franconl/ffaas/internal/core"
+)
+
+// ReportOptions define los umbrales del reporte de flags viejas
+type ReportOptions struct {
+	Now       time.Time
+	UnusedFor time.Duration // sin evaluaciones hace al menos este tiempo
+	StuckFor  time.Duration // al 0% o 100% sin cambios de rollout hace al menos este tiempo
+}
+
+// StaleFlag es una flag del reporte junto con su uso
+type StaleFlag struct {
+	Flag  core.FeatureFlag
+	Usage core.FlagUsage
+}
+
+// Report agrupa las flags candidatas a limpieza. Una flag puede aparecer en mas de una lista.
+type Report struct {
+	Unused  []StaleFlag
+	Stuck   []StaleFlag
+	Expired []StaleFlag
+}
+
+// BuildReport clasifica las flags segun su uso y estado.
+//   - Unused: no se evaluaron en UnusedFor (las creadas hace menos de UnusedFor no cuentan)
+//   - Stuck: totalmente prendidas o apagadas y sin cambiar enabled/percentage en StuckFor
+//   - Expired: pasaron su expires_at
+func BuildReport(flags []core.FeatureFlag, usage map[string]core.FlagUsage, opts ReportOptions) Report {
+	unusedSince := opts.Now.Add(-opts.UnusedFor)
+	stuckSince := opts.Now.Add(-opts.StuckFor)
+
+	var rep Report
+	for _, f := range flags {
+		item := StaleFlag{Flag: f, Usage: usage[f.ID]}
+		item.Usage.FlagID = f.ID
+
+		lastSeen := item.Usage.LastEvaluatedAt
+		if lastSeen.IsZero() {
+			lastSeen = f.CreatedAt
+		}
+		if lastSeen.Before(unusedSince) {
+			rep.Unused = append(rep.Unused, item)
+		}
+
+		if fullyRolled(f) && rolloutChangedAt(f).Before(stuckSince) {
+			rep.Stuck = append(rep.Stuck, item)
+		}
+
+		if f.Expired(opts.Now) {
+			rep.Expired = append(rep.Expired, item)
+		}
+	}
+
+	byKey := func(list []StaleFlag) {
+		sort.Slice(list, func(i, j int) bool { return list[i].Flag.Key < list[j].Flag.Key })
+	}
+	byKey(rep.Unused)
+	byKey(rep.Stuck)
+	byKey(rep.Expired)
+	return rep
+}
+
+// rolloutChangedAt: editar la descripcion o los tags no saca a una flag de
+// stuck. Las flags sin el dato (GitOps, snapshots viejos) usan updated_at.
+func rolloutChangedAt(f core.FeatureFlag) time.Time {
+	if f.RolloutChangedAt.IsZero() {
+		return f.UpdatedAt
+	}
+	return f.RolloutChangedAt
+}
+
+// fullyRolled indica si la flag da el mismo resultado para todos los usuarios
+func fullyRolled(f core.FeatureFlag) bool {
+	return !f.Enabled || f.Percentage <= 0 || f.Percentage >= 100
+}
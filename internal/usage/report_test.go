@@ -0,0 +1,91 @@
+package usage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Franconl/ffaas/internal/core"
+	"github.com/Franconl/ffaas/internal/repo/memory"
+)
+
+func TestBuildReport(t *testing.T) {
+	now := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	old := now.Add(-90 * 24 * time.Hour)
+	yesterday := now.Add(-24 * time.Hour)
+	expired := now.Add(-time.Hour)
+
+	flags := []core.FeatureFlag{
+		{ID: "1", Key: "unused", Enabled: true, Percentage: 50, CreatedAt: old, UpdatedAt: old},
+		{ID: "2", Key: "stuck_on", Enabled: true, Percentage: 100, CreatedAt: old, UpdatedAt: old},
+		{ID: "3", Key: "rolling", Enabled: true, Percentage: 30, CreatedAt: old, UpdatedAt: old},
+		{ID: "4", Key: "expired", Enabled: true, Percentage: 50, ExpiresAt: &expired, CreatedAt: yesterday, UpdatedAt: yesterday},
+		{ID: "5", Key: "new", Enabled: false, CreatedAt: yesterday, UpdatedAt: yesterday},
+		// le cambiaron la descripcion ayer, pero el rollout esta igual hace 90 dias
+		{ID: "6", Key: "stuck_off", Enabled: false, CreatedAt: old, UpdatedAt: yesterday, RolloutChangedAt: old},
+		{ID: "7", Key: "recently_on", Enabled: true, Percentage: 100, CreatedAt: old, UpdatedAt: yesterday, RolloutChangedAt: yesterday},
+	}
+	stats := map[string]core.FlagUsage{
+		"2": {FlagID: "2", Evaluations: 10, LastEvaluatedAt: yesterday},
+		"3": {FlagID: "3", Evaluations: 5, LastEvaluatedAt: yesterday},
+		"6": {FlagID: "6", Evaluations: 5, LastEvaluatedAt: yesterday},
+		"7": {FlagID: "7", Evaluations: 5, LastEvaluatedAt: yesterday},
+	}
+
+	rep := BuildReport(flags, stats, ReportOptions{
+		Now:       now,
+		UnusedFor: 30 * 24 * time.Hour,
+		StuckFor:  30 * 24 * time.Hour,
+	})
+
+	if keys(rep.Unused) != "unused" {
+		t.Errorf("unused: expected [unused], got [%s]", keys(rep.Unused))
+	}
+	if keys(rep.Stuck) != "stuck_off,stuck_on" {
+		t.Errorf("stuck: expected [stuck_off,stuck_on], got [%s]", keys(rep.Stuck))
+	}
+	if keys(rep.Expired) != "expired" {
+		t.Errorf("expired: expected [expired], got [%s]", keys(rep.Expired))
+	}
+}
+
+func TestTrackerFlush(t *testing.T) {
+	store := memory.New()
+	tracker := NewTracker(store)
+
+	at := time.Now().UTC()
+	tracker.Record("1", at)
+	tracker.Record("1", at.Add(time.Second))
+	tracker.Record("2", at)
+
+	if err := tracker.Flush(context.Background()); err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+	tracker.Record("1", at.Add(2*time.Second))
+
+	snap, err := tracker.Snapshot(context.Background())
+	if err != nil {
+		t.Fatalf("snapshot: %v", err)
+	}
+
+	if snap["1"].Evaluations != 3 {
+		t.Errorf("expected 3 evaluations, got %d", snap["1"].Evaluations)
+	}
+	if !snap["1"].LastEvaluatedAt.Equal(at.Add(2 * time.Second)) {
+		t.Errorf("unexpected last evaluation %v", snap["1"].LastEvaluatedAt)
+	}
+	if snap["2"].Evaluations != 1 {
+		t.Errorf("expected 1 evaluation, got %d", snap["2"].Evaluations)
+	}
+}
+
+func keys(list []StaleFlag) string {
+	out := ""
+	for i, item := range list {
+		if i > 0 {
+			out += ","
+		}
+		out += item.Flag.Key
+	}
+	return out
+}
@@ -0,0 +1,135 @@
+package usage
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/Franconl/ffaas/internal/core"
+	"github.com/Franconl/ffaas/internal/repo"
+)
+
+// Tracker agrega en memoria las evaluaciones de cada flag y las baja
+// periodicamente al store, para no escribir en la base en cada /sdk/eval.
+// Un *Tracker nil es valido y no registra nada.
+type Tracker struct {
+	store repo.Usage
+
+	mu      sync.Mutex
+	pending map[string]core.FlagUsage
+}
+
+func NewTracker(store repo.Usage) *Tracker {
+	return &Tracker{
+		store:   store,
+		pending: make(map[string]core.FlagUsage),
+	}
+}
+
+// Record registra una evaluacion de la flag
+func (t *Tracker) Record(flagID string, at time.Time) {
+	t.Add(core.FlagUsage{FlagID: flagID, Evaluations: 1, LastEvaluatedAt: at})
+}
+
+// Add suma evaluaciones ya agregadas por otro lado (los SDKs que evaluan local)
+func (t *Tracker) Add(u core.FlagUsage) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	cur := t.pending[u.FlagID]
+	cur.FlagID = u.FlagID
+	cur.Merge(u)
+	t.pending[u.FlagID] = cur
+}
+
+// Flush baja al store lo acumulado. Si falla, los contadores vuelven a pending
+// para el proximo intento.
+func (t *Tracker) Flush(ctx context.Context) error {
+	if t == nil {
+		return nil
+	}
+	batch := t.drain()
+	if len(batch) == 0 {
+		return nil
+	}
+	if err := t.store.AddUsage(ctx, batch); err != nil {
+		t.restore(batch)
+		return err
+	}
+	return nil
+}
+
+// Run hace Flush cada interval hasta que se cancele ctx, y un ultimo Flush al salir
+func (t *Tracker) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := t.Flush(ctx); err != nil {
+				log.Println("⚠️ Error guardando uso de flags:", err)
+			}
+		case <-ctx.Done():
+			flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			if err := t.Flush(flushCtx); err != nil {
+				log.Println("⚠️ Error guardando uso de flags:", err)
+			}
+			cancel()
+			return
+		}
+	}
+}
+
+// Snapshot devuelve el uso guardado mas lo que todavia no se bajo al store
+func (t *Tracker) Snapshot(ctx context.Context) (map[string]core.FlagUsage, error) {
+	out := make(map[string]core.FlagUsage)
+	if t == nil {
+		return out, nil
+	}
+	stored, err := t.store.ListUsage(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, u := range stored {
+		out[u.FlagID] = u
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for id, u := range t.pending {
+		cur := out[id]
+		cur.FlagID = id
+		cur.Merge(u)
+		out[id] = cur
+	}
+	return out, nil
+}
+
+func (t *Tracker) drain() []core.FlagUsage {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	batch := make([]core.FlagUsage, 0, len(t.pending))
+	for _, u := range t.pending {
+		batch = append(batch, u)
+	}
+	t.pending = make(map[string]core.FlagUsage)
+	return batch
+}
+
+func (t *Tracker) restore(batch []core.FlagUsage) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, u := range batch {
+		cur := t.pending[u.FlagID]
+		cur.FlagID = u.FlagID
+		cur.Merge(u)
+		t.pending[u.FlagID] = cur
+	}
+}
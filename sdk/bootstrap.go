@@ -0,0 +1,50 @@
+package sdk
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Franconl/ffaas/internal/core"
+	"github.com/Franconl/ffaas/internal/flagfile"
+)
+
+// loadBootstrap lee las flags iniciales. Acepta dos formatos:
+//   - la respuesta de GET /sdk/flags ({"items": [...]}), ej: curl .../sdk/flags > flags.json
+//   - un documento de GET /flags/export o de un repo GitOps ({"flags": [...]}), JSON o YAML
+func loadBootstrap(path string) ([]core.FeatureFlag, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		doc, err := flagfile.Decode(data, flagfile.FormatYAML)
+		if err != nil {
+			return nil, err
+		}
+		return doc.ToFlags(), nil
+	}
+
+	var probe struct {
+		Flags json.RawMessage `json:"flags"`
+	}
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return nil, err
+	}
+	if probe.Flags != nil {
+		doc, err := flagfile.Decode(data, flagfile.FormatJSON)
+		if err != nil {
+			return nil, err
+		}
+		return doc.ToFlags(), nil
+	}
+
+	var body flagsResponse
+	if err := json.Unmarshal(data, &body); err != nil {
+		return nil, err
+	}
+	return body.Items, nil
+}
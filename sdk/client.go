@@ -0,0 +1,358 @@
+// Package sdk es el cliente Go de ffaas: baja las flags de /sdk/flags, las
+// mantiene al dia (polling con ETag y, opcionalmente, /sdk/stream) y las
+// evalua en el proceso con la misma logica que el servidor (core.FeatureFlag),
+// sin un request por evaluacion.
+//
+//	client, err := sdk.New(sdk.Config{BaseURL: "http://ffaas:8080", Stream: true})
+//	if err != nil { ... }
+//	go client.Run(ctx)
+//
+//	if client.BoolVariation("new_checkout", userID, false) { ... }
+//
+// Si el servidor no responde se sigue evaluando con la ultima lista buena (o
+// con BootstrapFile, si todavia no se pudo bajar ninguna). Las flags que el
+// cliente no conoce devuelven el default.
+//
+// Las evaluaciones locales se cuentan por flag y se mandan a /sdk/usage cada
+// UsageInterval, para que el reporte de flags viejas las vea.
+package sdk
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Franconl/ffaas/internal/core"
+)
+
+const (
+	DefaultPollInterval = 30 * time.Second
+	defaultHTTPTimeout  = 10 * time.Second
+
+	// StringVariation devuelve estas variantes: las flags de ffaas son on/off
+	VariantOn  = core.VariantOn
+	VariantOff = core.VariantOff
+)
+
+// ErrFlagNotFound: el servidor no tiene la flag (o esta archivada)
+var ErrFlagNotFound = errors.New("sdk: flag not found")
+
+type Config struct {
+	// BaseURL del servidor, ej: http://ffaas:8080. Vacio es modo offline: solo
+	// se usan las flags de BootstrapFile.
+	BaseURL string
+	// BootstrapFile son las flags iniciales, usadas hasta el primer fetch
+	// exitoso (o siempre, offline). Acepta la respuesta de GET /sdk/flags o un
+	// documento de GET /flags/export en JSON o YAML.
+	BootstrapFile string
+	// PollInterval entre pedidos a /sdk/flags (default 30s). Con Stream sigue
+	// corriendo como red de seguridad por si se pierde un evento.
+	PollInterval time.Duration
+	// Stream escucha /sdk/stream y refresca apenas cambia una flag
+	Stream bool
+	// HTTPClient para los pedidos (default: uno con timeout de 10s)
+	HTTPClient *http.Client
+	// UsageInterval entre envios de las evaluaciones locales a /sdk/usage
+	// (default 1m). Negativo no reporta uso.
+	UsageInterval time.Duration
+}
+
+type Client struct {
+	cfg  Config
+	http *http.Client
+
+	flags atomic.Pointer[flagSet]
+
+	// refreshMu serializa los refresh (polling y stream) para que el ETag y
+	// el diff de cambios se calculen sobre la lista anterior correcta
+	refreshMu sync.Mutex
+	hooks     []func(keys []string)
+
+	// evaluaciones locales por key desde el ultimo envio a /sdk/usage
+	usageMu  sync.Mutex
+	usage    map[string]usageItem
+	usageOff atomic.Bool
+}
+
+// flagSet es la lista vigente, indexada por key. Se reemplaza entera.
+type flagSet struct {
+	byKey map[string]core.FeatureFlag
+	etag  string
+}
+
+// New arma el cliente y carga BootstrapFile. No hace pedidos: las flags del
+// servidor llegan con Refresh o Run.
+func New(cfg Config) (*Client, error) {
+	if cfg.BaseURL == "" && cfg.BootstrapFile == "" {
+		return nil, errors.New("sdk: BaseURL or BootstrapFile is required")
+	}
+	cfg.BaseURL = strings.TrimRight(cfg.BaseURL, "/")
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = DefaultPollInterval
+	}
+	if cfg.UsageInterval == 0 {
+		cfg.UsageInterval = DefaultUsageInterval
+	}
+
+	c := &Client{cfg: cfg, http: cfg.HTTPClient, usage: make(map[string]usageItem)}
+	if c.http == nil {
+		c.http = &http.Client{Timeout: defaultHTTPTimeout}
+	}
+
+	set := &flagSet{byKey: map[string]core.FeatureFlag{}}
+	if cfg.BootstrapFile != "" {
+		flags, err := loadBootstrap(cfg.BootstrapFile)
+		if err != nil {
+			return nil, fmt.Errorf("sdk: bootstrap: %w", err)
+		}
+		set = newFlagSet(flags, "")
+	}
+	c.flags.Store(set)
+
+	return c, nil
+}
+
+func newFlagSet(flags []core.FeatureFlag, etag string) *flagSet {
+	set := &flagSet{byKey: make(map[string]core.FeatureFlag, len(flags)), etag: etag}
+	for _, f := range flags {
+		if !f.Archived() {
+			set.byKey[f.Key] = f
+		}
+	}
+	return set
+}
+
+// OnChange registra fn para cuando un refresh cambia flags; recibe las keys
+// creadas, modificadas o borradas, ordenadas. Llamar antes de Run.
+func (c *Client) OnChange(fn func(keys []string)) {
+	c.hooks = append(c.hooks, fn)
+}
+
+// --- evaluacion ---
+
+func (c *Client) flag(key string) (core.FeatureFlag, bool) {
+	f, ok := c.flags.Load().byKey[key]
+	return f, ok
+}
+
+// Detail es el resultado de una evaluacion: el valor, la variante (VariantOn
+// o VariantOff) y el motivo (DISABLED, STATIC o SPLIT, los de OpenFeature)
+type Detail struct {
+	Enabled bool
+	Variant string
+	Reason  string
+}
+
+// Evaluate evalua la flag localmente; ok es false si el cliente no la conoce
+func (c *Client) Evaluate(key, userID string) (Detail, bool) {
+	f, ok := c.flag(key)
+	if !ok {
+		return Detail{}, false
+	}
+	c.recordUsage(key)
+	e := f.Evaluate(userID)
+	return Detail{Enabled: e.Enabled, Variant: e.Variant, Reason: string(e.Reason)}, true
+}
+
+// BoolVariation evalua la flag para userID; def si el cliente no la conoce
+func (c *Client) BoolVariation(key, userID string, def bool) bool {
+	d, ok := c.Evaluate(key, userID)
+	if !ok {
+		return def
+	}
+	return d.Enabled
+}
+
+// StringVariation devuelve la variante de la flag para userID (VariantOn o
+// VariantOff); def si el cliente no la conoce
+func (c *Client) StringVariation(key, userID, def string) string {
+	d, ok := c.Evaluate(key, userID)
+	if !ok {
+		return def
+	}
+	return d.Variant
+}
+
+// evalResponse es la respuesta de GET /sdk/eval
+type evalResponse struct {
+	Enabled bool   `json:"enabled"`
+	Variant string `json:"variant"`
+	Reason  string `json:"reason"`
+}
+
+// EvaluateRemote evalua la flag en el servidor (GET /sdk/eval), sin usar la
+// lista local. Devuelve ErrFlagNotFound si el servidor no la tiene.
+func (c *Client) EvaluateRemote(ctx context.Context, key, userID string) (Detail, error) {
+	if c.cfg.BaseURL == "" {
+		return Detail{}, errors.New("sdk: remote evaluation needs BaseURL")
+	}
+	q := url.Values{"key": {key}, "userId": {userID}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.cfg.BaseURL+"/sdk/eval?"+q.Encode(), nil)
+	if err != nil {
+		return Detail{}, err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return Detail{}, fmt.Errorf("sdk: eval %s: %w", key, err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return Detail{}, ErrFlagNotFound
+	default:
+		return Detail{}, fmt.Errorf("sdk: eval %s: unexpected status %s", key, resp.Status)
+	}
+
+	var body evalResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return Detail{}, fmt.Errorf("sdk: decode eval: %w", err)
+	}
+	return Detail{Enabled: body.Enabled, Variant: body.Variant, Reason: body.Reason}, nil
+}
+
+// Keys devuelve las keys de las flags conocidas, ordenadas
+func (c *Client) Keys() []string {
+	set := c.flags.Load()
+	keys := make([]string, 0, len(set.byKey))
+	for k := range set.byKey {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// --- sincronizacion ---
+
+// flagsResponse es la parte de GET /sdk/flags que usa el cliente
+type flagsResponse struct {
+	Items   []core.FeatureFlag `json:"items"`
+	Version uint64             `json:"version"`
+}
+
+// Refresh baja /sdk/flags. Manda el ETag de la lista vigente: si el servidor
+// responde 304 no hay nada que actualizar.
+func (c *Client) Refresh(ctx context.Context) error {
+	if c.cfg.BaseURL == "" {
+		return nil
+	}
+	c.refreshMu.Lock()
+	defer c.refreshMu.Unlock()
+
+	cur := c.flags.Load()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.cfg.BaseURL+"/sdk/flags", nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if cur.etag != "" {
+		req.Header.Set("If-None-Match", cur.etag)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("sdk: fetch flags: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusNotModified:
+		return nil
+	case http.StatusOK:
+	default:
+		return fmt.Errorf("sdk: fetch flags: unexpected status %s", resp.Status)
+	}
+
+	var body flagsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return fmt.Errorf("sdk: decode flags: %w", err)
+	}
+
+	next := newFlagSet(body.Items, resp.Header.Get("ETag"))
+	c.flags.Store(next)
+
+	if changed := diff(cur, next); len(changed) > 0 {
+		for _, fn := range c.hooks {
+			fn(changed)
+		}
+	}
+	return nil
+}
+
+// diff devuelve las keys que se agregaron, cambiaron o desaparecieron
+func diff(prev, next *flagSet) []string {
+	var keys []string
+	for k, f := range next.byKey {
+		old, ok := prev.byKey[k]
+		if !ok || old.ID != f.ID || old.Enabled != f.Enabled || old.Percentage != f.Percentage ||
+			!old.UpdatedAt.Equal(f.UpdatedAt) {
+			keys = append(keys, k)
+		}
+	}
+	for k := range prev.byKey {
+		if _, ok := next.byKey[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// Run mantiene las flags al dia hasta que se cancele ctx: un refresh al
+// arrancar, despues uno cada PollInterval y, con Stream, uno por cada cambio
+// que avisa el servidor. Tambien manda el uso cada UsageInterval y una ultima
+// vez al salir. Offline no hace nada.
+func (c *Client) Run(ctx context.Context) {
+	if c.cfg.BaseURL == "" {
+		return
+	}
+
+	c.refreshOrLog(ctx)
+	if c.cfg.Stream {
+		go c.runStream(ctx)
+	}
+
+	ticker := time.NewTicker(c.cfg.PollInterval)
+	defer ticker.Stop()
+
+	var usageC <-chan time.Time
+	if c.reportsUsage() {
+		usageTicker := time.NewTicker(c.cfg.UsageInterval)
+		defer usageTicker.Stop()
+		usageC = usageTicker.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			c.flushUsageOrLog(flushCtx)
+			cancel()
+			return
+		case <-ticker.C:
+			c.refreshOrLog(ctx)
+		case <-usageC:
+			c.flushUsageOrLog(ctx)
+		}
+	}
+}
+
+// refreshOrLog refresca desde Run y el stream, donde un error no corta nada:
+// se loguea y se sigue con la lista vigente
+func (c *Client) refreshOrLog(ctx context.Context) {
+	if err := c.Refresh(ctx); err != nil && ctx.Err() == nil {
+		log.Println("⚠️ SDK: no se pudieron bajar las flags:", err)
+	}
+}
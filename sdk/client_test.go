@@ -0,0 +1,281 @@
+package sdk
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Franconl/ffaas/internal/core"
+	"github.com/Franconl/ffaas/internal/httpapi"
+	"github.com/Franconl/ffaas/internal/repo/cached"
+	"github.com/Franconl/ffaas/internal/repo/memory"
+	"github.com/Franconl/ffaas/internal/stream"
+	"github.com/Franconl/ffaas/internal/usage"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestEvaluatesLikeServer(t *testing.T) {
+	ctx := context.Background()
+	store := memory.New()
+	for _, f := range []core.FeatureFlag{
+		{Key: "half", Enabled: true, Percentage: 50},
+		{Key: "off", Enabled: false, Percentage: 100},
+	} {
+		if err := store.Create(ctx, &f); err != nil {
+			t.Fatal(err)
+		}
+	}
+	srv := httptest.NewServer(httpapi.NewRouter(store, httpapi.Options{}))
+	defer srv.Close()
+
+	c, err := New(Config{BaseURL: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Refresh(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := range 20 {
+		user := fmt.Sprintf("user-%d", i)
+		resp, err := http.Get(srv.URL + "/sdk/eval?key=half&userId=" + user)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var remote httpapi.EvalResponse
+		json.NewDecoder(resp.Body).Decode(&remote)
+		resp.Body.Close()
+
+		if got := c.BoolVariation("half", user, !remote.Enabled); got != remote.Enabled {
+			t.Errorf("%s: local=%v, servidor=%v", user, got, remote.Enabled)
+		}
+	}
+
+	if c.BoolVariation("off", "u1", true) {
+		t.Error("una flag apagada tiene que evaluar false")
+	}
+	if !c.BoolVariation("missing", "u1", true) || c.StringVariation("missing", "u1", "def") != "def" {
+		t.Error("una flag desconocida tiene que devolver el default")
+	}
+	if got := c.StringVariation("off", "u1", "def"); got != VariantOff {
+		t.Errorf("StringVariation = %q, se esperaba %q", got, VariantOff)
+	}
+}
+
+func TestRefreshUsesETagAndReportsChanges(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	store := cached.New(memory.New(), redis.NewClient(&redis.Options{Addr: mr.Addr()}), time.Minute)
+	f := core.FeatureFlag{Key: "new_checkout"}
+	if err := store.Create(ctx, &f); err != nil {
+		t.Fatal(err)
+	}
+
+	var notModified atomic.Int32
+	router := httpapi.NewRouter(store, httpapi.Options{Snapshots: store})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, r)
+		if rec.Code == http.StatusNotModified {
+			notModified.Add(1)
+		}
+		for k, v := range rec.Header() {
+			w.Header()[k] = v
+		}
+		w.WriteHeader(rec.Code)
+		w.Write(rec.Body.Bytes())
+	}))
+	defer srv.Close()
+
+	c, err := New(Config{BaseURL: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var changes [][]string
+	c.OnChange(func(keys []string) { changes = append(changes, keys) })
+
+	if err := c.Refresh(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Refresh(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if notModified.Load() != 1 {
+		t.Errorf("el segundo refresh tiene que ser un 304, hubo %d", notModified.Load())
+	}
+
+	f.Enabled, f.Percentage = true, 100
+	if err := store.Update(ctx, &f); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Refresh(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if !c.BoolVariation("new_checkout", "u1", false) {
+		t.Error("el refresh no trajo el cambio")
+	}
+	if fmt.Sprint(changes) != "[[new_checkout] [new_checkout]]" {
+		t.Errorf("OnChange = %v", changes)
+	}
+}
+
+func TestStreamTriggersRefresh(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	store := memory.New()
+	hub := stream.NewHub()
+	srv := httptest.NewServer(httpapi.NewRouter(store, httpapi.Options{Stream: hub}))
+	defer srv.Close()
+	defer hub.Close()
+
+	c, err := New(Config{BaseURL: srv.URL, Stream: true, PollInterval: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	changed := make(chan []string, 4)
+	c.OnChange(func(keys []string) { changed <- keys })
+	go c.Run(ctx)
+
+	// esperar a que el SDK este suscripto
+	deadline := time.Now().Add(3 * time.Second)
+	for hub.Subscribers() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("el SDK no se conecto al stream")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	f := core.FeatureFlag{Key: "streamed", Enabled: true, Percentage: 100}
+	if err := store.Create(ctx, &f); err != nil {
+		t.Fatal(err)
+	}
+	hub.Publish(stream.Event{Op: "insert", ID: f.ID, Key: f.Key})
+
+	select {
+	case keys := <-changed:
+		if fmt.Sprint(keys) != "[streamed]" {
+			t.Errorf("OnChange = %v", keys)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("el evento del stream no disparo un refresh")
+	}
+	if !c.BoolVariation("streamed", "u1", false) {
+		t.Error("la flag del stream no se evalua")
+	}
+}
+
+func TestBootstrapOffline(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"sdk.json":    `{"items":[{"id":"1","key":"from_sdk","enabled":true,"percentage":100}],"version":3}`,
+		"export.json": `{"version":1,"flags":[{"key":"from_export","enabled":true,"percentage":100}]}`,
+		"export.yaml": "version: 1\nflags:\n  - key: from_yaml\n    enabled: true\n    percentage: 100\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		c, err := New(Config{BootstrapFile: path})
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		go c.Run(context.Background()) // offline: no hace nada
+		keys := c.Keys()
+		if len(keys) != 1 || !c.BoolVariation(keys[0], "u1", false) {
+			t.Errorf("%s: flags = %v", name, keys)
+		}
+	}
+
+	// con el servidor caido se sigue usando el bootstrap
+	c, err := New(Config{BaseURL: "http://127.0.0.1:1", BootstrapFile: filepath.Join(dir, "sdk.json")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Refresh(context.Background()); err == nil {
+		t.Error("se esperaba error con el servidor caido")
+	}
+	if !c.BoolVariation("from_sdk", "u1", false) {
+		t.Error("con el servidor caido se tiene que seguir evaluando el bootstrap")
+	}
+
+	if _, err := New(Config{}); err == nil {
+		t.Error("sin BaseURL ni BootstrapFile New tiene que fallar")
+	}
+}
+
+func TestReportsLocalUsage(t *testing.T) {
+	ctx := context.Background()
+	store := memory.New()
+	f := core.FeatureFlag{Key: "new_checkout", Enabled: true, Percentage: 100}
+	if err := store.Create(ctx, &f); err != nil {
+		t.Fatal(err)
+	}
+	tracker := usage.NewTracker(store)
+	srv := httptest.NewServer(httpapi.NewRouter(store, httpapi.Options{Usage: tracker}))
+	defer srv.Close()
+
+	c, err := New(Config{BaseURL: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Refresh(ctx); err != nil {
+		t.Fatal(err)
+	}
+	for range 3 {
+		c.BoolVariation("new_checkout", "u1", false)
+	}
+	c.BoolVariation("missing", "u1", false) // desconocida: no se cuenta
+
+	if err := c.FlushUsage(ctx); err != nil {
+		t.Fatal(err)
+	}
+	snap, err := tracker.Snapshot(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u := snap[f.ID]; u.Evaluations != 3 || u.LastEvaluatedAt.IsZero() {
+		t.Errorf("uso reportado = %+v, se esperaban 3 evaluaciones", u)
+	}
+
+	// lo ya enviado no se vuelve a mandar
+	if err := c.FlushUsage(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if snap, _ := tracker.Snapshot(ctx); snap[f.ID].Evaluations != 3 {
+		t.Errorf("evaluaciones despues del segundo envio = %d", snap[f.ID].Evaluations)
+	}
+
+	// si el servidor no responde, las cuentas quedan para el proximo envio
+	down, err := New(Config{BaseURL: "http://127.0.0.1:1", BootstrapFile: writeBootstrap(t)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	down.BoolVariation("from_sdk", "u1", false)
+	if err := down.FlushUsage(ctx); err == nil {
+		t.Fatal("se esperaba error con el servidor caido")
+	}
+	if got := down.drainUsage(); len(got) != 1 || got[0].Evaluations != 1 {
+		t.Errorf("uso pendiente = %+v", got)
+	}
+}
+
+func writeBootstrap(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "flags.json")
+	content := `{"items":[{"id":"1","key":"from_sdk","enabled":true,"percentage":100}]}`
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
@@ -0,0 +1,217 @@
+// Package ofprovider es el provider de OpenFeature para ffaas, sobre el
+// cliente de sdk. Evalua en el proceso sobre la lista de flags (NewLocal) o
+// pidiendo cada flag a /sdk/eval (NewRemote).
+//
+//	client, _ := sdk.New(sdk.Config{BaseURL: "http://ffaas:8080", Stream: true})
+//	openfeature.SetProviderAndWait(ofprovider.NewLocal(client))
+//	of := openfeature.NewDefaultClient()
+//	on, _ := of.BooleanValue(ctx, "new_checkout", false, openfeature.NewEvaluationContext(userID, nil))
+//
+// Las flags de ffaas son on/off: se pueden pedir como bool (el valor) o como
+// string (la variante, "on" u "off"). Pedirlas como int, float u objeto da
+// TYPE_MISMATCH. El targeting key del contexto es el userId de ffaas.
+package ofprovider
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Franconl/ffaas/sdk"
+	"github.com/open-feature/go-sdk/openfeature"
+)
+
+const (
+	Name = "ffaas"
+
+	// initTimeout acota el primer fetch de Init; si falla se arranca con lo
+	// que tenga el cliente (bootstrap) y se sigue reintentando en Run
+	initTimeout = 10 * time.Second
+	// eventBuffer: los eventos que no entran se descartan (nadie los esta leyendo)
+	eventBuffer = 16
+)
+
+type Provider struct {
+	client *sdk.Client
+	remote bool
+	events chan openfeature.Event
+	// ready: la lista inicial ya se cargo; los cambios de antes no son eventos
+	ready atomic.Bool
+
+	mu     sync.Mutex
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewLocal evalua en el proceso sobre las flags del cliente. Init baja la
+// lista y arranca client.Run (no llamarlo aparte); cada cambio de flags llega
+// como PROVIDER_CONFIGURATION_CHANGED con las keys que cambiaron.
+func NewLocal(client *sdk.Client) *Provider {
+	p := newProvider(client, false)
+	client.OnChange(p.emitChange)
+	return p
+}
+
+// NewRemote evalua cada flag con un GET /sdk/eval: siempre el estado del
+// servidor, a costa de un request por evaluacion. La lista del cliente se
+// sincroniza igual que en NewLocal (polling con ETag y, con Stream, el stream),
+// solo para avisar los cambios como PROVIDER_CONFIGURATION_CHANGED.
+func NewRemote(client *sdk.Client) *Provider {
+	p := newProvider(client, true)
+	client.OnChange(p.emitChange)
+	return p
+}
+
+func newProvider(client *sdk.Client, remote bool) *Provider {
+	return &Provider{client: client, remote: remote, events: make(chan openfeature.Event, eventBuffer)}
+}
+
+func (p *Provider) Metadata() openfeature.Metadata {
+	return openfeature.Metadata{Name: Name}
+}
+
+func (p *Provider) Hooks() []openfeature.Hook {
+	return nil
+}
+
+// EventChannel implementa openfeature.EventHandler
+func (p *Provider) EventChannel() <-chan openfeature.Event {
+	return p.events
+}
+
+func (p *Provider) emitChange(keys []string) {
+	if !p.ready.Load() {
+		return
+	}
+	ev := openfeature.Event{
+		ProviderName: Name,
+		EventType:    openfeature.ProviderConfigChange,
+		ProviderEventDetails: openfeature.ProviderEventDetails{
+			Message:     "flags updated",
+			FlagChanges: keys,
+		},
+	}
+	select {
+	case p.events <- ev:
+	default:
+		log.Println("⚠️ OpenFeature: se descarta un evento de cambio, nadie lee los eventos del provider")
+	}
+}
+
+// Init implementa openfeature.StateHandler: baja las flags y arranca la
+// sincronizacion. En modo local falla solo si no hay ninguna lista con la que
+// evaluar (sin servidor y sin bootstrap); en remoto la lista es solo para los
+// eventos y no falla nunca.
+func (p *Provider) Init(openfeature.EvaluationContext) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.cancel != nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), initTimeout)
+	err := p.client.Refresh(ctx)
+	cancel()
+	if err != nil && !p.remote && len(p.client.Keys()) == 0 {
+		return err
+	}
+	p.ready.Store(true)
+
+	runCtx, stop := context.WithCancel(context.Background())
+	p.cancel, p.done = stop, make(chan struct{})
+	go func(done chan struct{}) {
+		defer close(done)
+		p.client.Run(runCtx)
+	}(p.done)
+	return nil
+}
+
+// Shutdown implementa openfeature.StateHandler: frena la sincronizacion
+func (p *Provider) Shutdown() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.cancel == nil {
+		return
+	}
+	p.cancel()
+	<-p.done
+	p.cancel, p.done = nil, nil
+	p.ready.Store(false)
+}
+
+// --- evaluacion ---
+
+// resolve evalua la flag para el targeting key del contexto. Los errores ya
+// vienen como ResolutionError de OpenFeature.
+func (p *Provider) resolve(ctx context.Context, flag string, flatCtx openfeature.FlattenedContext) (sdk.Detail, *openfeature.ResolutionError) {
+	userID, _ := flatCtx[openfeature.TargetingKey].(string)
+	if userID == "" {
+		rerr := openfeature.NewTargetingKeyMissingResolutionError("targeting key is required to evaluate ffaas flags")
+		return sdk.Detail{}, &rerr
+	}
+
+	if !p.remote {
+		d, ok := p.client.Evaluate(flag, userID)
+		if !ok {
+			rerr := openfeature.NewFlagNotFoundResolutionError("flag " + flag + " not found")
+			return sdk.Detail{}, &rerr
+		}
+		return d, nil
+	}
+
+	d, err := p.client.EvaluateRemote(ctx, flag, userID)
+	switch {
+	case errors.Is(err, sdk.ErrFlagNotFound):
+		rerr := openfeature.NewFlagNotFoundResolutionError("flag " + flag + " not found")
+		return sdk.Detail{}, &rerr
+	case err != nil:
+		rerr := openfeature.NewGeneralResolutionError(err.Error())
+		return sdk.Detail{}, &rerr
+	}
+	return d, nil
+}
+
+func failed(rerr *openfeature.ResolutionError) openfeature.ProviderResolutionDetail {
+	return openfeature.ProviderResolutionDetail{ResolutionError: *rerr, Reason: openfeature.ErrorReason}
+}
+
+func resolved(d sdk.Detail) openfeature.ProviderResolutionDetail {
+	return openfeature.ProviderResolutionDetail{Reason: openfeature.Reason(d.Reason), Variant: d.Variant}
+}
+
+func typeMismatch(flag, kind string) openfeature.ProviderResolutionDetail {
+	rerr := openfeature.NewTypeMismatchResolutionError("ffaas flag " + flag + " is boolean, not " + kind)
+	return failed(&rerr)
+}
+
+func (p *Provider) BooleanEvaluation(ctx context.Context, flag string, defaultValue bool, flatCtx openfeature.FlattenedContext) openfeature.BoolResolutionDetail {
+	d, rerr := p.resolve(ctx, flag, flatCtx)
+	if rerr != nil {
+		return openfeature.BoolResolutionDetail{Value: defaultValue, ProviderResolutionDetail: failed(rerr)}
+	}
+	return openfeature.BoolResolutionDetail{Value: d.Enabled, ProviderResolutionDetail: resolved(d)}
+}
+
+// StringEvaluation devuelve la variante ("on" u "off")
+func (p *Provider) StringEvaluation(ctx context.Context, flag string, defaultValue string, flatCtx openfeature.FlattenedContext) openfeature.StringResolutionDetail {
+	d, rerr := p.resolve(ctx, flag, flatCtx)
+	if rerr != nil {
+		return openfeature.StringResolutionDetail{Value: defaultValue, ProviderResolutionDetail: failed(rerr)}
+	}
+	return openfeature.StringResolutionDetail{Value: d.Variant, ProviderResolutionDetail: resolved(d)}
+}
+
+func (p *Provider) FloatEvaluation(ctx context.Context, flag string, defaultValue float64, flatCtx openfeature.FlattenedContext) openfeature.FloatResolutionDetail {
+	return openfeature.FloatResolutionDetail{Value: defaultValue, ProviderResolutionDetail: typeMismatch(flag, "float")}
+}
+
+func (p *Provider) IntEvaluation(ctx context.Context, flag string, defaultValue int64, flatCtx openfeature.FlattenedContext) openfeature.IntResolutionDetail {
+	return openfeature.IntResolutionDetail{Value: defaultValue, ProviderResolutionDetail: typeMismatch(flag, "int")}
+}
+
+func (p *Provider) ObjectEvaluation(ctx context.Context, flag string, defaultValue any, flatCtx openfeature.FlattenedContext) openfeature.InterfaceResolutionDetail {
+	return openfeature.InterfaceResolutionDetail{Value: defaultValue, ProviderResolutionDetail: typeMismatch(flag, "object")}
+}
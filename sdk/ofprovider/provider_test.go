@@ -0,0 +1,157 @@
+package ofprovider
+
+import (
+	"context"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Franconl/ffaas/internal/core"
+	"github.com/Franconl/ffaas/internal/httpapi"
+	"github.com/Franconl/ffaas/internal/repo/memory"
+	"github.com/Franconl/ffaas/sdk"
+	"github.com/open-feature/go-sdk/openfeature"
+)
+
+func newServer(t *testing.T) (*memory.Repo, *httptest.Server) {
+	t.Helper()
+	store := memory.New()
+	for _, f := range []core.FeatureFlag{
+		{Key: "all", Enabled: true, Percentage: 100},
+		{Key: "half", Enabled: true, Percentage: 50},
+		{Key: "off", Enabled: false},
+	} {
+		if err := store.Create(context.Background(), &f); err != nil {
+			t.Fatal(err)
+		}
+	}
+	srv := httptest.NewServer(httpapi.NewRouter(store, httpapi.Options{}))
+	t.Cleanup(srv.Close)
+	return store, srv
+}
+
+func newOFClient(t *testing.T, domain string, p *Provider) *openfeature.Client {
+	t.Helper()
+	if err := openfeature.SetNamedProviderAndWait(domain, p); err != nil {
+		t.Fatalf("set provider: %v", err)
+	}
+	t.Cleanup(p.Shutdown)
+	return openfeature.NewClient(domain)
+}
+
+func TestLocalProvider(t *testing.T) {
+	ctx := context.Background()
+	store, srv := newServer(t)
+	client, err := sdk.New(sdk.Config{BaseURL: srv.URL, PollInterval: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := NewLocal(client)
+	of := newOFClient(t, "ffaas-local", p)
+	user := openfeature.NewEvaluationContext("user-1", nil)
+
+	d, err := of.BooleanValueDetails(ctx, "all", false, user)
+	if err != nil || !d.Value || d.Variant != "on" || d.Reason != openfeature.StaticReason {
+		t.Errorf("all = %+v, %v", d, err)
+	}
+	d, _ = of.BooleanValueDetails(ctx, "off", true, user)
+	if d.Value || d.Variant != "off" || d.Reason != openfeature.DisabledReason {
+		t.Errorf("off = %+v", d)
+	}
+	s, _ := of.StringValueDetails(ctx, "all", "def", user)
+	if s.Value != "on" {
+		t.Errorf("string all = %+v", s)
+	}
+
+	d, _ = of.BooleanValueDetails(ctx, "missing", true, user)
+	if !d.Value || d.ErrorCode != openfeature.FlagNotFoundCode || d.Reason != openfeature.ErrorReason {
+		t.Errorf("missing = %+v", d)
+	}
+	d, _ = of.BooleanValueDetails(ctx, "all", false, openfeature.NewTargetlessEvaluationContext(nil))
+	if d.Value || d.ErrorCode != openfeature.TargetingKeyMissingCode {
+		t.Errorf("sin targeting key = %+v", d)
+	}
+	i, _ := of.IntValueDetails(ctx, "all", 7, user)
+	if i.Value != 7 || i.ErrorCode != openfeature.TypeMismatchCode {
+		t.Errorf("int = %+v", i)
+	}
+
+	// un cambio de flags llega como PROVIDER_CONFIGURATION_CHANGED
+	changes := make(chan []string, 1)
+	handler := func(e openfeature.EventDetails) { changes <- e.FlagChanges }
+	of.AddHandler(openfeature.ProviderConfigChange, &handler)
+
+	f, _ := store.GetByKey(ctx, "off")
+	f.Enabled, f.Percentage = true, 100
+	if err := store.Update(ctx, f); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Refresh(ctx); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case keys := <-changes:
+		if fmt.Sprint(keys) != "[off]" {
+			t.Errorf("FlagChanges = %v", keys)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("no llego el evento de cambio")
+	}
+	if on, _ := of.BooleanValue(ctx, "off", false, user); !on {
+		t.Error("el provider no ve el cambio")
+	}
+}
+
+func TestRemoteProvider(t *testing.T) {
+	ctx := context.Background()
+	store, srv := newServer(t)
+	client, err := sdk.New(sdk.Config{BaseURL: srv.URL, PollInterval: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	of := newOFClient(t, "ffaas-remote", NewRemote(client))
+
+	half := core.FeatureFlag{Key: "half", Enabled: true, Percentage: 50}
+	for i := range 10 {
+		user := fmt.Sprintf("user-%d", i)
+		d, err := of.BooleanValueDetails(ctx, "half", !half.Eval(user), openfeature.NewEvaluationContext(user, nil))
+		if err != nil || d.Value != half.Eval(user) || d.Reason != openfeature.SplitReason {
+			t.Errorf("%s: %+v, %v", user, d, err)
+		}
+	}
+
+	user := openfeature.NewEvaluationContext("user-1", nil)
+	d, _ := of.BooleanValueDetails(ctx, "missing", true, user)
+	if !d.Value || d.ErrorCode != openfeature.FlagNotFoundCode {
+		t.Errorf("missing = %+v", d)
+	}
+
+	// los cambios tambien llegan como PROVIDER_CONFIGURATION_CHANGED
+	changes := make(chan []string, 1)
+	handler := func(e openfeature.EventDetails) { changes <- e.FlagChanges }
+	of.AddHandler(openfeature.ProviderConfigChange, &handler)
+
+	f, _ := store.GetByKey(ctx, "half")
+	f.Percentage = 100
+	if err := store.Update(ctx, f); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Refresh(ctx); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case keys := <-changes:
+		if fmt.Sprint(keys) != "[half]" {
+			t.Errorf("FlagChanges = %v", keys)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("no llego el evento de cambio")
+	}
+
+	srv.Close()
+	d, _ = of.BooleanValueDetails(ctx, "all", false, user)
+	if d.Value || d.ErrorCode != openfeature.GeneralCode {
+		t.Errorf("servidor caido = %+v", d)
+	}
+}
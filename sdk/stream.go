@@ -0,0 +1,93 @@
+package sdk
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// el servidor manda un ping cada 15s: si pasan tres sin nada, la conexion
+	// esta muerta aunque TCP no se haya enterado
+	streamIdleTimeout = 45 * time.Second
+	streamMinBackoff  = time.Second
+	streamMaxBackoff  = 30 * time.Second
+)
+
+// runStream escucha /sdk/stream y reconecta con backoff hasta que se cancele ctx
+func (c *Client) runStream(ctx context.Context) {
+	backoff := streamMinBackoff
+	for {
+		connected, err := c.stream(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if connected {
+			backoff = streamMinBackoff
+		}
+		log.Printf("⚠️ SDK: stream cortado (%v), reconectando en %s", err, backoff)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, streamMaxBackoff)
+	}
+}
+
+// stream lee los eventos de una conexion. Tanto "ready" (al conectar, para
+// cubrir lo que cambio mientras no estaba conectado) como "change" disparan un
+// Refresh: el evento no trae la flag y con el ETag el pedido es barato.
+func (c *Client) stream(ctx context.Context) (connected bool, err error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.cfg.BaseURL+"/sdk/stream", nil)
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+
+	// la conexion es larga: sin el timeout total del cliente (lo cubre el idle timeout)
+	client := *c.http
+	client.Timeout = 0
+	resp, err := client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	idle := time.AfterFunc(streamIdleTimeout, cancel)
+	defer idle.Stop()
+
+	sc := bufio.NewScanner(resp.Body)
+	var event string
+	for sc.Scan() {
+		idle.Reset(streamIdleTimeout)
+		line := sc.Text()
+
+		switch {
+		case line == "":
+			// fin del evento
+			if event == "ready" || event == "change" {
+				c.refreshOrLog(ctx)
+			}
+			event = ""
+		case strings.HasPrefix(line, "event:"):
+			event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return true, err
+	}
+	return true, io.EOF
+}
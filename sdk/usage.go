@@ -0,0 +1,115 @@
+package sdk
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+const DefaultUsageInterval = time.Minute
+
+// usageItem es una entrada de POST /sdk/usage
+type usageItem struct {
+	Key             string    `json:"key"`
+	Evaluations     int64     `json:"evaluations"`
+	LastEvaluatedAt time.Time `json:"last_evaluated_at"`
+}
+
+// recordUsage cuenta una evaluacion local de key para el proximo envio
+func (c *Client) recordUsage(key string) {
+	if !c.reportsUsage() {
+		return
+	}
+	now := time.Now().UTC()
+
+	c.usageMu.Lock()
+	defer c.usageMu.Unlock()
+	u := c.usage[key]
+	u.Key = key
+	u.Evaluations++
+	u.LastEvaluatedAt = now
+	c.usage[key] = u
+}
+
+func (c *Client) reportsUsage() bool {
+	return c.cfg.BaseURL != "" && c.cfg.UsageInterval > 0 && !c.usageOff.Load()
+}
+
+// FlushUsage manda a /sdk/usage las evaluaciones locales acumuladas. Si falla
+// quedan para el proximo envio. Run lo llama cada UsageInterval y al terminar.
+func (c *Client) FlushUsage(ctx context.Context) error {
+	if !c.reportsUsage() {
+		return nil
+	}
+	batch := c.drainUsage()
+	if len(batch) == 0 {
+		return nil
+	}
+
+	body, err := json.Marshal(map[string]any{"items": batch})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.cfg.BaseURL+"/sdk/usage", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		c.restoreUsage(batch)
+		return fmt.Errorf("sdk: report usage: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNoContent || resp.StatusCode == http.StatusOK:
+		return nil
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusMethodNotAllowed:
+		// servidor viejo, sin /sdk/usage: no tiene sentido seguir juntando
+		c.usageOff.Store(true)
+		return fmt.Errorf("sdk: report usage: server does not support /sdk/usage (%s)", resp.Status)
+	case resp.StatusCode >= 500:
+		c.restoreUsage(batch)
+	}
+	return fmt.Errorf("sdk: report usage: unexpected status %s", resp.Status)
+}
+
+func (c *Client) drainUsage() []usageItem {
+	c.usageMu.Lock()
+	defer c.usageMu.Unlock()
+
+	batch := make([]usageItem, 0, len(c.usage))
+	for _, u := range c.usage {
+		batch = append(batch, u)
+	}
+	clear(c.usage)
+	return batch
+}
+
+func (c *Client) restoreUsage(batch []usageItem) {
+	c.usageMu.Lock()
+	defer c.usageMu.Unlock()
+
+	for _, u := range batch {
+		cur := c.usage[u.Key]
+		cur.Key = u.Key
+		cur.Evaluations += u.Evaluations
+		if u.LastEvaluatedAt.After(cur.LastEvaluatedAt) {
+			cur.LastEvaluatedAt = u.LastEvaluatedAt
+		}
+		c.usage[u.Key] = cur
+	}
+}
+
+// flushUsageOrLog es el envio desde Run: un error no corta nada
+func (c *Client) flushUsageOrLog(ctx context.Context) {
+	if err := c.FlushUsage(ctx); err != nil {
+		log.Println("⚠️ SDK: no se pudo reportar el uso de flags:", err)
+	}
+}